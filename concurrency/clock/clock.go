@@ -0,0 +1,183 @@
+// Package clock abstracts the time package so code that waits on timers
+// can be tested with a Fake clock instead of real sleeps
+package clock
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Clock is the subset of the time package used by the examples
+type Clock interface {
+	Now() time.Time
+	NewTimer(d time.Duration) Timer
+	NewTicker(d time.Duration) Ticker
+	// Sleep and After are the time functions on this clock
+	Sleep(d time.Duration)
+	After(d time.Duration) <-chan time.Time
+}
+
+// Timer is a time.Timer behind an interface, C is a method so fakes can
+// provide their own channel
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+// Ticker is a time.Ticker behind an interface, like Timer
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+	Reset(d time.Duration)
+}
+
+// Real is the Clock backed by the time package
+type Real struct{}
+
+// New returns the real clock
+func New() Clock {
+	return Real{}
+}
+
+// Now returns time.Now()
+func (Real) Now() time.Time {
+	return time.Now()
+}
+
+// NewTimer wraps time.NewTimer
+func (Real) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+// NewTicker wraps time.NewTicker
+func (Real) NewTicker(d time.Duration) Ticker {
+	return realTicker{time.NewTicker(d)}
+}
+
+// Sleep is time.Sleep
+func (Real) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+// After is time.After
+func (Real) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// WithTimeout is context.WithTimeout on c. On a fake clock the context is
+// done once c has been advanced by d, and like the real one its Err is
+// then context.DeadlineExceeded and its Deadline c.Now().Add(d)
+func WithTimeout(c Clock, parent context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if _, ok := c.(Real); ok {
+		return context.WithTimeout(parent, d)
+	}
+	ctx := &timeoutCtx{
+		// values only, the cancellation of parent is watched below
+		Context:  context.WithoutCancel(parent),
+		deadline: c.Now().Add(d),
+		done:     make(chan struct{}),
+	}
+	if pd, ok := parent.Deadline(); ok && pd.Before(ctx.deadline) {
+		ctx.deadline = pd
+	}
+	timer := c.NewTimer(d)
+	stop := make(chan struct{})
+	go func() {
+		var err error
+		select {
+		case <-timer.C():
+			err = context.DeadlineExceeded
+		case <-parent.Done():
+			err = parent.Err()
+		case <-stop:
+			err = context.Canceled
+		}
+		// stopped first, the timer is gone once cancel returns
+		timer.Stop()
+		ctx.cancel(err)
+	}()
+	var once sync.Once
+	return ctx, func() {
+		once.Do(func() { close(stop) })
+		<-ctx.done
+	}
+}
+
+// timeoutCtx is the context of WithTimeout on a fake clock. It has its own
+// done channel so the contexts derived from it get its Err, and hides the
+// cancellation of the parent so context.Cause returns Err too
+type timeoutCtx struct {
+	context.Context
+	deadline time.Time
+	done     chan struct{}
+
+	mu  sync.Mutex
+	err error
+}
+
+func (c *timeoutCtx) cancel(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.err = err
+	close(c.done)
+}
+
+func (c *timeoutCtx) Deadline() (time.Time, bool) {
+	return c.deadline, true
+}
+
+func (c *timeoutCtx) Done() <-chan struct{} {
+	return c.done
+}
+
+func (c *timeoutCtx) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// Wait is Sleep cut short when ctx is done, it returns ctx.Err() then
+func Wait(ctx context.Context, c Clock, d time.Duration) error {
+	timer := c.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C():
+		return nil
+	}
+}
+
+type realTimer struct {
+	t *time.Timer
+}
+
+func (r realTimer) C() <-chan time.Time {
+	return r.t.C
+}
+
+func (r realTimer) Stop() bool {
+	return r.t.Stop()
+}
+
+func (r realTimer) Reset(d time.Duration) bool {
+	return r.t.Reset(d)
+}
+
+type realTicker struct {
+	t *time.Ticker
+}
+
+func (r realTicker) C() <-chan time.Time {
+	return r.t.C
+}
+
+func (r realTicker) Stop() {
+	r.t.Stop()
+}
+
+func (r realTicker) Reset(d time.Duration) {
+	r.t.Reset(d)
+}
@@ -0,0 +1,186 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Fake is a Clock that only moves when Advance is called
+// Timers and tickers fire synchronously inside Advance once their deadline
+// is reached
+type Fake struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	now     time.Time
+	waiters []*fakeTimer
+}
+
+// NewFake returns a Fake clock set to now
+func NewFake(now time.Time) *Fake {
+	f := &Fake{now: now}
+	f.cond = sync.NewCond(&f.mu)
+	return f
+}
+
+// Now returns the fake time
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// NewTimer returns a timer firing once the clock is advanced by d
+func (f *Fake) NewTimer(d time.Duration) Timer {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t := &fakeTimer{clock: f, c: make(chan time.Time, 1)}
+	f.schedule(t, d)
+	return t
+}
+
+// NewTicker returns a ticker firing every d of fake time, it panics when
+// d is not positive like time.NewTicker
+func (f *Fake) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("clock: non-positive interval for NewTicker")
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t := &fakeTimer{clock: f, c: make(chan time.Time, 1), period: d}
+	f.schedule(t, d)
+	return fakeTicker{t}
+}
+
+// Sleep blocks until the clock is advanced by d, the sleeper counts as a
+// waiter for BlockUntil
+func (f *Fake) Sleep(d time.Duration) {
+	<-f.NewTimer(d).C()
+}
+
+// After is NewTimer(d).C()
+func (f *Fake) After(d time.Duration) <-chan time.Time {
+	return f.NewTimer(d).C()
+}
+
+// Advance moves the clock forward by d and fires every expired timer
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+	pending := f.waiters[:0]
+	for _, t := range f.waiters {
+		switch {
+		case t.deadline.After(f.now):
+			pending = append(pending, t)
+		case t.period == 0:
+			t.fire(f.now)
+		default:
+			// A ticker sends the first tick due and drops the others, like
+			// time.Ticker with a slow reader, then stays armed
+			t.fire(t.deadline)
+			for !t.deadline.After(f.now) {
+				t.deadline = t.deadline.Add(t.period)
+			}
+			pending = append(pending, t)
+		}
+	}
+	f.waiters = pending
+}
+
+// BlockUntil blocks until n timers are waiting on the clock
+// It lets a test wait for the goroutine under test to arm its timer
+// before calling Advance
+func (f *Fake) BlockUntil(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for len(f.waiters) < n {
+		f.cond.Wait()
+	}
+}
+
+// Waiters returns the number of timers waiting on the clock
+func (f *Fake) Waiters() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.waiters)
+}
+
+// schedule arms t, the caller holds f.mu
+func (f *Fake) schedule(t *fakeTimer, d time.Duration) {
+	t.deadline = f.now.Add(d)
+	if d <= 0 {
+		t.fire(f.now)
+		return
+	}
+	f.waiters = append(f.waiters, t)
+	f.cond.Broadcast()
+}
+
+// remove disarms t and reports if it was armed, the caller holds f.mu
+func (f *Fake) remove(t *fakeTimer) bool {
+	for i, w := range f.waiters {
+		if w == t {
+			f.waiters = append(f.waiters[:i], f.waiters[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+type fakeTimer struct {
+	clock    *Fake
+	c        chan time.Time
+	deadline time.Time
+	// period is the interval of a ticker, zero for a timer
+	period time.Duration
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	return t.clock.remove(t)
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	active := t.clock.remove(t)
+	t.clock.schedule(t, d)
+	return active
+}
+
+// fire sends without blocking, like the runtime the channel holds one value
+func (t *fakeTimer) fire(now time.Time) {
+	select {
+	case t.c <- now:
+	default:
+	}
+}
+
+type fakeTicker struct {
+	t *fakeTimer
+}
+
+func (t fakeTicker) C() <-chan time.Time {
+	return t.t.c
+}
+
+func (t fakeTicker) Stop() {
+	t.t.Stop()
+}
+
+// Reset stops the ticker and starts it again with the interval d
+func (t fakeTicker) Reset(d time.Duration) {
+	if d <= 0 {
+		panic("clock: non-positive interval for Ticker.Reset")
+	}
+	t.t.clock.mu.Lock()
+	defer t.t.clock.mu.Unlock()
+	t.t.clock.remove(t.t)
+	t.t.period = d
+	t.t.clock.schedule(t.t, d)
+}
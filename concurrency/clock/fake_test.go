@@ -0,0 +1,165 @@
+package clock
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+var epoch = time.Unix(0, 0)
+
+// recv returns the value waiting in c, or false when there is none
+func recv(c <-chan time.Time) (time.Time, bool) {
+	select {
+	case t := <-c:
+		return t, true
+	default:
+		return time.Time{}, false
+	}
+}
+
+func TestFakeTimer(t *testing.T) {
+	f := NewFake(epoch)
+	timer := f.NewTimer(time.Second)
+	f.Advance(999 * time.Millisecond)
+	if _, ok := recv(timer.C()); ok {
+		t.Errorf("timer fired early")
+	}
+	f.Advance(time.Millisecond)
+	if at, ok := recv(timer.C()); !ok || !at.Equal(epoch.Add(time.Second)) {
+		t.Errorf("got %v %v, wanted a fire at 1s", at, ok)
+	}
+	if f.Waiters() != 0 || timer.Stop() {
+		t.Errorf("fired timer still waiting")
+	}
+	if timer.Reset(time.Second) {
+		t.Errorf("Reset of a fired timer got true")
+	}
+	if !timer.Stop() {
+		t.Errorf("Stop of a reset timer got false")
+	}
+	f.Advance(time.Hour)
+	if _, ok := recv(timer.C()); ok {
+		t.Errorf("stopped timer fired")
+	}
+}
+
+func TestFakeSleep(t *testing.T) {
+	f := NewFake(epoch)
+	done := make(chan time.Time)
+	go func() {
+		f.Sleep(time.Minute)
+		done <- f.Now()
+	}()
+	select {
+	case <-f.After(0):
+	default:
+		t.Errorf("After(0) did not fire right away")
+	}
+	f.BlockUntil(1)
+	f.Advance(time.Minute)
+	if at := <-done; !at.Equal(epoch.Add(time.Minute)) {
+		t.Errorf("woke up at %v", at)
+	}
+}
+
+func TestFakeTicker(t *testing.T) {
+	f := NewFake(epoch)
+	ticker := f.NewTicker(time.Second)
+	for i := 1; i <= 3; i++ {
+		f.Advance(time.Second)
+		if at, ok := recv(ticker.C()); !ok || !at.Equal(epoch.Add(time.Duration(i)*time.Second)) {
+			t.Errorf("tick %d got %v %v", i, at, ok)
+		}
+	}
+	if f.Waiters() != 1 {
+		t.Errorf("ticker is not waiting")
+	}
+
+	// a reader that does not keep up gets the first tick, the others are
+	// dropped and the ticker stays on its period
+	f.Advance(2500 * time.Millisecond)
+	f.Advance(time.Second)
+	if at, _ := recv(ticker.C()); !at.Equal(epoch.Add(4 * time.Second)) {
+		t.Errorf("slow reader got %v, wanted 4s", at)
+	}
+	f.Advance(500 * time.Millisecond)
+	if at, _ := recv(ticker.C()); !at.Equal(epoch.Add(7 * time.Second)) {
+		t.Errorf("got %v, wanted 7s", at)
+	}
+
+	ticker.Reset(5 * time.Second)
+	f.Advance(4 * time.Second)
+	if _, ok := recv(ticker.C()); ok {
+		t.Errorf("reset ticker fired early")
+	}
+	f.Advance(time.Second)
+	if at, _ := recv(ticker.C()); !at.Equal(epoch.Add(12 * time.Second)) {
+		t.Errorf("reset ticker got %v, wanted 12s", at)
+	}
+
+	ticker.Stop()
+	f.Advance(time.Hour)
+	if _, ok := recv(ticker.C()); ok || f.Waiters() != 0 {
+		t.Errorf("stopped ticker fired")
+	}
+}
+
+func TestFakeWithTimeout(t *testing.T) {
+	f := NewFake(epoch)
+	ctx, cancel := WithTimeout(f, context.Background(), time.Second)
+	defer cancel()
+	if deadline, ok := ctx.Deadline(); !ok || !deadline.Equal(epoch.Add(time.Second)) {
+		t.Errorf("Deadline got %v %v", deadline, ok)
+	}
+	child, cancelChild := context.WithCancel(ctx)
+	defer cancelChild()
+	waited := make(chan error)
+	go func() {
+		waited <- Wait(ctx, f, time.Hour)
+	}()
+	// the deadline and the wait
+	f.BlockUntil(2)
+	f.Advance(999 * time.Millisecond)
+	if ctx.Err() != nil {
+		t.Errorf("done early with %v", ctx.Err())
+	}
+	f.Advance(time.Millisecond)
+	// the same errors as a real deadline, for the context and its children
+	if err := <-waited; !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Wait got %v", err)
+	}
+	<-child.Done()
+	for _, err := range []error{ctx.Err(), context.Cause(ctx), child.Err()} {
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("got %v, wanted %v", err, context.DeadlineExceeded)
+		}
+	}
+	if err := Wait(context.Background(), f, 0); err != nil {
+		t.Errorf("Wait of 0 got %v", err)
+	}
+}
+
+func TestFakeWithTimeoutCancel(t *testing.T) {
+	f := NewFake(epoch)
+	type key struct{}
+	parent, cancelParent := context.WithCancel(context.WithValue(context.Background(), key{}, "v"))
+	ctx, cancel := WithTimeout(f, parent, time.Second)
+	defer cancel()
+	if ctx.Value(key{}) != "v" {
+		t.Errorf("value of the parent lost")
+	}
+	cancelParent()
+	<-ctx.Done()
+	if !errors.Is(ctx.Err(), context.Canceled) {
+		t.Errorf("cancelled parent got %v", ctx.Err())
+	}
+
+	ctx, cancel = WithTimeout(f, context.Background(), time.Second)
+	cancel()
+	cancel()
+	if !errors.Is(ctx.Err(), context.Canceled) || f.Waiters() != 0 {
+		t.Errorf("cancel got %v with %d timers left", ctx.Err(), f.Waiters())
+	}
+}
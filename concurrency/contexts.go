@@ -0,0 +1,270 @@
+package concurrency
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/vrnvu/go-examples/concurrency/clock"
+)
+
+// The functions in this file are the context aware versions of the
+// worker pool, ticker, rate limiter and stateful goroutine examples.
+// Instead of a done channel or a sleep in the caller, every goroutine
+// selects on ctx.Done() and returns ctx.Err() once it is cancelled,
+// so nothing keeps running after the function returns
+
+// workDuration is the simulated work of WorkerContext, Worker sleeps a second
+var workDuration = time.Second
+
+// WorkerContext is Worker with cancellation
+// It returns nil once jobs is closed and ctx.Err() if ctx is done first
+func WorkerContext(ctx context.Context, id int, jobs <-chan int, results chan<- int) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case j, more := <-jobs:
+			if !more {
+				return nil
+			}
+			// A sleep can not be interrupted, a timer in a select can
+			timer := clk.NewTimer(workDuration)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C():
+			}
+			select {
+			case results <- j * 2:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+}
+
+// WorkerPoolsContext runs numJobs jobs over numWorkers WorkerContext
+// It returns the results collected so far and ctx.Err() if ctx is done
+// before all the jobs finish. Every worker has exited when it returns
+func WorkerPoolsContext(ctx context.Context, numWorkers, numJobs int) ([]int, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	jobs := make(chan int, numJobs)
+	results := make(chan int, numJobs)
+
+	var wg sync.WaitGroup
+	for w := 1; w <= numWorkers; w++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			WorkerContext(ctx, id, jobs, results)
+		}(w)
+	}
+	// The workers are gone when we return, whatever the reason
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	for j := 1; j <= numJobs; j++ {
+		jobs <- j
+	}
+	close(jobs)
+
+	collected := make([]int, 0, numJobs)
+	for len(collected) < numJobs {
+		select {
+		case r := <-results:
+			collected = append(collected, r)
+		case <-ctx.Done():
+			return collected, ctx.Err()
+		}
+	}
+	return collected, nil
+}
+
+// TickersContext calls tick on every tick of a ticker until ctx is done
+// It replaces the done channel of Tickers and always returns ctx.Err()
+func TickersContext(ctx context.Context, d time.Duration, tick func(time.Time)) error {
+	ticker := clk.NewTicker(d)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case t := <-ticker.C():
+			tick(t)
+		}
+	}
+}
+
+// RateLimitingContext serves requests with the bursty limiter of RateLimiting
+// Up to burst requests are served at once, then one every d.
+// The refill goroutine stops with the function instead of ticking forever.
+// It returns nil once requests is closed and ctx.Err() if ctx is done first
+func RateLimitingContext(ctx context.Context, requests <-chan int, d time.Duration, burst int, serve func(int)) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	burstyLimiter := make(chan time.Time, burst)
+	for i := 0; i < burst; i++ {
+		burstyLimiter <- clk.Now()
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := clk.NewTicker(d)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case t := <-ticker.C():
+				// Drop the token when the bucket is full instead of blocking
+				select {
+				case burstyLimiter <- t:
+				default:
+				}
+			}
+		}
+	}()
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case req, more := <-requests:
+			if !more {
+				return nil
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-burstyLimiter:
+				serve(req)
+			}
+		}
+	}
+}
+
+// StateOwner is the owning goroutine of StatefulGoroutines
+// The goroutine stops when the context given to NewStateOwner is done
+type StateOwner struct {
+	reads  chan readOp
+	writes chan writeOp
+	done   chan struct{}
+	err    error
+}
+
+// NewStateOwner starts the goroutine owning the state
+func NewStateOwner(ctx context.Context) *StateOwner {
+	s := &StateOwner{
+		reads:  make(chan readOp),
+		writes: make(chan writeOp),
+		done:   make(chan struct{}),
+	}
+	go func() {
+		defer close(s.done)
+		var state = make(map[int]int)
+		for {
+			select {
+			case <-ctx.Done():
+				s.err = ctx.Err()
+				return
+			case read := <-s.reads:
+				read.resp <- state[read.key]
+			case write := <-s.writes:
+				state[write.key] = write.val
+				write.resp <- true
+			}
+		}
+	}()
+	return s
+}
+
+// Read asks the owner for the value of key
+func (s *StateOwner) Read(ctx context.Context, key int) (int, error) {
+	// Buffered so the owner never blocks on a reader that gave up
+	read := readOp{key: key, resp: make(chan int, 1)}
+	select {
+	case s.reads <- read:
+		return <-read.resp, nil
+	case <-s.done:
+		return 0, s.err
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+}
+
+// Write asks the owner to store val under key
+func (s *StateOwner) Write(ctx context.Context, key, val int) error {
+	write := writeOp{key: key, val: val, resp: make(chan bool, 1)}
+	select {
+	case s.writes <- write:
+		<-write.resp
+		return nil
+	case <-s.done:
+		return s.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Done is closed once the owner goroutine has exited
+func (s *StateOwner) Done() <-chan struct{} {
+	return s.done
+}
+
+// Err returns the reason the owner stopped, nil while it is running
+func (s *StateOwner) Err() error {
+	select {
+	case <-s.done:
+		return s.err
+	default:
+		return nil
+	}
+}
+
+// Contexts runs the context aware examples with short deadlines
+func Contexts() {
+	ctx, cancel := clock.WithTimeout(clk, context.Background(), 1500*time.Millisecond)
+	defer cancel()
+	results, err := WorkerPoolsContext(ctx, 3, 9)
+	fmt.Println("worker pool results:", results, "err:", err)
+
+	ctx, cancel = clock.WithTimeout(clk, context.Background(), 1600*time.Millisecond)
+	defer cancel()
+	err = TickersContext(ctx, 500*time.Millisecond, func(t time.Time) {
+		fmt.Println("Tick at", t)
+	})
+	fmt.Println("ticker stopped:", err)
+
+	requests := make(chan int, 5)
+	for i := 1; i <= 5; i++ {
+		requests <- i
+	}
+	close(requests)
+	err = RateLimitingContext(context.Background(), requests, 200*time.Millisecond, 3, func(req int) {
+		fmt.Println("request", req, clk.Now())
+	})
+	fmt.Println("rate limiter done:", err)
+
+	ctx, cancel = context.WithCancel(context.Background())
+	owner := NewStateOwner(ctx)
+	owner.Write(ctx, 1, 42)
+	v, _ := owner.Read(ctx, 1)
+	fmt.Println("state[1]:", v)
+	cancel()
+	<-owner.Done()
+	fmt.Println("owner stopped:", owner.Err())
+}
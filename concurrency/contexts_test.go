@@ -0,0 +1,164 @@
+package concurrency
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// checkLeaks fails the test if the number of goroutines does not go back
+// to the value it had when checkLeaks was called
+func checkLeaks(t *testing.T) func() {
+	before := runtime.NumGoroutine()
+	return func() {
+		t.Helper()
+		deadline := time.Now().Add(time.Second)
+		for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		if after := runtime.NumGoroutine(); after > before {
+			buf := make([]byte, 1<<16)
+			n := runtime.Stack(buf, true)
+			t.Errorf("leaked %d goroutines\n%s", after-before, buf[:n])
+		}
+	}
+}
+
+func setWorkDuration(t *testing.T, d time.Duration) {
+	old := workDuration
+	workDuration = d
+	t.Cleanup(func() { workDuration = old })
+}
+
+func TestWorkerPoolsContext(t *testing.T) {
+	defer checkLeaks(t)()
+	setWorkDuration(t, time.Millisecond)
+
+	results, err := WorkerPoolsContext(context.Background(), 3, 5)
+	if err != nil {
+		t.Fatalf("got %v, wanted nil", err)
+	}
+	sum := 0
+	for _, r := range results {
+		sum += r
+	}
+	if sum != 30 {
+		t.Errorf("got sum %d, wanted 30", sum)
+	}
+}
+
+func TestWorkerPoolsContextCancel(t *testing.T) {
+	defer checkLeaks(t)()
+	setWorkDuration(t, time.Hour)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	results, err := WorkerPoolsContext(ctx, 3, 5)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v, wanted %v", err, context.DeadlineExceeded)
+	}
+	if len(results) != 0 {
+		t.Errorf("got %v, wanted no results", results)
+	}
+}
+
+func TestTickersContext(t *testing.T) {
+	defer checkLeaks(t)()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ticks := 0
+	err := TickersContext(ctx, time.Millisecond, func(time.Time) {
+		ticks++
+		if ticks == 3 {
+			cancel()
+		}
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v, wanted %v", err, context.Canceled)
+	}
+	if ticks != 3 {
+		t.Errorf("got %d ticks, wanted 3", ticks)
+	}
+}
+
+func TestRateLimitingContext(t *testing.T) {
+	defer checkLeaks(t)()
+
+	requests := make(chan int, 5)
+	for i := 1; i <= 5; i++ {
+		requests <- i
+	}
+	close(requests)
+
+	served := 0
+	err := RateLimitingContext(context.Background(), requests, time.Millisecond, 3, func(int) {
+		served++
+	})
+	if err != nil {
+		t.Errorf("got %v, wanted nil", err)
+	}
+	if served != 5 {
+		t.Errorf("served %d requests, wanted 5", served)
+	}
+}
+
+func TestRateLimitingContextCancel(t *testing.T) {
+	defer checkLeaks(t)()
+
+	// Never closed, only the context can stop the limiter
+	requests := make(chan int, 5)
+	for i := 1; i <= 5; i++ {
+		requests <- i
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := 0
+	err := RateLimitingContext(ctx, requests, time.Hour, 2, func(int) {
+		served++
+		if served == 2 {
+			cancel()
+		}
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v, wanted %v", err, context.Canceled)
+	}
+	if served != 2 {
+		t.Errorf("served %d requests, wanted 2", served)
+	}
+}
+
+func TestStateOwner(t *testing.T) {
+	defer checkLeaks(t)()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	owner := NewStateOwner(ctx)
+	if err := owner.Write(ctx, 1, 42); err != nil {
+		t.Fatalf("Write returned %v", err)
+	}
+	if v, err := owner.Read(ctx, 1); err != nil || v != 42 {
+		t.Errorf("got %d %v, wanted 42 nil", v, err)
+	}
+	if err := owner.Err(); err != nil {
+		t.Errorf("got %v while running, wanted nil", err)
+	}
+
+	cancel()
+	<-owner.Done()
+	if err := owner.Err(); !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v, wanted %v", err, context.Canceled)
+	}
+	if _, err := owner.Read(context.Background(), 1); !errors.Is(err, context.Canceled) {
+		t.Errorf("Read after stop got %v, wanted %v", err, context.Canceled)
+	}
+}
+
+func TestMutexesAndStatefulGoroutinesDoNotLeak(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs for two seconds")
+	}
+	defer checkLeaks(t)()
+	Mutexes()
+	StatefulGoroutines()
+}
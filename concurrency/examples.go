@@ -0,0 +1,50 @@
+package concurrency
+
+import "github.com/vrnvu/go-examples/registry"
+
+const category = "concurrency"
+
+// Every example of the package registers itself so main can run it by name
+func init() {
+	for _, e := range []registry.Example{
+		{Name: "Goroutines", Description: "direct call against go statement", Run: Goroutines},
+		{Name: "Channels", Description: "unbuffered send and receive", Run: Channels},
+		{Name: "ChannelBuffering", Description: "buffered channel of size 2", Run: ChannelBuffering},
+		{Name: "ChannelSync", Description: "wait for a goroutine with a done channel", Run: ChannelSync},
+		{Name: "ChannelDirections", Description: "send only and receive only channels", Run: ChannelDirections},
+		{Name: "Select", Description: "select over two channels", Run: Select},
+		{Name: "Timeouts", Description: "select with time.After", Run: Timeouts},
+		{Name: "NonBlockingChannelOperations", Description: "select with a default case", Run: NonBlockingChannelOperations},
+		{Name: "ClosingChannels", Description: "close a jobs channel to signal completion", Run: ClosingChannels},
+		{Name: "RangeOverChannels", Description: "range over a closed buffered channel", Run: RangeOverChannels},
+		{Name: "RangeOverChannelsWorker", Description: "range over a channel from a worker goroutine", Run: RangeOverChannelsWorker},
+		{Name: "Timers", Description: "fire and stop timers", Run: Timers},
+		{Name: "Tickers", Description: "ticker loop with a done channel", Run: Tickers},
+		{Name: "WorkerPools", Description: "3 workers consuming 5 jobs", Run: WorkerPools},
+		{Name: "GenericWorkerPools", Description: "WorkerPools on top of the generic pool package", Run: GenericWorkerPools},
+		{Name: "WaitGroups", Description: "wait for workers with sync.WaitGroup", Run: WaitGroups},
+		{Name: "WaitGroupsExtended", Description: "worker pool awaited with a WaitGroup", Run: WaitGroupsExtended},
+		{Name: "RateLimiting", Description: "steady and bursty rate limiters", Run: RateLimiting},
+		{Name: "TokenBucketRateLimiting", Description: "bursty limiter on top of the ratelimit package", Run: TokenBucketRateLimiting},
+		{Name: "AtomicCounters", Description: "counter shared through sync/atomic", Run: AtomicCounters},
+		{Name: "Mutexes", Description: "map state guarded by a mutex", Run: Mutexes},
+		{Name: "StatefulGoroutines", Description: "map state owned by a single goroutine", Run: StatefulGoroutines},
+		{Name: "StatefulStore", Description: "StatefulGoroutines workload on the kv package", Run: StatefulStore},
+		{Name: "CompareStores", Description: "ops/sec of the actor, mutex, rwmutex and sharded kv stores", Run: CompareStores},
+		{Name: "Supervisors", Description: "workers and a state owner that panic, restarted one-for-one and one-for-all", Run: Supervisors},
+		{Name: "ScheduledJobs", Description: "every, cron, one-shot and jittered jobs on a timer wheel scheduler", Run: ScheduledJobs},
+		{Name: "Contexts", Description: "worker pool, ticker, rate limiter and state owner stopped by a context", Run: Contexts},
+		{Name: "BadThreadBroadcastPattern", Description: "busy waiting on a mutex", Run: BadThreadBroadcastPattern},
+		{Name: "CondThreadBroadcastPattern", Description: "waiting with sync.Cond and Broadcast", Run: CondThreadBroadcastPattern},
+		{Name: "MapReduce", Description: "count students and sum their ages, -h for inputs, formats and sinks", Main: MapReduceMain},
+		{Name: "SortStudents", Description: "sort the students with an external merge sort, -sort and -run-size flags", Main: SortStudentsMain},
+		{Name: "MapReduceStats", Description: "count, sum, min, max, mean, median, p90 and histogram of students.csv ages", Main: MapReduceStatsMain},
+		{Name: "OneProcessor", Description: "scheduler with GOMAXPROCS(1)", Run: OneProcessor},
+		{Name: "TwoProcessor", Description: "scheduler with GOMAXPROCS(2)", Run: TwoProcessor},
+		{Name: "DefaultProcessor", Description: "scheduler with the default GOMAXPROCS", Run: DefaultProcessor},
+		{Name: "RaceConditionDetector", Description: "a data race, build with -race", Run: RaceConditionDetector},
+	} {
+		e.Category = category
+		registry.Register(e)
+	}
+}
@@ -1,13 +1,23 @@
 package concurrency
 
 import (
+	"context"
 	"fmt"
 	"math/rand"
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"github.com/vrnvu/go-examples/concurrency/clock"
+	"github.com/vrnvu/go-examples/concurrency/kv"
+	"github.com/vrnvu/go-examples/concurrency/pool"
+	"github.com/vrnvu/go-examples/concurrency/ratelimit"
 )
 
+// clk is the clock of every example that sleeps, waits or ticks, tests
+// replace it with a clock.Fake
+var clk clock.Clock = clock.New()
+
 func f(from string) {
 	for i := 0; i < 3; i++ {
 		fmt.Println(from, ":", i)
@@ -23,7 +33,7 @@ func Goroutines() {
 		fmt.Println(msg)
 	}("lambda going")
 
-	time.Sleep(time.Second)
+	clk.Sleep(time.Second)
 
 	fmt.Println("done")
 }
@@ -72,7 +82,7 @@ func ChannelBuffering() {
 
 func worker(done chan bool) {
 	fmt.Println("Working..")
-	time.Sleep(time.Second)
+	clk.Sleep(time.Second)
 	fmt.Println("done")
 	done <- true
 }
@@ -115,12 +125,12 @@ func Select() {
 	c2 := make(chan string)
 
 	go func() {
-		time.Sleep(1 * time.Second)
+		clk.Sleep(1 * time.Second)
 		c1 <- "one"
 	}()
 
 	go func() {
-		time.Sleep(2 * time.Second)
+		clk.Sleep(2 * time.Second)
 		c2 <- "two"
 	}()
 
@@ -143,15 +153,15 @@ func Timeouts() {
 	// This is a common patter to prevent leaks
 	c1 := make(chan string, 1)
 	go func() {
-		time.Sleep(2 * time.Second)
+		clk.Sleep(2 * time.Second)
 		c1 <- "result 1"
 	}()
 
-	// Our select awaits the result of <-time.After
+	// Our select awaits the result of <-clk.After, time.After on the real clock
 	select {
 	case res := <-c1:
 		fmt.Println(res)
-	case <-time.After(1 * time.Second):
+	case <-clk.After(1 * time.Second):
 		fmt.Println("timeout 1")
 	}
 
@@ -160,7 +170,7 @@ func Timeouts() {
 	// we would obtain the result 1!
 	c2 := make(chan string, 1)
 	go func() {
-		time.Sleep(2 * time.Second)
+		clk.Sleep(2 * time.Second)
 		c2 <- "result 2"
 	}()
 
@@ -168,7 +178,7 @@ func Timeouts() {
 	select {
 	case res := <-c2:
 		fmt.Println(res)
-	case <-time.After(3 * time.Second):
+	case <-clk.After(3 * time.Second):
 		fmt.Println("timeout 2")
 	}
 
@@ -196,8 +206,9 @@ func NonBlockingChannelOperations() {
 	// here msg cannot be sent to the messages channel
 	// the channel has no buffer and there is no receiver
 	// therefore the default is selected
+	// A plain messages <- msg before the select would block forever, with
+	// no receiver it is a deadlock
 	msg := "hi"
-	messages <- msg
 	select {
 	case messages <- msg:
 		fmt.Println("sent message", msg)
@@ -292,16 +303,16 @@ func Timers() {
 	// Timers represent a single event in the future.
 	// You tell them how long you want to wait
 	// It produces a channel that will notify you
-	timer1 := time.NewTimer(2 * time.Second)
+	timer1 := clk.NewTimer(2 * time.Second)
 
 	// Blocks on the timer's channel C until it sends a value indicating
 	// that the timer fired
-	<-timer1.C
+	<-timer1.C()
 	fmt.Println("timer 1 fired")
 
-	timer2 := time.NewTimer(time.Second)
+	timer2 := clk.NewTimer(time.Second)
 	go func() {
-		<-timer2.C
+		<-timer2.C()
 		fmt.Println("timer 2 fired")
 	}()
 	// A big difference with a sleep is that you can stop a timer
@@ -309,12 +320,12 @@ func Timers() {
 	if stop2 {
 		fmt.Println("timer 2 stopped")
 	}
-	time.Sleep(2 * time.Second)
+	clk.Sleep(2 * time.Second)
 }
 
 func Tickers() {
 	// A ticker is a channel that sends values
-	ticker := time.NewTicker(500 * time.Millisecond)
+	ticker := clk.NewTicker(500 * time.Millisecond)
 	done := make(chan bool)
 
 	// We await values with a ticker.C
@@ -325,13 +336,13 @@ func Tickers() {
 			select {
 			case <-done:
 				return
-			case t := <-ticker.C:
+			case t := <-ticker.C():
 				fmt.Println("Tick at", t)
 			}
 		}
 	}()
 
-	time.Sleep(1600 * time.Millisecond)
+	clk.Sleep(1600 * time.Millisecond)
 	// Tickers can be stopped like Timers
 	ticker.Stop()
 	done <- true
@@ -343,7 +354,7 @@ func Worker(id int, jobs <-chan int, results chan<- int) {
 	// When jobs get closed it stops
 	for j := range jobs {
 		fmt.Println("worker", id, "started job", j)
-		time.Sleep(time.Second)
+		clk.Sleep(time.Second)
 		fmt.Println("worker", id, "finished job", j)
 		results <- j * 2
 	}
@@ -377,12 +388,39 @@ func WorkerPools() {
 
 }
 
+// GenericWorkerPools runs the WorkerPools jobs through pool.Pool
+// The worker loop, the channels and the wait are handled by the package
+// and we only provide the body of Worker
+func GenericWorkerPools() {
+	p := pool.New(context.Background(), func(ctx context.Context, j int) (int, error) {
+		fmt.Println("started job", j)
+		clk.Sleep(time.Second)
+		fmt.Println("finished job", j)
+		return j * 2, nil
+	}, pool.WithWorkers(3), pool.WithOrdered())
+
+	go func() {
+		for j := 1; j <= 5; j++ {
+			p.Submit(j)
+		}
+		p.Close()
+	}()
+
+	// Results arrive in submission order thanks to WithOrdered
+	for r := range p.Results() {
+		fmt.Println("result", r.Seq, r.Value)
+	}
+	if err := p.Wait(); err != nil {
+		fmt.Println("error:", err)
+	}
+}
+
 func WorkerWait(id int, wg *sync.WaitGroup) {
 	// We pass our WaitGroup pointer
 	// On return we notify that we are done
 	defer wg.Done()
 	fmt.Printf("worker %d starting\n", id)
-	time.Sleep(time.Second)
+	clk.Sleep(time.Second)
 	fmt.Printf("worker %d done\n", id)
 }
 
@@ -404,7 +442,7 @@ func WorkerWaitExtended(id int, jobs <-chan int, wg *sync.WaitGroup) {
 	defer wg.Done()
 	for j := range jobs {
 		fmt.Println("worker", id, "started job", j)
-		time.Sleep(time.Second)
+		clk.Sleep(time.Second)
 		fmt.Println("worker", id, "finished job", j)
 	}
 
@@ -441,25 +479,40 @@ func RateLimiting() {
 	close(requests)
 
 	// Limiter channel will receive a value every 200 ms
-	limiter := time.Tick(200 * time.Millisecond)
+	limiter := clk.NewTicker(200 * time.Millisecond)
+	defer limiter.Stop()
 
 	// By blocking the limiter, we limit ourselves to 1 req every 200ms
 	for req := range requests {
-		<-limiter
-		fmt.Println("request", req, time.Now())
+		<-limiter.C()
+		fmt.Println("request", req, clk.Now())
 	}
 
 	// We may want to allow bursts of requests
 	// This channel allows bursts of 3 messages
 	burstyLimiter := make(chan time.Time, 3)
 	for i := 0; i < 3; i++ {
-		burstyLimiter <- time.Now()
+		burstyLimiter <- clk.Now()
 	}
 
 	// Every 200 ms we try to add a message to burstyLimiter up to its limit of 3
+	// The refill goroutine and its ticker stop when we return
+	refill := clk.NewTicker(200 * time.Millisecond)
+	defer refill.Stop()
+	done := make(chan struct{})
+	defer close(done)
 	go func() {
-		for t := range time.Tick(200 * time.Millisecond) {
-			burstyLimiter <- t
+		for {
+			select {
+			case <-done:
+				return
+			case t := <-refill.C():
+				select {
+				case burstyLimiter <- t:
+				case <-done:
+					return
+				}
+			}
 		}
 	}()
 
@@ -472,7 +525,23 @@ func RateLimiting() {
 	// The first 3 messages will benefit form burstyLimiter
 	for req := range burstyRequests {
 		<-burstyLimiter
-		fmt.Println("request", req, time.Now())
+		fmt.Println("request", req, clk.Now())
+	}
+}
+
+// TokenBucketRateLimiting serves the bursty requests of RateLimiting
+// with ratelimit.Limiter, there is no refill goroutine left behind
+func TokenBucketRateLimiting() {
+	limiter := ratelimit.New(ratelimit.Every(200*time.Millisecond), 3, ratelimit.WithClock(clk))
+	defer limiter.Stop()
+
+	// The first 3 requests use the burst, the rest wait 200ms each
+	for req := 1; req <= 5; req++ {
+		if err := limiter.Wait(context.Background()); err != nil {
+			fmt.Println("error:", err)
+			return
+		}
+		fmt.Println("request", req, clk.Now())
 	}
 }
 
@@ -501,34 +570,45 @@ func Mutexes() {
 	var readOps uint64
 	var writeOps uint64
 
+	// The goroutines loop until the context expires
+	// Without it they would keep running after we return
+	ctx, cancel := clock.WithTimeout(clk, context.Background(), time.Second)
+	defer cancel()
+	var wg sync.WaitGroup
+
 	for r := 0; r < 100; r++ {
+		wg.Add(1)
 		go func() {
+			defer wg.Done()
 			total := 0
-			for {
+			for ctx.Err() == nil {
 				key := rand.Intn(5)
 				mutex.Lock()
 				total += state[key]
 				mutex.Unlock()
 				atomic.AddUint64(&readOps, 1)
-				time.Sleep(time.Millisecond)
+				clock.Wait(ctx, clk, time.Millisecond)
 			}
 		}()
 	}
 	for w := 0; w < 10; w++ {
+		wg.Add(1)
 		go func() {
-			for {
+			defer wg.Done()
+			for ctx.Err() == nil {
 				key := rand.Intn(5)
 				val := rand.Intn(100)
 				mutex.Lock()
 				state[key] = val
 				mutex.Unlock()
 				atomic.AddUint64(&writeOps, 1)
-				time.Sleep(time.Millisecond)
+				clock.Wait(ctx, clk, time.Millisecond)
 			}
 		}()
 	}
 
-	time.Sleep(time.Second)
+	<-ctx.Done()
+	wg.Wait()
 
 	readOpsFinal := atomic.LoadUint64(&readOps)
 	fmt.Println("readOps:", readOpsFinal)
@@ -570,16 +650,25 @@ func StatefulGoroutines() {
 	reads := make(chan readOp)
 	writes := make(chan writeOp)
 
+	// Every goroutine, the owner included, stops when the context expires
+	ctx, cancel := clock.WithTimeout(clk, context.Background(), time.Second)
+	defer cancel()
+	var wg sync.WaitGroup
+
 	// Here is the goroutine that owns the state, which is a map, its private
 	// to this goroutine.
 	// The goroutine repeatedly selects on reads and writes channels, responding
 	// to requests as they arrive. A response is executed by first performing
 	// the requested operation and then sending a value on the response channel
 	// resp to indicate success
+	wg.Add(1)
 	go func() {
+		defer wg.Done()
 		var state = make(map[int]int)
 		for {
 			select {
+			case <-ctx.Done():
+				return
 			case read := <-reads:
 				read.resp <- state[read.key]
 			case write := <-writes:
@@ -590,34 +679,47 @@ func StatefulGoroutines() {
 	}()
 
 	for r := 0; r < 100; r++ {
+		wg.Add(1)
 		go func() {
+			defer wg.Done()
 			for {
 				read := readOp{
 					key:  rand.Intn(5),
 					resp: make(chan int)}
-				reads <- read
+				select {
+				case reads <- read:
+				case <-ctx.Done():
+					return
+				}
 				<-read.resp
 				atomic.AddUint64(&readOps, 1)
-				time.Sleep(time.Millisecond)
+				clock.Wait(ctx, clk, time.Millisecond)
 			}
 		}()
 	}
 	for w := 0; w < 10; w++ {
+		wg.Add(1)
 		go func() {
+			defer wg.Done()
 			for {
 				write := writeOp{
 					key:  rand.Intn(5),
 					val:  rand.Intn(100),
 					resp: make(chan bool)}
-				writes <- write
+				select {
+				case writes <- write:
+				case <-ctx.Done():
+					return
+				}
 				<-write.resp
 				atomic.AddUint64(&writeOps, 1)
-				time.Sleep(time.Millisecond)
+				clock.Wait(ctx, clk, time.Millisecond)
 			}
 		}()
 	}
 
-	time.Sleep(time.Second)
+	<-ctx.Done()
+	wg.Wait()
 
 	readOpsFinal := atomic.LoadUint64(&readOps)
 	fmt.Println("readOps:", readOpsFinal)
@@ -631,6 +733,69 @@ func StatefulGoroutines() {
 
 }
 
+// StatefulStore runs the StatefulGoroutines workload on kv.Store
+// The owner goroutine, the request structs and the counters live in the package
+func StatefulStore() {
+	store := kv.NewActor[int, int]()
+	defer store.Close()
+
+	ctx, cancel := clock.WithTimeout(clk, context.Background(), time.Second)
+	defer cancel()
+	var wg sync.WaitGroup
+
+	for r := 0; r < 100; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				if _, _, err := store.Get(ctx, rand.Intn(5)); err != nil {
+					return
+				}
+				clock.Wait(ctx, clk, time.Millisecond)
+			}
+		}()
+	}
+	for w := 0; w < 10; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				if err := store.Put(ctx, rand.Intn(5), rand.Intn(100)); err != nil {
+					return
+				}
+				clock.Wait(ctx, clk, time.Millisecond)
+			}
+		}()
+	}
+	wg.Wait()
+
+	stats := store.Stats()
+	fmt.Println("readOps:", stats.Gets)
+	fmt.Println("writeOps:", stats.Puts)
+}
+
+// CompareStores replays the Mutexes and StatefulGoroutines workload on
+// every kv.Store implementation and prints their throughput
+func CompareStores() {
+	stores := []struct {
+		name  string
+		store kv.Store[int, int]
+	}{
+		{"actor", kv.NewActor[int, int]()},
+		{"mutex", kv.NewMutex[int, int]()},
+		{"rwmutex", kv.NewRWMutex[int, int]()},
+		{"sharded", kv.NewSharded[int, int](0)},
+	}
+	for _, s := range stores {
+		w := kv.ExampleWorkload
+		w.Clock = clk
+		report := kv.Replay(context.Background(), s.store, w)
+		s.store.Close()
+		fmt.Printf("%-8s reads: %7d writes: %6d ops/sec: %10.0f\n",
+			s.name, report.Reads, report.Writes, report.OpsPerSec())
+	}
+}
+
 // BadThreadBroadcastPattern for is blocking the cpu
 // Active waiting
 func BadThreadBroadcastPattern() {
@@ -0,0 +1,178 @@
+package concurrency
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vrnvu/go-examples/concurrency/clock"
+)
+
+var epoch = time.Unix(0, 0).UTC()
+
+// setClock replaces clk with a fake clock for the test
+func setClock(t *testing.T) *clock.Fake {
+	fake := clock.NewFake(epoch)
+	old := clk
+	clk = fake
+	t.Cleanup(func() { clk = old })
+	return fake
+}
+
+// output runs example with its stdout redirected and returns the lines it
+// prints, the channel is closed once example returns
+func output(t *testing.T, example func()) <-chan string {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	t.Cleanup(func() { os.Stdout = stdout })
+
+	go func() {
+		defer w.Close()
+		example()
+	}()
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		defer r.Close()
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+	return lines
+}
+
+// expect reads the next line and compares it with want
+func expect(t *testing.T, lines <-chan string, want string) {
+	t.Helper()
+	select {
+	case got, ok := <-lines:
+		if !ok {
+			t.Fatalf("example returned, wanted %q", want)
+		}
+		if got != want {
+			t.Fatalf("got %q, wanted %q", got, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no output, wanted %q", want)
+	}
+}
+
+// expectDone waits for the example to return with nothing more printed
+func expectDone(t *testing.T, lines <-chan string) {
+	t.Helper()
+	for line := range lines {
+		t.Errorf("unexpected %q", line)
+	}
+}
+
+func TestTimeouts(t *testing.T) {
+	fake := setClock(t)
+	lines := output(t, Timeouts)
+
+	// the worker sleeps 2s and the select waits 1s
+	fake.BlockUntil(2)
+	fake.Advance(time.Second)
+	expect(t, lines, "timeout 1")
+
+	// the first worker is still asleep, the second sleeps 2s and the
+	// select waits 3s
+	fake.BlockUntil(3)
+	fake.Advance(2 * time.Second)
+	expect(t, lines, "result 2")
+	expectDone(t, lines)
+	if fake.Waiters() != 1 {
+		t.Errorf("got %d waiters, wanted the unused 3s timeout", fake.Waiters())
+	}
+}
+
+func TestTickers(t *testing.T) {
+	fake := setClock(t)
+	lines := output(t, Tickers)
+
+	// the ticker and the 1600ms sleep
+	fake.BlockUntil(2)
+	for i := 1; i <= 3; i++ {
+		fake.Advance(500 * time.Millisecond)
+		expect(t, lines, fmt.Sprint("Tick at ", epoch.Add(time.Duration(i)*500*time.Millisecond)))
+	}
+	fake.Advance(100 * time.Millisecond)
+	expect(t, lines, "ticker stopped")
+	expectDone(t, lines)
+	if fake.Waiters() != 0 {
+		t.Errorf("ticker not stopped")
+	}
+}
+
+func TestRateLimiting(t *testing.T) {
+	defer checkLeaks(t)()
+	fake := setClock(t)
+	lines := output(t, RateLimiting)
+
+	// one request every 200ms
+	fake.BlockUntil(1)
+	for req := 1; req <= 5; req++ {
+		fake.Advance(200 * time.Millisecond)
+		expect(t, lines, fmt.Sprint("request ", req, " ", fake.Now()))
+	}
+
+	// 3 requests at once from the burst, then one every 200ms
+	second := epoch.Add(time.Second)
+	for req := 1; req <= 3; req++ {
+		expect(t, lines, fmt.Sprint("request ", req, " ", second))
+	}
+	// the limiter ticker and the refill ticker
+	fake.BlockUntil(2)
+	for req := 4; req <= 5; req++ {
+		fake.Advance(200 * time.Millisecond)
+		expect(t, lines, fmt.Sprint("request ", req, " ", fake.Now()))
+	}
+	expectDone(t, lines)
+	if elapsed := fake.Now().Sub(epoch); elapsed != 1400*time.Millisecond {
+		t.Errorf("took %v of fake time, wanted 1.4s", elapsed)
+	}
+	if fake.Waiters() != 0 {
+		t.Errorf("got %d tickers left running", fake.Waiters())
+	}
+}
+
+func TestMutexesFakeClock(t *testing.T) {
+	defer checkLeaks(t)()
+	fake := setClock(t)
+	lines := output(t, Mutexes)
+
+	// the deadline and the pause of every reader and writer
+	fake.BlockUntil(111)
+	fake.Advance(time.Second)
+	for _, prefix := range []string{"readOps:", "writeOps:", "state:"} {
+		select {
+		case line := <-lines:
+			if !strings.HasPrefix(line, prefix) {
+				t.Errorf("got %q, wanted %s", line, prefix)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Mutexes did not stop on the fake deadline")
+		}
+	}
+	expectDone(t, lines)
+}
+
+func TestTimers(t *testing.T) {
+	fake := setClock(t)
+	lines := output(t, Timers)
+
+	fake.BlockUntil(1)
+	fake.Advance(2 * time.Second)
+	expect(t, lines, "timer 1 fired")
+	expect(t, lines, "timer 2 stopped")
+	fake.BlockUntil(1)
+	fake.Advance(2 * time.Second)
+	expectDone(t, lines)
+}
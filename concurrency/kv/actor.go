@@ -0,0 +1,149 @@
+package kv
+
+import (
+	"context"
+	"sync"
+)
+
+type request[K, V comparable] struct {
+	ops []Op[K, V]
+	// snapshot asks for a copy of the map instead of running ops
+	snapshot bool
+	resp     chan response[K, V]
+}
+
+type response[K, V comparable] struct {
+	results []Result[V]
+	state   map[K]V
+}
+
+// Actor is a map owned by a goroutine, the StatefulGoroutines version
+type Actor[K, V comparable] struct {
+	counters
+
+	requests chan request[K, V]
+	quit     chan struct{}
+	done     chan struct{}
+	close    sync.Once
+}
+
+// NewActor starts the goroutine owning the state, call Close to stop it
+func NewActor[K, V comparable]() *Actor[K, V] {
+	s := &Actor[K, V]{
+		requests: make(chan request[K, V]),
+		quit:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go s.own()
+	return s
+}
+
+// own is the only goroutine touching state
+func (s *Actor[K, V]) own() {
+	defer close(s.done)
+	state := make(map[K]V)
+	for {
+		select {
+		case <-s.quit:
+			return
+		case req := <-s.requests:
+			if req.snapshot {
+				snapshot := make(map[K]V, len(state))
+				for k, v := range state {
+					snapshot[k] = v
+				}
+				req.resp <- response[K, V]{state: snapshot}
+				continue
+			}
+			results := make([]Result[V], len(req.ops))
+			for i, op := range req.ops {
+				results[i] = apply(&s.counters, state, op)
+			}
+			req.resp <- response[K, V]{results: results}
+		}
+	}
+}
+
+// send hands a request to the owner and waits for the response
+func (s *Actor[K, V]) send(ctx context.Context, req request[K, V]) (response[K, V], error) {
+	// Buffered so the owner never blocks on a caller that gave up
+	req.resp = make(chan response[K, V], 1)
+	select {
+	case s.requests <- req:
+	case <-s.quit:
+		return response[K, V]{}, ErrClosed
+	case <-ctx.Done():
+		return response[K, V]{}, ctx.Err()
+	}
+	// Once accepted the owner always answers
+	return <-req.resp, nil
+}
+
+// Batch runs the operations in order, no other operation runs in between
+func (s *Actor[K, V]) Batch(ctx context.Context, ops ...Op[K, V]) ([]Result[V], error) {
+	resp, err := s.send(ctx, request[K, V]{ops: ops})
+	if err != nil {
+		return nil, err
+	}
+	s.batches.Add(1)
+	return resp.results, nil
+}
+
+func (s *Actor[K, V]) one(ctx context.Context, op Op[K, V]) (Result[V], error) {
+	resp, err := s.send(ctx, request[K, V]{ops: []Op[K, V]{op}})
+	if err != nil {
+		return Result[V]{}, err
+	}
+	return resp.results[0], nil
+}
+
+// Get returns the value stored under key and whether it was found
+func (s *Actor[K, V]) Get(ctx context.Context, key K) (V, bool, error) {
+	r, err := s.one(ctx, Op[K, V]{Kind: OpGet, Key: key})
+	return r.Value, r.OK, err
+}
+
+// Put stores value under key
+func (s *Actor[K, V]) Put(ctx context.Context, key K, value V) error {
+	_, err := s.one(ctx, Op[K, V]{Kind: OpPut, Key: key, Value: value})
+	return err
+}
+
+// Delete removes key and reports whether it was present
+func (s *Actor[K, V]) Delete(ctx context.Context, key K) (bool, error) {
+	r, err := s.one(ctx, Op[K, V]{Kind: OpDelete, Key: key})
+	return r.OK, err
+}
+
+// CompareAndSwap stores new under key only if the current value is old
+func (s *Actor[K, V]) CompareAndSwap(ctx context.Context, key K, old, new V) (bool, error) {
+	r, err := s.one(ctx, Op[K, V]{Kind: OpCompareAndSwap, Key: key, Old: old, Value: new})
+	return r.OK, err
+}
+
+// Range calls fn for every entry of a snapshot of the store until fn
+// returns false. fn runs outside the owner so it can use the store
+func (s *Actor[K, V]) Range(ctx context.Context, fn func(key K, value V) bool) error {
+	resp, err := s.send(ctx, request[K, V]{snapshot: true})
+	if err != nil {
+		return err
+	}
+	s.ranges.Add(1)
+	for k, v := range resp.state {
+		if !fn(k, v) {
+			break
+		}
+	}
+	return nil
+}
+
+// Close stops the owner goroutine once the request being served is
+// answered, later operations return ErrClosed. It is safe to call Close
+// more than once
+func (s *Actor[K, V]) Close() error {
+	s.close.Do(func() {
+		close(s.quit)
+	})
+	<-s.done
+	return nil
+}
@@ -0,0 +1,127 @@
+// Package kv turns the map workload of the Mutexes and StatefulGoroutines
+// examples into key value stores sharing the Store interface
+//
+//   - Actor: the map is owned by a single goroutine and every operation is
+//     a request sent over a channel, as in StatefulGoroutines
+//   - Mutex: the map is guarded by a sync.Mutex, as in Mutexes
+//   - RWMutex: readers share a sync.RWMutex
+//   - Sharded: the keys are spread over N maps with their own RWMutex
+//
+// Replay runs the workload of the examples against any of them
+package kv
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+)
+
+// ErrClosed is returned by every operation once the store is closed
+var ErrClosed = errors.New("kv: store closed")
+
+// Store is the interface shared by every implementation
+// The context only matters to the Actor, the lock based stores never block
+// on anything but their locks
+type Store[K, V comparable] interface {
+	// Get returns the value stored under key and whether it was found
+	Get(ctx context.Context, key K) (V, bool, error)
+	// Put stores value under key
+	Put(ctx context.Context, key K, value V) error
+	// Delete removes key and reports whether it was present
+	Delete(ctx context.Context, key K) (bool, error)
+	// CompareAndSwap stores new under key only if the current value is old
+	CompareAndSwap(ctx context.Context, key K, old, new V) (bool, error)
+	// Batch runs the operations in order, no other operation on the same
+	// keys runs in between
+	Batch(ctx context.Context, ops ...Op[K, V]) ([]Result[V], error)
+	// Range calls fn for every entry of a snapshot until fn returns false,
+	// fn may use the store
+	Range(ctx context.Context, fn func(key K, value V) bool) error
+	// Stats returns the operation counters
+	Stats() Stats
+	// Close releases the store, later operations return ErrClosed
+	Close() error
+}
+
+// OpKind is the kind of a batched operation
+type OpKind int
+
+const (
+	OpGet OpKind = iota
+	OpPut
+	OpDelete
+	OpCompareAndSwap
+)
+
+// Op is one operation of a Batch
+// Value is the value to Put or the new value of a CompareAndSwap,
+// Old is the value CompareAndSwap expects
+type Op[K, V comparable] struct {
+	Kind  OpKind
+	Key   K
+	Value V
+	Old   V
+}
+
+// Result is the outcome of one Op
+// For OpGet Value is the stored value and OK reports if it was found,
+// for OpPut and OpDelete Value is the previous value and OK reports if
+// there was one, for OpCompareAndSwap OK reports if the swap happened
+type Result[V comparable] struct {
+	Value V
+	OK    bool
+}
+
+// Stats counts the operations served by a store, like the readOps and
+// writeOps counters of StatefulGoroutines
+type Stats struct {
+	Gets    uint64
+	Puts    uint64
+	Deletes uint64
+	CASs    uint64
+	Ranges  uint64
+	Batches uint64
+}
+
+// counters is embedded by every store to implement Stats
+type counters struct {
+	gets, puts, deletes, cass, ranges, batches atomic.Uint64
+}
+
+// Stats returns the operation counters
+func (c *counters) Stats() Stats {
+	return Stats{
+		Gets:    c.gets.Load(),
+		Puts:    c.puts.Load(),
+		Deletes: c.deletes.Load(),
+		CASs:    c.cass.Load(),
+		Ranges:  c.ranges.Load(),
+		Batches: c.batches.Load(),
+	}
+}
+
+// apply runs op on state, the caller owns state while it runs
+func apply[K, V comparable](c *counters, state map[K]V, op Op[K, V]) Result[V] {
+	old, found := state[op.Key]
+	switch op.Kind {
+	case OpGet:
+		c.gets.Add(1)
+		return Result[V]{Value: old, OK: found}
+	case OpPut:
+		c.puts.Add(1)
+		state[op.Key] = op.Value
+		return Result[V]{Value: old, OK: found}
+	case OpDelete:
+		c.deletes.Add(1)
+		delete(state, op.Key)
+		return Result[V]{Value: old, OK: found}
+	case OpCompareAndSwap:
+		c.cass.Add(1)
+		if !found || old != op.Old {
+			return Result[V]{Value: old}
+		}
+		state[op.Key] = op.Value
+		return Result[V]{Value: old, OK: true}
+	}
+	return Result[V]{}
+}
@@ -0,0 +1,212 @@
+package kv
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vrnvu/go-examples/concurrency/clock"
+)
+
+// stores returns a constructor for every implementation of Store
+func stores[K, V comparable]() map[string]func() Store[K, V] {
+	return map[string]func() Store[K, V]{
+		"Actor":   func() Store[K, V] { return NewActor[K, V]() },
+		"Mutex":   func() Store[K, V] { return NewMutex[K, V]() },
+		"RWMutex": func() Store[K, V] { return NewRWMutex[K, V]() },
+		"Sharded": func() Store[K, V] { return NewSharded[K, V](4) },
+	}
+}
+
+func forEachStore[K, V comparable](t *testing.T, test func(t *testing.T, s Store[K, V])) {
+	for name, newStore := range stores[K, V]() {
+		t.Run(name, func(t *testing.T) {
+			s := newStore()
+			defer s.Close()
+			test(t, s)
+		})
+	}
+}
+
+func TestStoreOperations(t *testing.T) {
+	forEachStore(t, testStoreOperations)
+}
+
+func testStoreOperations(t *testing.T, s Store[string, int]) {
+	ctx := context.Background()
+
+	if _, ok, _ := s.Get(ctx, "a"); ok {
+		t.Errorf("Get on empty store found a value")
+	}
+	s.Put(ctx, "a", 1)
+	if v, ok, err := s.Get(ctx, "a"); v != 1 || !ok || err != nil {
+		t.Errorf("got %d %v %v, wanted 1 true nil", v, ok, err)
+	}
+
+	if swapped, _ := s.CompareAndSwap(ctx, "a", 2, 3); swapped {
+		t.Errorf("CompareAndSwap with a wrong old value swapped")
+	}
+	if swapped, _ := s.CompareAndSwap(ctx, "a", 1, 3); !swapped {
+		t.Errorf("CompareAndSwap with the right old value did not swap")
+	}
+	if swapped, _ := s.CompareAndSwap(ctx, "missing", 0, 3); swapped {
+		t.Errorf("CompareAndSwap on a missing key swapped")
+	}
+
+	if deleted, _ := s.Delete(ctx, "a"); !deleted {
+		t.Errorf("Delete did not find the key")
+	}
+	if deleted, _ := s.Delete(ctx, "a"); deleted {
+		t.Errorf("second Delete found the key")
+	}
+
+	want := Stats{Gets: 2, Puts: 1, Deletes: 2, CASs: 3}
+	if got := s.Stats(); got != want {
+		t.Errorf("got %+v, wanted %+v", got, want)
+	}
+}
+
+func TestStoreBatchAndRange(t *testing.T) {
+	forEachStore(t, testStoreBatchAndRange)
+}
+
+func testStoreBatchAndRange(t *testing.T, s Store[int, int]) {
+	ctx := context.Background()
+
+	results, err := s.Batch(ctx,
+		Op[int, int]{Kind: OpPut, Key: 1, Value: 10},
+		Op[int, int]{Kind: OpPut, Key: 2, Value: 20},
+		Op[int, int]{Kind: OpCompareAndSwap, Key: 1, Old: 10, Value: 11},
+		Op[int, int]{Kind: OpGet, Key: 1},
+	)
+	if err != nil {
+		t.Fatalf("Batch returned %v", err)
+	}
+	if !results[2].OK || results[3].Value != 11 {
+		t.Errorf("got %+v, wanted the swap to be visible to the get", results)
+	}
+
+	sum := 0
+	s.Range(ctx, func(k, v int) bool {
+		// fn runs outside the owner so it may call the store
+		s.Get(ctx, k)
+		sum += v
+		return true
+	})
+	if sum != 31 {
+		t.Errorf("got sum %d, wanted 31", sum)
+	}
+	if stats := s.Stats(); stats.Batches != 1 || stats.Ranges != 1 {
+		t.Errorf("got %+v, wanted one batch and one range", stats)
+	}
+}
+
+func TestStoreConcurrent(t *testing.T) {
+	forEachStore(t, testStoreConcurrent)
+}
+
+func testStoreConcurrent(t *testing.T, s Store[int, int]) {
+	ctx := context.Background()
+
+	// Every writer increments the same key with CompareAndSwap retries
+	s.Put(ctx, 0, 0)
+	var wg sync.WaitGroup
+	for w := 0; w < 10; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				for {
+					v, _, _ := s.Get(ctx, 0)
+					if ok, _ := s.CompareAndSwap(ctx, 0, v, v+1); ok {
+						break
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if v, _, _ := s.Get(ctx, 0); v != 1000 {
+		t.Errorf("got %d, wanted 1000", v)
+	}
+}
+
+func TestStoreClose(t *testing.T) {
+	forEachStore(t, testStoreClose)
+}
+
+func testStoreClose(t *testing.T, s Store[int, int]) {
+	ctx := context.Background()
+	s.Close()
+	s.Close()
+	if err := s.Put(ctx, 1, 1); !errors.Is(err, ErrClosed) {
+		t.Errorf("got %v, wanted %v", err, ErrClosed)
+	}
+	if _, _, err := s.Get(ctx, 1); !errors.Is(err, ErrClosed) {
+		t.Errorf("got %v, wanted %v", err, ErrClosed)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	w := Workload{Readers: 4, Writers: 2, Keys: 5, Pause: time.Millisecond, Duration: 50 * time.Millisecond}
+	forEachStore(t, func(t *testing.T, s Store[int, int]) {
+		report := Replay(context.Background(), s, w)
+		if report.Reads == 0 || report.Writes == 0 {
+			t.Errorf("got %+v, wanted reads and writes", report)
+		}
+		if stats := s.Stats(); stats.Gets < report.Reads || stats.Puts < report.Writes {
+			t.Errorf("got stats %+v for report %+v", stats, report)
+		}
+	})
+}
+
+func TestReplayDefaults(t *testing.T) {
+	fake := clock.NewFake(time.Unix(0, 0))
+	// no keys and no duration, the ones of ExampleWorkload
+	w := Workload{Readers: 2, Writers: 1, Clock: fake}
+	forEachStore(t, func(t *testing.T, s Store[int, int]) {
+		done := make(chan Report)
+		go func() {
+			done <- Replay(context.Background(), s, w)
+		}()
+		fake.BlockUntil(1)
+		// the keys are drawn, the readers and writers are running
+		for stats := s.Stats(); stats.Gets == 0 || stats.Puts == 0; stats = s.Stats() {
+			time.Sleep(time.Millisecond)
+		}
+		fake.Advance(ExampleWorkload.Duration - time.Millisecond)
+		select {
+		case report := <-done:
+			t.Fatalf("Replay returned early with %+v", report)
+		default:
+		}
+		fake.Advance(time.Millisecond)
+		report := <-done
+		if report.Reads == 0 || report.Writes == 0 || report.Elapsed != ExampleWorkload.Duration {
+			t.Errorf("got %+v, wanted reads and writes for %v", report, ExampleWorkload.Duration)
+		}
+	})
+}
+
+// BenchmarkStores replays the 100 readers and 10 writers of the examples
+// without the pause, run with
+// $ go test -bench Stores ./concurrency/kv
+func BenchmarkStores(b *testing.B) {
+	w := ExampleWorkload
+	w.Pause = 0
+	w.Duration = 200 * time.Millisecond
+	for _, name := range []string{"Actor", "Mutex", "RWMutex", "Sharded"} {
+		newStore := stores[int, int]()[name]
+		b.Run(name, func(b *testing.B) {
+			var ops float64
+			for i := 0; i < b.N; i++ {
+				s := newStore()
+				ops += Replay(context.Background(), s, w).OpsPerSec()
+				s.Close()
+			}
+			b.ReportMetric(ops/float64(b.N), "ops/s")
+		})
+	}
+}
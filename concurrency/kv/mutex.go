@@ -0,0 +1,203 @@
+package kv
+
+import (
+	"context"
+	"sync"
+)
+
+// Mutex is a map guarded by a single sync.Mutex, the Mutexes version
+type Mutex[K, V comparable] struct {
+	counters
+
+	mu     sync.Mutex
+	state  map[K]V
+	closed bool
+}
+
+// NewMutex returns an empty Mutex store
+func NewMutex[K, V comparable]() *Mutex[K, V] {
+	return &Mutex[K, V]{state: make(map[K]V)}
+}
+
+func (s *Mutex[K, V]) one(op Op[K, V]) (Result[V], error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return Result[V]{}, ErrClosed
+	}
+	return apply(&s.counters, s.state, op), nil
+}
+
+// Get returns the value stored under key and whether it was found
+func (s *Mutex[K, V]) Get(_ context.Context, key K) (V, bool, error) {
+	r, err := s.one(Op[K, V]{Kind: OpGet, Key: key})
+	return r.Value, r.OK, err
+}
+
+// Put stores value under key
+func (s *Mutex[K, V]) Put(_ context.Context, key K, value V) error {
+	_, err := s.one(Op[K, V]{Kind: OpPut, Key: key, Value: value})
+	return err
+}
+
+// Delete removes key and reports whether it was present
+func (s *Mutex[K, V]) Delete(_ context.Context, key K) (bool, error) {
+	r, err := s.one(Op[K, V]{Kind: OpDelete, Key: key})
+	return r.OK, err
+}
+
+// CompareAndSwap stores new under key only if the current value is old
+func (s *Mutex[K, V]) CompareAndSwap(_ context.Context, key K, old, new V) (bool, error) {
+	r, err := s.one(Op[K, V]{Kind: OpCompareAndSwap, Key: key, Old: old, Value: new})
+	return r.OK, err
+}
+
+// Batch runs the operations in order while holding the lock
+func (s *Mutex[K, V]) Batch(_ context.Context, ops ...Op[K, V]) ([]Result[V], error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil, ErrClosed
+	}
+	results := make([]Result[V], len(ops))
+	for i, op := range ops {
+		results[i] = apply(&s.counters, s.state, op)
+	}
+	s.batches.Add(1)
+	return results, nil
+}
+
+// Range calls fn for every entry of a snapshot until fn returns false
+func (s *Mutex[K, V]) Range(_ context.Context, fn func(key K, value V) bool) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return ErrClosed
+	}
+	snapshot := copyState(s.state)
+	s.mu.Unlock()
+
+	s.ranges.Add(1)
+	rangeState(snapshot, fn)
+	return nil
+}
+
+// Close drops the state, later operations return ErrClosed
+func (s *Mutex[K, V]) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	s.state = nil
+	return nil
+}
+
+// RWMutex is a map guarded by a sync.RWMutex, Get runs under the read lock
+// so readers do not wait for each other
+type RWMutex[K, V comparable] struct {
+	counters
+
+	mu     sync.RWMutex
+	state  map[K]V
+	closed bool
+}
+
+// NewRWMutex returns an empty RWMutex store
+func NewRWMutex[K, V comparable]() *RWMutex[K, V] {
+	return &RWMutex[K, V]{state: make(map[K]V)}
+}
+
+func (s *RWMutex[K, V]) one(op Op[K, V]) (Result[V], error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return Result[V]{}, ErrClosed
+	}
+	return apply(&s.counters, s.state, op), nil
+}
+
+// Get returns the value stored under key and whether it was found
+func (s *RWMutex[K, V]) Get(_ context.Context, key K) (V, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		var zero V
+		return zero, false, ErrClosed
+	}
+	// apply would be safe too, a get does not write the map
+	s.gets.Add(1)
+	v, ok := s.state[key]
+	return v, ok, nil
+}
+
+// Put stores value under key
+func (s *RWMutex[K, V]) Put(_ context.Context, key K, value V) error {
+	_, err := s.one(Op[K, V]{Kind: OpPut, Key: key, Value: value})
+	return err
+}
+
+// Delete removes key and reports whether it was present
+func (s *RWMutex[K, V]) Delete(_ context.Context, key K) (bool, error) {
+	r, err := s.one(Op[K, V]{Kind: OpDelete, Key: key})
+	return r.OK, err
+}
+
+// CompareAndSwap stores new under key only if the current value is old
+func (s *RWMutex[K, V]) CompareAndSwap(_ context.Context, key K, old, new V) (bool, error) {
+	r, err := s.one(Op[K, V]{Kind: OpCompareAndSwap, Key: key, Old: old, Value: new})
+	return r.OK, err
+}
+
+// Batch runs the operations in order while holding the write lock
+func (s *RWMutex[K, V]) Batch(_ context.Context, ops ...Op[K, V]) ([]Result[V], error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil, ErrClosed
+	}
+	results := make([]Result[V], len(ops))
+	for i, op := range ops {
+		results[i] = apply(&s.counters, s.state, op)
+	}
+	s.batches.Add(1)
+	return results, nil
+}
+
+// Range calls fn for every entry of a snapshot until fn returns false
+func (s *RWMutex[K, V]) Range(_ context.Context, fn func(key K, value V) bool) error {
+	s.mu.RLock()
+	if s.closed {
+		s.mu.RUnlock()
+		return ErrClosed
+	}
+	snapshot := copyState(s.state)
+	s.mu.RUnlock()
+
+	s.ranges.Add(1)
+	rangeState(snapshot, fn)
+	return nil
+}
+
+// Close drops the state, later operations return ErrClosed
+func (s *RWMutex[K, V]) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	s.state = nil
+	return nil
+}
+
+func copyState[K, V comparable](state map[K]V) map[K]V {
+	snapshot := make(map[K]V, len(state))
+	for k, v := range state {
+		snapshot[k] = v
+	}
+	return snapshot
+}
+
+func rangeState[K, V comparable](state map[K]V, fn func(key K, value V) bool) {
+	for k, v := range state {
+		if !fn(k, v) {
+			return
+		}
+	}
+}
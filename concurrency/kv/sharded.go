@@ -0,0 +1,150 @@
+package kv
+
+import (
+	"context"
+	"hash/maphash"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// Sharded spreads the keys over N RWMutex guarded maps
+// Operations on keys of different shards never wait for each other
+type Sharded[K, V comparable] struct {
+	counters
+
+	seed   maphash.Seed
+	shards []shard[K, V]
+}
+
+type shard[K, V comparable] struct {
+	mu     sync.RWMutex
+	state  map[K]V
+	closed bool
+}
+
+// NewSharded returns an empty store with n shards
+// n <= 0 uses one shard per CPU
+func NewSharded[K, V comparable](n int) *Sharded[K, V] {
+	if n <= 0 {
+		n = runtime.NumCPU()
+	}
+	s := &Sharded[K, V]{
+		seed:   maphash.MakeSeed(),
+		shards: make([]shard[K, V], n),
+	}
+	for i := range s.shards {
+		s.shards[i].state = make(map[K]V)
+	}
+	return s
+}
+
+func (s *Sharded[K, V]) index(key K) int {
+	return int(maphash.Comparable(s.seed, key) % uint64(len(s.shards)))
+}
+
+func (s *Sharded[K, V]) one(op Op[K, V]) (Result[V], error) {
+	sh := &s.shards[s.index(op.Key)]
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	if sh.closed {
+		return Result[V]{}, ErrClosed
+	}
+	return apply(&s.counters, sh.state, op), nil
+}
+
+// Get returns the value stored under key and whether it was found
+func (s *Sharded[K, V]) Get(_ context.Context, key K) (V, bool, error) {
+	sh := &s.shards[s.index(key)]
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+	if sh.closed {
+		var zero V
+		return zero, false, ErrClosed
+	}
+	s.gets.Add(1)
+	v, ok := sh.state[key]
+	return v, ok, nil
+}
+
+// Put stores value under key
+func (s *Sharded[K, V]) Put(_ context.Context, key K, value V) error {
+	_, err := s.one(Op[K, V]{Kind: OpPut, Key: key, Value: value})
+	return err
+}
+
+// Delete removes key and reports whether it was present
+func (s *Sharded[K, V]) Delete(_ context.Context, key K) (bool, error) {
+	r, err := s.one(Op[K, V]{Kind: OpDelete, Key: key})
+	return r.OK, err
+}
+
+// CompareAndSwap stores new under key only if the current value is old
+func (s *Sharded[K, V]) CompareAndSwap(_ context.Context, key K, old, new V) (bool, error) {
+	r, err := s.one(Op[K, V]{Kind: OpCompareAndSwap, Key: key, Old: old, Value: new})
+	return r.OK, err
+}
+
+// Batch locks every shard touched by ops, always in index order so two
+// batches can not deadlock, and runs the operations in order
+func (s *Sharded[K, V]) Batch(_ context.Context, ops ...Op[K, V]) ([]Result[V], error) {
+	touched := make(map[int]bool)
+	indexes := make([]int, 0)
+	for _, op := range ops {
+		if i := s.index(op.Key); !touched[i] {
+			touched[i] = true
+			indexes = append(indexes, i)
+		}
+	}
+	sort.Ints(indexes)
+	for _, i := range indexes {
+		s.shards[i].mu.Lock()
+		defer s.shards[i].mu.Unlock()
+	}
+	for _, i := range indexes {
+		if s.shards[i].closed {
+			return nil, ErrClosed
+		}
+	}
+
+	results := make([]Result[V], len(ops))
+	for i, op := range ops {
+		results[i] = apply(&s.counters, s.shards[s.index(op.Key)].state, op)
+	}
+	s.batches.Add(1)
+	return results, nil
+}
+
+// Range calls fn for every entry of a snapshot until fn returns false
+// The snapshot is taken shard by shard, it is not atomic across shards
+func (s *Sharded[K, V]) Range(_ context.Context, fn func(key K, value V) bool) error {
+	snapshot := make(map[K]V)
+	for i := range s.shards {
+		sh := &s.shards[i]
+		sh.mu.RLock()
+		if sh.closed {
+			sh.mu.RUnlock()
+			return ErrClosed
+		}
+		for k, v := range sh.state {
+			snapshot[k] = v
+		}
+		sh.mu.RUnlock()
+	}
+
+	s.ranges.Add(1)
+	rangeState(snapshot, fn)
+	return nil
+}
+
+// Close drops the state, later operations return ErrClosed
+func (s *Sharded[K, V]) Close() error {
+	for i := range s.shards {
+		sh := &s.shards[i]
+		sh.mu.Lock()
+		sh.closed = true
+		sh.state = nil
+		sh.mu.Unlock()
+	}
+	return nil
+}
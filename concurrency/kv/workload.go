@@ -0,0 +1,103 @@
+package kv
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/vrnvu/go-examples/concurrency/clock"
+)
+
+// Workload describes the map workload of Mutexes and StatefulGoroutines:
+// readers and writers hit random keys and pause between operations
+type Workload struct {
+	Readers int
+	Writers int
+	// Keys and Duration are the ones of ExampleWorkload when not positive
+	Keys     int
+	Pause    time.Duration
+	Duration time.Duration
+	// Clock times the pauses and the duration, clock.New() when nil
+	Clock clock.Clock
+}
+
+// ExampleWorkload is the workload of the examples, 100 readers and
+// 10 writers over 5 keys with a millisecond pause for one second
+var ExampleWorkload = Workload{
+	Readers:  100,
+	Writers:  10,
+	Keys:     5,
+	Pause:    time.Millisecond,
+	Duration: time.Second,
+}
+
+// Report is the outcome of a Replay
+type Report struct {
+	Reads   uint64
+	Writes  uint64
+	Elapsed time.Duration
+}
+
+// OpsPerSec returns the reads and writes per second
+func (r Report) OpsPerSec() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(r.Reads+r.Writes) / r.Elapsed.Seconds()
+}
+
+// Replay runs w against store until w.Duration elapses or ctx is done
+func Replay(ctx context.Context, store Store[int, int], w Workload) Report {
+	c := w.Clock
+	if c == nil {
+		c = clock.New()
+	}
+	// rand.Intn panics without keys and a zero timeout is over right away
+	if w.Keys <= 0 {
+		w.Keys = ExampleWorkload.Keys
+	}
+	if w.Duration <= 0 {
+		w.Duration = ExampleWorkload.Duration
+	}
+	ctx, cancel := clock.WithTimeout(c, ctx, w.Duration)
+	defer cancel()
+
+	var reads, writes uint64
+	var wg sync.WaitGroup
+	worker := func(op func() error, ops *uint64) {
+		defer wg.Done()
+		for ctx.Err() == nil {
+			if op() != nil {
+				return
+			}
+			atomic.AddUint64(ops, 1)
+			if w.Pause > 0 {
+				clock.Wait(ctx, c, w.Pause)
+			}
+		}
+	}
+
+	start := c.Now()
+	for r := 0; r < w.Readers; r++ {
+		wg.Add(1)
+		go worker(func() error {
+			_, _, err := store.Get(ctx, rand.Intn(w.Keys))
+			return err
+		}, &reads)
+	}
+	for i := 0; i < w.Writers; i++ {
+		wg.Add(1)
+		go worker(func() error {
+			return store.Put(ctx, rand.Intn(w.Keys), rand.Intn(100))
+		}, &writes)
+	}
+	wg.Wait()
+
+	return Report{
+		Reads:   atomic.LoadUint64(&reads),
+		Writes:  atomic.LoadUint64(&writes),
+		Elapsed: c.Now().Sub(start),
+	}
+}
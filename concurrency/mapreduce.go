@@ -1,56 +1,386 @@
 package concurrency
 
 import (
-	"encoding/csv"
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"os"
 	"strconv"
+	"strings"
+
+	"github.com/vrnvu/go-examples/concurrency/mapreduce"
+	"github.com/vrnvu/go-examples/lang/sorting"
 )
 
-type Student struct {
-	name string `json: "name"`
-	age  int    `json: "age"`
+func mapStudent(_ context.Context, s Student, emit func(string, int)) error {
+	emit("students", 1)
+	emit("age", s.Age)
+	return nil
+}
+
+func combineSum(_ string, a, b int) int {
+	return a + b
+}
+
+func reduceSumAge(_ context.Context, _ string, values []int) (int, error) {
+	r := 0
+	for _, v := range values {
+		r += v
+	}
+	return r, nil
+}
+
+// studentAgeSum counts the students and sums their ages
+// The combiner makes every map worker send one partial sum per key
+var studentAgeSum = mapreduce.Job[Student, string, int, int]{
+	Mapper:        mapreduce.MapperFunc[Student, string, int](mapStudent),
+	Combiner:      mapreduce.CombinerFunc[string, int](combineSum),
+	Reducer:       mapreduce.ReducerFunc[string, int, int](reduceSumAge),
+	MapWorkers:    4,
+	ReduceWorkers: 2,
+}
+
+// ageGroup buckets students by decade, "20s" for 20 to 29
+func ageGroup(s Student) string {
+	return fmt.Sprintf("%ds", s.Age/10*10)
+}
+
+// studentAgeStats groups the students by decade and aggregates their ages
+var studentAgeStats = mapreduce.Job[Student, string, int, mapreduce.Row]{
+	Mapper: mapreduce.GroupBy(ageGroup, func(s Student) int { return s.Age }),
+	Reducer: mapreduce.Aggregate[string](
+		mapreduce.Count[int](),
+		mapreduce.Sum[int](),
+		mapreduce.Min[int](),
+		mapreduce.Max[int](),
+		mapreduce.Mean[int](),
+		mapreduce.Median[int](),
+		mapreduce.Percentile[int](90),
+		mapreduce.Histogram(18, 30, 65),
+	),
+}
+
+//go:embed students.csv
+var studentsCSV []byte
+
+// MapReduceConfig is the input and the output of the student jobs
+type MapReduceConfig struct {
+	// Inputs are paths, globs or "-" for stdin
+	// The students.csv embedded in the binary is used when it is empty,
+	// so the examples run from any working directory
+	Inputs []string
+	// Format is csv, tsv, jsonl or fixed, guessed from the extension when empty
+	Format string
+	// Fixed are the columns of the fixed format, the name and age columns
+	// are found by name
+	Fixed []mapreduce.FixedColumn
+	// Load configures the decoding of the students, Load.Header applies to
+	// every csv and tsv input
+	Load LoadOptions
+	// Output receives the results, stdout when nil
+	Output io.Writer
+	// Sink is text or json, and table for the stats job
+	Sink string
+	// SortBy sorts the rows before they are decoded: name, age, or -name
+	// and -age for descending. Inputs larger than SortRunSize rows are
+	// sorted on disk with an external merge sort
+	SortBy      string
+	SortRunSize int
 }
 
-func newStudent(name, age string) Student {
-	a, err := strconv.Atoi(age)
+// reader opens the inputs of the config, sorted when SortBy is set
+func (c MapReduceConfig) reader() (*StudentReader, io.Closer, error) {
+	records, closer, err := c.records()
+	if err != nil || c.SortBy == "" {
+		return NewStudentRecordReader(records, c.Load), closer, err
+	}
+	defer closer.Close()
+
+	field, desc := strings.CutPrefix(c.SortBy, "-")
+	var order sorting.Comparator[mapreduce.Record]
+	switch field {
+	case "name":
+		order = mapreduce.ByField("name", 0)
+	case "age":
+		order = mapreduce.ByField("age", 1).ThenBy(mapreduce.ByField("name", 0))
+	default:
+		return nil, nil, fmt.Errorf("unknown sort field %q, wanted name or age", field)
+	}
+	if desc {
+		order = order.Reversed()
+	}
+	sorted, err := mapreduce.SortRecords(records, order, sorting.ExternalOptions{RunSize: c.SortRunSize})
 	if err != nil {
-		panic(err)
+		return nil, nil, err
 	}
-	return Student{name, a}
+	return NewStudentRecordReader(sorted, c.Load), sorted, nil
 }
 
-func readLines(fileName string) [][]string {
-	f, err := os.Open(fileName)
+// records opens the inputs of the config
+func (c MapReduceConfig) records() (mapreduce.RecordReader, io.Closer, error) {
+	opts := mapreduce.CSVOptions{Header: c.Load.Header}
+	if len(c.Inputs) == 0 {
+		csv := mapreduce.CSV(opts)(bytes.NewReader(studentsCSV), "students.csv")
+		return csv, nopCloser{}, nil
+	}
+
+	var format mapreduce.Format
+	switch c.Format {
+	case "":
+		// Guessed per input from its extension
+	case "csv":
+		format = mapreduce.CSV(opts)
+	case "tsv":
+		format = mapreduce.TSV(opts)
+	case "jsonl":
+		format = mapreduce.JSONLines()
+	case "fixed":
+		if len(c.Fixed) == 0 {
+			return nil, nil, errors.New("the fixed format needs its columns")
+		}
+		format = mapreduce.FixedWidth(c.Fixed...)
+	default:
+		return nil, nil, fmt.Errorf("unknown format %q", c.Format)
+	}
+	inputs, err := mapreduce.OpenInputs(c.Inputs, format, opts)
 	if err != nil {
-		panic(err)
+		return nil, nil, err
+	}
+	return inputs, inputs, nil
+}
+
+// nopCloser is the closer of the embedded students.csv
+type nopCloser struct{}
+
+func (nopCloser) Close() error { return nil }
+
+func (c MapReduceConfig) output() io.Writer {
+	if c.Output == nil {
+		return os.Stdout
 	}
-	defer f.Close()
-	lines, err := csv.NewReader(f).ReadAll()
+	return c.Output
+}
+
+// RunMapReduce counts the students of the inputs and sums their ages
+func RunMapReduce(ctx context.Context, c MapReduceConfig) error {
+	students, closer, err := c.reader()
 	if err != nil {
-		panic(err)
+		return err
 	}
-	return lines
+	defer closer.Close()
+
+	var sink mapreduce.Sink[string, int]
+	switch c.Sink {
+	case "", "text":
+		sink = mapreduce.TextSink[string, int](c.output())
+	case "json":
+		sink = mapreduce.JSONSink[string, int](c.output())
+	default:
+		return fmt.Errorf("unknown sink %q", c.Sink)
+	}
+	if err := studentAgeSum.RunTo(ctx, students, sink); err != nil {
+		return err
+	}
+	return reportBadRows(students)
 }
 
-func reduceSumAge(students []Student) int {
-	r := 0
-	for _, s := range students {
-		r += s.age
+// RunMapReduceStats writes the age statistics of the inputs by decade
+func RunMapReduceStats(ctx context.Context, c MapReduceConfig) error {
+	students, closer, err := c.reader()
+	if err != nil {
+		return err
 	}
-	return r
+	defer closer.Close()
+
+	var sink mapreduce.Sink[string, mapreduce.Row]
+	switch c.Sink {
+	case "", "table":
+		sink = mapreduce.TableSink[string](c.output(), "group")
+	case "json":
+		sink = mapreduce.SinkFunc[string, mapreduce.Row](func(result map[string]mapreduce.Row) error {
+			return mapreduce.Results[string](result).WriteJSON(c.output(), "group")
+		})
+	case "text":
+		sink = mapreduce.TextSink[string, mapreduce.Row](c.output())
+	default:
+		return fmt.Errorf("unknown sink %q", c.Sink)
+	}
+	if err := studentAgeStats.RunTo(ctx, students, sink); err != nil {
+		return err
+	}
+	return reportBadRows(students)
+}
+
+func reportBadRows(students *StudentReader) error {
+	for _, err := range students.Errors() {
+		fmt.Fprintln(os.Stderr, "skipped:", err)
+	}
+	return nil
+}
+
+// parseMapReduceFlags builds the config of the student jobs from the
+// command line, the remaining arguments are the inputs
+func parseMapReduceFlags(name string, args []string) (MapReduceConfig, func() error, error) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	format := fs.String("format", "", "csv, tsv, jsonl or fixed, guessed from the extension by default")
+	fixed := fs.String("fixed", "", "columns of the fixed format as name:width,name:width")
+	header := fs.Bool("header", false, "csv and tsv inputs start with a header row")
+	bad := fs.String("bad", "collect", "what to do with bad rows: fail, skip or collect")
+	minAge := fs.Int("min-age", 0, "smallest valid age")
+	maxAge := fs.Int("max-age", 150, "largest valid age")
+	output := fs.String("output", "-", "output file, - for stdout")
+	sink := fs.String("sink", "", "output format: text, json or table")
+	sortBy := fs.String("sort", "", "sort the rows by name, age, -name or -age before decoding them")
+	runSize := fs.Int("run-size", 0, "rows sorted in memory at once, larger inputs are sorted on disk")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: go run . run %s [flags] [input ...]\n", name)
+		fmt.Fprintln(fs.Output(), "Inputs are paths, globs or - for stdin read as csv unless -format is set, the embedded students.csv by default")
+		fs.PrintDefaults()
+	}
+	noop := func() error { return nil }
+	if err := fs.Parse(args); err != nil {
+		return MapReduceConfig{}, noop, err
+	}
+
+	c := MapReduceConfig{
+		Inputs:      fs.Args(),
+		Format:      *format,
+		Load:        LoadOptions{Header: *header, MinAge: *minAge, MaxAge: *maxAge},
+		Sink:        *sink,
+		SortBy:      *sortBy,
+		SortRunSize: *runSize,
+	}
+	switch *bad {
+	case "fail":
+		c.Load.Policy = FailOnBadRow
+	case "skip":
+		c.Load.Policy = SkipBadRows
+	case "collect":
+		c.Load.Policy = CollectBadRows
+	default:
+		return c, noop, fmt.Errorf("unknown bad row policy %q", *bad)
+	}
+	if *fixed != "" {
+		for _, column := range strings.Split(*fixed, ",") {
+			name, width, _ := strings.Cut(column, ":")
+			w, err := strconv.Atoi(width)
+			if err != nil || w <= 0 {
+				return c, noop, fmt.Errorf("bad fixed column %q, wanted name:width", column)
+			}
+			c.Fixed = append(c.Fixed, mapreduce.FixedColumn{Name: name, Width: w})
+		}
+		if c.Format == "" {
+			c.Format = "fixed"
+		}
+	}
+	if *output != "-" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return c, noop, err
+		}
+		c.Output = f
+		return c, f.Close, nil
+	}
+	return c, noop, nil
 }
 
 // MapReduce show cases an example of map reduce pipeline
 func MapReduce() {
-	fileName := "students.csv"
-	lines := readLines(fileName)
-	students := make([]Student, 0, len(lines))
-	fmt.Println(len(lines))
-	for _, l := range lines {
-		student := newStudent(l[0], l[1])
-		students = append(students, student)
-	}
-	sumAge := reduceSumAge(students)
-	fmt.Println(sumAge)
+	if err := RunMapReduce(context.Background(), MapReduceConfig{Load: LoadOptions{Policy: CollectBadRows}}); err != nil {
+		fmt.Println("error:", err)
+	}
+}
+
+// MapReduceMain is MapReduce reading its config from the command line
+func MapReduceMain(args []string) error {
+	c, closeOutput, err := parseMapReduceFlags("MapReduce", args)
+	if err != nil {
+		return err
+	}
+	if err := RunMapReduce(context.Background(), c); err != nil {
+		closeOutput()
+		return err
+	}
+	return closeOutput()
+}
+
+// MapReduceStats prints the age statistics of the students as a table
+func MapReduceStats() {
+	if err := RunMapReduceStats(context.Background(), MapReduceConfig{Load: LoadOptions{Policy: CollectBadRows}}); err != nil {
+		fmt.Println("error:", err)
+	}
+}
+
+// MapReduceStatsMain is MapReduceStats reading its config from the command line
+func MapReduceStatsMain(args []string) error {
+	c, closeOutput, err := parseMapReduceFlags("MapReduceStats", args)
+	if err != nil {
+		return err
+	}
+	if err := RunMapReduceStats(context.Background(), c); err != nil {
+		closeOutput()
+		return err
+	}
+	return closeOutput()
+}
+
+// RunSortStudents writes the students of the inputs in the order of
+// c.SortBy, by age when it is empty, as text lines or a JSON array
+func RunSortStudents(c MapReduceConfig) error {
+	if c.SortBy == "" {
+		c.SortBy = "age"
+	}
+	students, closer, err := c.reader()
+	if err != nil {
+		return err
+	}
+	defer closer.Close()
+
+	var sorted []Student
+	for {
+		s, err := students.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		switch c.Sink {
+		case "", "text":
+			if _, err := fmt.Fprintf(c.output(), "%s\t%d\n", s.Name, s.Age); err != nil {
+				return err
+			}
+		case "json":
+			sorted = append(sorted, s)
+		default:
+			return fmt.Errorf("unknown sink %q", c.Sink)
+		}
+	}
+	if c.Sink == "json" {
+		enc := json.NewEncoder(c.output())
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(sorted); err != nil {
+			return err
+		}
+	}
+	return reportBadRows(students)
+}
+
+// SortStudentsMain sorts the students of the inputs, -run-size 2 forces the
+// external merge sort on the small embedded students.csv
+func SortStudentsMain(args []string) error {
+	c, closeOutput, err := parseMapReduceFlags("SortStudents", args)
+	if err != nil {
+		return err
+	}
+	if err := RunSortStudents(c); err != nil {
+		closeOutput()
+		return err
+	}
+	return closeOutput()
 }
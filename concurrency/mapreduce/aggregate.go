@@ -0,0 +1,284 @@
+package mapreduce
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strings"
+	"text/tabwriter"
+)
+
+// Number is the constraint of the numeric aggregators
+type Number interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 |
+		~float32 | ~float64
+}
+
+// ErrNoValues is returned by the aggregators that need at least one value
+var ErrNoValues = errors.New("mapreduce: no values to aggregate")
+
+// Aggregator computes one named statistic over the values of a key
+// Aggregators are composed into a Reducer with Aggregate
+type Aggregator[V any] struct {
+	Name  string
+	Apply func(values []V) (any, error)
+}
+
+// Count counts the values
+func Count[V any]() Aggregator[V] {
+	return Aggregator[V]{Name: "count", Apply: func(values []V) (any, error) {
+		return len(values), nil
+	}}
+}
+
+// Sum adds the values, this is reduceSumAge for any number type
+func Sum[V Number]() Aggregator[V] {
+	return Aggregator[V]{Name: "sum", Apply: func(values []V) (any, error) {
+		var sum V
+		for _, v := range values {
+			sum += v
+		}
+		return sum, nil
+	}}
+}
+
+// Min returns the smallest value
+func Min[V cmp.Ordered]() Aggregator[V] {
+	return Aggregator[V]{Name: "min", Apply: func(values []V) (any, error) {
+		if len(values) == 0 {
+			return nil, ErrNoValues
+		}
+		return slices.Min(values), nil
+	}}
+}
+
+// Max returns the largest value
+func Max[V cmp.Ordered]() Aggregator[V] {
+	return Aggregator[V]{Name: "max", Apply: func(values []V) (any, error) {
+		if len(values) == 0 {
+			return nil, ErrNoValues
+		}
+		return slices.Max(values), nil
+	}}
+}
+
+// Mean returns the arithmetic mean as a float64
+func Mean[V Number]() Aggregator[V] {
+	return Aggregator[V]{Name: "mean", Apply: func(values []V) (any, error) {
+		if len(values) == 0 {
+			return nil, ErrNoValues
+		}
+		sum := 0.0
+		for _, v := range values {
+			sum += float64(v)
+		}
+		return sum / float64(len(values)), nil
+	}}
+}
+
+// Median is the 50th percentile
+func Median[V Number]() Aggregator[V] {
+	a := Percentile[V](50)
+	a.Name = "median"
+	return a
+}
+
+// Percentile returns the p-th percentile, 0 <= p <= 100, interpolating
+// linearly between the two closest ranks
+func Percentile[V Number](p float64) Aggregator[V] {
+	return Aggregator[V]{Name: fmt.Sprintf("p%g", p), Apply: func(values []V) (any, error) {
+		if len(values) == 0 {
+			return nil, ErrNoValues
+		}
+		if p < 0 || p > 100 {
+			return nil, fmt.Errorf("mapreduce: percentile %g out of [0, 100]", p)
+		}
+		sorted := make([]float64, len(values))
+		for i, v := range values {
+			sorted[i] = float64(v)
+		}
+		slices.Sort(sorted)
+		rank := p / 100 * float64(len(sorted)-1)
+		lo := int(math.Floor(rank))
+		hi := int(math.Ceil(rank))
+		return sorted[lo] + (sorted[hi]-sorted[lo])*(rank-float64(lo)), nil
+	}}
+}
+
+// Bucket is one bucket of a Histogram, Label reads like "<10", "10-20" or ">=20"
+type Bucket struct {
+	Label string `json:"label"`
+	Count int    `json:"count"`
+}
+
+// Buckets is the result of Histogram
+type Buckets []Bucket
+
+func (b Buckets) String() string {
+	parts := make([]string, len(b))
+	for i, bucket := range b {
+		parts[i] = fmt.Sprintf("%s:%d", bucket.Label, bucket.Count)
+	}
+	return strings.Join(parts, " ")
+}
+
+// Histogram counts the values falling between the sorted bounds
+// The bounds b0 < b1 < ... < bn give the buckets v < b0, b0 <= v < b1,
+// ..., v >= bn
+func Histogram[V Number](bounds ...V) Aggregator[V] {
+	labels := make([]string, 0, len(bounds)+1)
+	if len(bounds) > 0 {
+		labels = append(labels, fmt.Sprintf("<%v", bounds[0]))
+	}
+	for i := 1; i < len(bounds); i++ {
+		labels = append(labels, fmt.Sprintf("%v-%v", bounds[i-1], bounds[i]))
+	}
+	if len(bounds) > 0 {
+		labels = append(labels, fmt.Sprintf(">=%v", bounds[len(bounds)-1]))
+	}
+
+	return Aggregator[V]{Name: "histogram", Apply: func(values []V) (any, error) {
+		if !slices.IsSorted(bounds) {
+			return nil, fmt.Errorf("mapreduce: histogram bounds %v are not sorted", bounds)
+		}
+		buckets := make(Buckets, len(labels))
+		for i, label := range labels {
+			buckets[i].Label = label
+		}
+		if len(buckets) == 0 {
+			return buckets, nil
+		}
+		for _, v := range values {
+			// Index of the first bound greater than v
+			i, found := slices.BinarySearch(bounds, v)
+			if found {
+				i++
+			}
+			buckets[i].Count++
+		}
+		return buckets, nil
+	}}
+}
+
+// Row is the output of Aggregate, one column per Aggregator in order
+type Row struct {
+	Columns []string
+	Values  []any
+}
+
+// Get returns the value of a column and whether it exists
+func (r Row) Get(column string) (any, bool) {
+	if i := slices.Index(r.Columns, column); i >= 0 {
+		return r.Values[i], true
+	}
+	return nil, false
+}
+
+// MarshalJSON encodes the row as an object keeping the column order
+func (r Row) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, column := range r.Columns {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, err := json.Marshal(column)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(r.Values[i])
+		if err != nil {
+			return nil, fmt.Errorf("mapreduce: column %s: %w", column, err)
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// Aggregate composes aggregators into a single Reducer
+func Aggregate[K comparable, V any](aggs ...Aggregator[V]) Reducer[K, V, Row] {
+	columns := make([]string, len(aggs))
+	for i, a := range aggs {
+		columns[i] = a.Name
+	}
+	return ReducerFunc[K, V, Row](func(_ context.Context, _ K, values []V) (Row, error) {
+		row := Row{Columns: columns, Values: make([]any, len(aggs))}
+		for i, a := range aggs {
+			v, err := a.Apply(values)
+			if err != nil {
+				return Row{}, fmt.Errorf("%s: %w", a.Name, err)
+			}
+			row.Values[i] = v
+		}
+		return row, nil
+	})
+}
+
+// GroupBy is a Mapper emitting value(in) under key(in), together with
+// Aggregate it gives group by queries over any record type
+func GroupBy[In any, K comparable, V any](key func(In) K, value func(In) V) Mapper[In, K, V] {
+	return MapperFunc[In, K, V](func(_ context.Context, in In, emit func(K, V)) error {
+		emit(key(in), value(in))
+		return nil
+	})
+}
+
+// Results is the output of a job using Aggregate, keyed by group
+type Results[K cmp.Ordered] map[K]Row
+
+// Keys returns the sorted group keys
+func (r Results[K]) Keys() []K {
+	keys := make([]K, 0, len(r))
+	for k := range r {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// WriteTable prints the results as an aligned table, one row per key
+func (r Results[K]) WriteTable(w io.Writer, keyName string) error {
+	keys := r.Keys()
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if len(keys) > 0 {
+		fmt.Fprintf(tw, "%s\t%s\n", strings.ToUpper(keyName), strings.ToUpper(strings.Join(r[keys[0]].Columns, "\t")))
+	}
+	for _, k := range keys {
+		fmt.Fprint(tw, k)
+		for _, v := range r[k].Values {
+			if f, ok := v.(float64); ok {
+				fmt.Fprintf(tw, "\t%.2f", f)
+			} else {
+				fmt.Fprintf(tw, "\t%v", v)
+			}
+		}
+		fmt.Fprintln(tw)
+	}
+	return tw.Flush()
+}
+
+// WriteJSON encodes the results as an array of rows sorted by key, the key
+// is stored in the keyName field of every row
+func (r Results[K]) WriteJSON(w io.Writer, keyName string) error {
+	rows := make([]Row, 0, len(r))
+	for _, k := range r.Keys() {
+		row := r[k]
+		rows = append(rows, Row{
+			Columns: append([]string{keyName}, row.Columns...),
+			Values:  append([]any{k}, row.Values...),
+		})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(rows)
+}
@@ -0,0 +1,92 @@
+package mapreduce
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestAggregators(t *testing.T) {
+	values := []int{40, 10, 30, 20, 50}
+	tests := []struct {
+		agg  Aggregator[int]
+		want any
+	}{
+		{Count[int](), 5},
+		{Sum[int](), 150},
+		{Min[int](), 10},
+		{Max[int](), 50},
+		{Mean[int](), 30.0},
+		{Median[int](), 30.0},
+		{Percentile[int](25), 20.0},
+		{Percentile[int](90), 46.0},
+		{Percentile[int](100), 50.0},
+	}
+	for _, test := range tests {
+		got, err := test.agg.Apply(values)
+		if err != nil {
+			t.Errorf("%s returned %v", test.agg.Name, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("%s got %v, wanted %v", test.agg.Name, got, test.want)
+		}
+	}
+}
+
+func TestAggregatorsNoValues(t *testing.T) {
+	for _, agg := range []Aggregator[int]{Min[int](), Max[int](), Mean[int](), Median[int]()} {
+		if _, err := agg.Apply(nil); err != ErrNoValues {
+			t.Errorf("%s got %v, wanted %v", agg.Name, err, ErrNoValues)
+		}
+	}
+	if _, err := Percentile[int](101).Apply([]int{1}); err == nil {
+		t.Errorf("Percentile(101) did not fail")
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	got, err := Histogram(18, 30).Apply([]int{10, 18, 25, 30, 99})
+	if err != nil {
+		t.Fatalf("Histogram returned %v", err)
+	}
+	if s := got.(Buckets).String(); s != "<18:1 18-30:2 >=30:2" {
+		t.Errorf("got %s", s)
+	}
+}
+
+type record struct {
+	group string
+	value float64
+}
+
+func TestAggregateJob(t *testing.T) {
+	records := []record{{"a", 1}, {"a", 3}, {"b", 10}}
+	job := Job[record, string, float64, Row]{
+		Mapper:  GroupBy(func(r record) string { return r.group }, func(r record) float64 { return r.value }),
+		Reducer: Aggregate[string](Count[float64](), Sum[float64](), Mean[float64]()),
+	}
+	result, err := job.Run(context.Background(), SliceSource(records))
+	if err != nil {
+		t.Fatalf("Run returned %v", err)
+	}
+	if mean, _ := result["a"].Get("mean"); mean != 2.0 {
+		t.Errorf("got mean %v for a, wanted 2", mean)
+	}
+
+	var table bytes.Buffer
+	Results[string](result).WriteTable(&table, "group")
+	wantTable := "GROUP  COUNT  SUM    MEAN\na      2      4.00   2.00\nb      1      10.00  10.00\n"
+	if table.String() != wantTable {
+		t.Errorf("got table\n%s\nwanted\n%s", table.String(), wantTable)
+	}
+
+	var js bytes.Buffer
+	Results[string](result).WriteJSON(&js, "group")
+	compact := strings.Join(strings.Fields(js.String()), "")
+	wantJSON := `[{"group":"a","count":2,"sum":4,"mean":2},{"group":"b","count":1,"sum":10,"mean":10}]`
+	if compact != wantJSON {
+		t.Errorf("got %s, wanted %s", compact, wantJSON)
+	}
+}
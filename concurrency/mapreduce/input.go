@@ -0,0 +1,349 @@
+package mapreduce
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Record is one row of input before it is decoded into a typed record
+type Record struct {
+	File string
+	// Line is the 1 based line of the record
+	Line int
+	// Columns holds the 1 based column of every field, 0 when unknown
+	Columns []int
+	Fields  []string
+	// Names are the column names when the format has them, a header row
+	// or the keys of a JSON object
+	Names []string
+}
+
+// Field returns the field called name, or the field at index when the
+// record has no names
+func (r Record) Field(name string, index int) (value string, column int, ok bool) {
+	if r.Names != nil {
+		index = slices.Index(r.Names, name)
+	}
+	if index < 0 || index >= len(r.Fields) {
+		return "", 0, false
+	}
+	if index < len(r.Columns) {
+		column = r.Columns[index]
+	}
+	return r.Fields[index], column, true
+}
+
+// RecordReader streams the records of some input, Read returns io.EOF
+// after the last one. A *PositionError means that record is malformed and
+// the reader can go on with the next one, other errors are fatal
+type RecordReader interface {
+	Read() (Record, error)
+}
+
+// PositionError is a malformed record
+type PositionError struct {
+	File   string
+	Line   int
+	Column int
+	Err    error
+}
+
+func (e *PositionError) Error() string {
+	return fmt.Sprintf("%s:%d:%d: %v", e.File, e.Line, e.Column, e.Err)
+}
+
+func (e *PositionError) Unwrap() error {
+	return e.Err
+}
+
+// Format builds the RecordReader of one input, file is only used in errors
+type Format func(r io.Reader, file string) RecordReader
+
+// CSVOptions configures CSV and TSV
+type CSVOptions struct {
+	Comma rune
+	// Header means the first row of every input holds the column names
+	Header bool
+}
+
+// CSV reads comma separated values
+func CSV(opts CSVOptions) Format {
+	if opts.Comma == 0 {
+		opts.Comma = ','
+	}
+	return func(r io.Reader, file string) RecordReader {
+		cr := csv.NewReader(r)
+		cr.Comma = opts.Comma
+		// The number of fields is the concern of the decoder
+		cr.FieldsPerRecord = -1
+		cr.TrimLeadingSpace = true
+		return &csvReader{r: cr, file: file, header: opts.Header}
+	}
+}
+
+// TSV reads tab separated values
+func TSV(opts CSVOptions) Format {
+	opts.Comma = '\t'
+	return CSV(opts)
+}
+
+type csvReader struct {
+	r      *csv.Reader
+	file   string
+	header bool
+	names  []string
+}
+
+func (c *csvReader) Read() (Record, error) {
+	record, err := c.r.Read()
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return Record{}, &PositionError{File: c.file, Line: parseErr.Line, Column: parseErr.Column, Err: parseErr.Err}
+		}
+		return Record{}, err
+	}
+	if c.header {
+		c.header = false
+		c.names = make([]string, len(record))
+		for i, name := range record {
+			c.names[i] = strings.ToLower(strings.TrimSpace(name))
+		}
+		return c.Read()
+	}
+
+	columns := make([]int, len(record))
+	line := 0
+	for i := range record {
+		line, columns[i] = c.r.FieldPos(i)
+	}
+	return Record{File: c.file, Line: line, Columns: columns, Fields: record, Names: c.names}, nil
+}
+
+// JSONLines reads one JSON object per line, the fields are the values of
+// the object sorted by key
+func JSONLines() Format {
+	return func(r io.Reader, file string) RecordReader {
+		return &jsonLinesReader{s: bufio.NewScanner(r), file: file}
+	}
+}
+
+type jsonLinesReader struct {
+	s    *bufio.Scanner
+	file string
+	line int
+}
+
+func (j *jsonLinesReader) Read() (Record, error) {
+	for j.s.Scan() {
+		j.line++
+		text := strings.TrimSpace(j.s.Text())
+		if text == "" {
+			continue
+		}
+		object := make(map[string]json.RawMessage)
+		if err := json.Unmarshal([]byte(text), &object); err != nil {
+			return Record{}, &PositionError{File: j.file, Line: j.line, Column: 1, Err: err}
+		}
+		names := make([]string, 0, len(object))
+		for name := range object {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		fields := make([]string, len(names))
+		for i, name := range names {
+			// Strings lose their quotes, numbers and the rest stay as written
+			var s string
+			if json.Unmarshal(object[name], &s) == nil {
+				fields[i] = s
+			} else {
+				fields[i] = string(object[name])
+			}
+			names[i] = strings.ToLower(name)
+		}
+		return Record{File: j.file, Line: j.line, Fields: fields, Names: names}, nil
+	}
+	if err := j.s.Err(); err != nil {
+		return Record{}, err
+	}
+	return Record{}, io.EOF
+}
+
+// FixedColumn is a column of a fixed width file
+type FixedColumn struct {
+	Name  string
+	Width int
+}
+
+// FixedWidth reads lines cut into columns of fixed width, the fields are
+// trimmed and a short line leaves the last fields empty
+func FixedWidth(columns ...FixedColumn) Format {
+	names := make([]string, len(columns))
+	for i, c := range columns {
+		names[i] = strings.ToLower(c.Name)
+	}
+	return func(r io.Reader, file string) RecordReader {
+		return &fixedWidthReader{s: bufio.NewScanner(r), file: file, columns: columns, names: names}
+	}
+}
+
+type fixedWidthReader struct {
+	s       *bufio.Scanner
+	file    string
+	columns []FixedColumn
+	names   []string
+	line    int
+}
+
+func (f *fixedWidthReader) Read() (Record, error) {
+	for f.s.Scan() {
+		f.line++
+		text := f.s.Text()
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		fields := make([]string, len(f.columns))
+		columns := make([]int, len(f.columns))
+		start := 0
+		for i, c := range f.columns {
+			columns[i] = start + 1
+			end := min(start+c.Width, len(text))
+			if start < end {
+				fields[i] = strings.TrimSpace(text[start:end])
+			}
+			start += c.Width
+		}
+		return Record{File: f.file, Line: f.line, Columns: columns, Fields: fields, Names: f.names}, nil
+	}
+	if err := f.s.Err(); err != nil {
+		return Record{}, err
+	}
+	return Record{}, io.EOF
+}
+
+// FormatFor guesses the format from the file extension: .csv, .tsv,
+// .jsonl and .ndjson. Stdin has no extension and is read as csv. Fixed
+// width files have to be configured explicitly
+func FormatFor(path string, opts CSVOptions) (Format, bool) {
+	if path == Stdin {
+		return CSV(opts), true
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return CSV(opts), true
+	case ".tsv", ".tab":
+		return TSV(opts), true
+	case ".jsonl", ".ndjson":
+		return JSONLines(), true
+	}
+	return nil, false
+}
+
+// Stdin is the input name standing for the standard input
+const Stdin = "-"
+
+// ExpandInputs resolves the globs of inputs, Stdin is kept as is
+// A glob matching no file is an error so typos do not go unnoticed
+func ExpandInputs(inputs []string) ([]string, error) {
+	paths := make([]string, 0, len(inputs))
+	for _, input := range inputs {
+		if input == Stdin {
+			paths = append(paths, input)
+			continue
+		}
+		matches, err := filepath.Glob(input)
+		if err != nil {
+			return nil, fmt.Errorf("mapreduce: input %q: %w", input, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("mapreduce: input %q: %w", input, os.ErrNotExist)
+		}
+		paths = append(paths, matches...)
+	}
+	return paths, nil
+}
+
+// OpenInputs returns a RecordReader over every input in order, the files
+// are opened one at a time. When format is nil it is guessed with FormatFor
+func OpenInputs(inputs []string, format Format, opts CSVOptions) (*MultiReader, error) {
+	paths, err := ExpandInputs(inputs)
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range paths {
+		if format != nil {
+			continue
+		}
+		if _, ok := FormatFor(path, opts); !ok {
+			return nil, fmt.Errorf("mapreduce: unknown format of input %q", path)
+		}
+	}
+	return &MultiReader{paths: paths, format: format, opts: opts}, nil
+}
+
+// MultiReader reads several inputs one after the other
+type MultiReader struct {
+	paths   []string
+	format  Format
+	opts    CSVOptions
+	current RecordReader
+	closer  io.Closer
+}
+
+// Read returns the next record of the current input, moving to the next
+// input at the end of each one
+func (m *MultiReader) Read() (Record, error) {
+	for {
+		if m.current == nil {
+			if len(m.paths) == 0 {
+				return Record{}, io.EOF
+			}
+			if err := m.open(m.paths[0]); err != nil {
+				return Record{}, err
+			}
+			m.paths = m.paths[1:]
+		}
+		record, err := m.current.Read()
+		if err == io.EOF {
+			m.Close()
+			continue
+		}
+		return record, err
+	}
+}
+
+func (m *MultiReader) open(path string) error {
+	format := m.format
+	if format == nil {
+		format, _ = FormatFor(path, m.opts)
+	}
+	if path == Stdin {
+		m.current = format(os.Stdin, "stdin")
+		return nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	m.current, m.closer = format(f, path), f
+	return nil
+}
+
+// Close closes the current input
+func (m *MultiReader) Close() error {
+	m.current = nil
+	if m.closer == nil {
+		return nil
+	}
+	err := m.closer.Close()
+	m.closer = nil
+	return err
+}
@@ -0,0 +1,164 @@
+package mapreduce
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vrnvu/go-examples/lang/sorting"
+)
+
+func readAll(t *testing.T, r RecordReader) []Record {
+	t.Helper()
+	records := make([]Record, 0)
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			return records
+		}
+		if err != nil {
+			t.Fatalf("Read returned %v", err)
+		}
+		records = append(records, record)
+	}
+}
+
+func checkField(t *testing.T, r Record, name string, index int, want string) {
+	t.Helper()
+	if got, _, ok := r.Field(name, index); !ok || got != want {
+		t.Errorf("got %q %v for field %s, wanted %q", got, ok, name, want)
+	}
+}
+
+func TestFormats(t *testing.T) {
+	tests := []struct {
+		name   string
+		format Format
+		input  string
+	}{
+		{"csv", CSV(CSVOptions{}), "a,30\n"},
+		{"csv header", CSV(CSVOptions{Header: true}), "Age,Name\n30,a\n"},
+		{"tsv", TSV(CSVOptions{}), "a\t30\n"},
+		{"jsonl", JSONLines(), "\n{\"name\": \"a\", \"age\": 30}\n"},
+		{"fixed", FixedWidth(FixedColumn{"name", 4}, FixedColumn{"age", 3}), "a    30\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			records := readAll(t, test.format(strings.NewReader(test.input), "input"))
+			if len(records) != 1 {
+				t.Fatalf("got %d records, wanted 1", len(records))
+			}
+			checkField(t, records[0], "name", 0, "a")
+			checkField(t, records[0], "age", 1, "30")
+		})
+	}
+}
+
+func TestFormatErrorPosition(t *testing.T) {
+	r := JSONLines()(strings.NewReader("{\"name\": \"a\"}\nnot json\n{\"name\": \"b\"}\n"), "in.jsonl")
+	r.Read()
+	_, err := r.Read()
+	var posErr *PositionError
+	if !errors.As(err, &posErr) || posErr.File != "in.jsonl" || posErr.Line != 2 {
+		t.Fatalf("got %v, wanted a PositionError on line 2 of in.jsonl", err)
+	}
+	// The reader goes on after a malformed record
+	if record, err := r.Read(); err != nil || record.Line != 3 {
+		t.Errorf("got %v %v, wanted the record of line 3", record, err)
+	}
+}
+
+func TestOpenInputs(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.csv":   "a,30\n",
+		"b.csv":   "b,20\nc,40\n",
+		"c.jsonl": "{\"name\": \"d\", \"age\": 10}\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r, err := OpenInputs([]string{filepath.Join(dir, "*.csv"), filepath.Join(dir, "c.jsonl")}, nil, CSVOptions{})
+	if err != nil {
+		t.Fatalf("OpenInputs returned %v", err)
+	}
+	defer r.Close()
+	records := readAll(t, r)
+	if len(records) != 4 {
+		t.Fatalf("got %d records, wanted 4", len(records))
+	}
+	checkField(t, records[3], "name", 0, "d")
+	if filepath.Base(records[1].File) != "b.csv" || records[1].Line != 1 {
+		t.Errorf("got %s:%d, wanted b.csv:1", records[1].File, records[1].Line)
+	}
+
+	if _, err := OpenInputs([]string{filepath.Join(dir, "*.missing")}, nil, CSVOptions{}); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("got %v, wanted %v", err, os.ErrNotExist)
+	}
+	if _, err := OpenInputs([]string{filepath.Join(dir, "a.csv"), "data.unknown"}, CSV(CSVOptions{}), CSVOptions{}); err == nil {
+		t.Errorf("OpenInputs accepted a missing file")
+	}
+}
+
+func TestOpenInputsStdin(t *testing.T) {
+	in := filepath.Join(t.TempDir(), "stdin")
+	if err := os.WriteFile(in, []byte("a,30\nb,20\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	stdin := os.Stdin
+	os.Stdin = f
+	defer func() { os.Stdin = stdin }()
+
+	// no extension and no format, stdin is csv
+	r, err := OpenInputs([]string{Stdin}, nil, CSVOptions{})
+	if err != nil {
+		t.Fatalf("OpenInputs returned %v", err)
+	}
+	defer r.Close()
+	records := readAll(t, r)
+	if len(records) != 2 {
+		t.Fatalf("got %d records, wanted 2", len(records))
+	}
+	checkField(t, records[1], "name", 0, "b")
+}
+
+func TestSortRecords(t *testing.T) {
+	in := "name,age\nkai,30\nann,9\nbob,\neve,100\nb\"ad,1\nal,9\n"
+	r := CSV(CSVOptions{Header: true})(strings.NewReader(in), "in.csv")
+	dir := t.TempDir()
+	sorted, err := SortRecords(r, ByField("age", 1).ThenBy(ByField("name", 0)), sorting.ExternalOptions{RunSize: 2, TempDir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = sorted.Read()
+	var posErr *PositionError
+	if !errors.As(err, &posErr) || posErr.Line != 6 {
+		t.Errorf("got %v, wanted the malformed line 6 first", err)
+	}
+	var got []string
+	for _, record := range readAll(t, sorted) {
+		got = append(got, fmt.Sprintf("%s:%d", record.Fields[0], record.Line))
+	}
+	if err := sorted.Close(); err != nil {
+		t.Fatal(err)
+	}
+	want := "al:7 ann:3 kai:2 eve:5 bob:4"
+	if strings.Join(got, " ") != want {
+		t.Errorf("got %v, wanted %s", got, want)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("temp files left: %v", entries)
+	}
+}
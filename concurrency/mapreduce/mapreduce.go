@@ -0,0 +1,260 @@
+// Package mapreduce is a concurrent, streaming MapReduce engine
+//
+// Records are pulled one at a time from a Source and handed to a pool of
+// map workers. Every emitted key value pair is routed by the hash of its key
+// to one of the reduce workers, so all the values of a key meet in the same
+// worker. An optional Combiner folds values inside each map worker before
+// the shuffle to cut the traffic between map and reduce workers
+//
+//	Source -> map workers -> (combine) -> shuffle by hash(key) -> reduce workers -> result
+package mapreduce
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/maphash"
+	"io"
+	"runtime"
+	"sync"
+)
+
+// Source streams the input records, Next returns io.EOF after the last one
+type Source[T any] interface {
+	Next() (T, error)
+}
+
+// Mapper turns one input record into any number of key value pairs
+type Mapper[In any, K comparable, V any] interface {
+	Map(ctx context.Context, in In, emit func(K, V)) error
+}
+
+// Reducer turns all the values of a key into the output of that key
+type Reducer[K comparable, V, Out any] interface {
+	Reduce(ctx context.Context, key K, values []V) (Out, error)
+}
+
+// Combiner folds two values of the same key into one
+// It must be associative, the engine applies it in any order
+type Combiner[K comparable, V any] interface {
+	Combine(key K, a, b V) V
+}
+
+// MapperFunc adapts a function to Mapper
+type MapperFunc[In any, K comparable, V any] func(ctx context.Context, in In, emit func(K, V)) error
+
+// Map calls f
+func (f MapperFunc[In, K, V]) Map(ctx context.Context, in In, emit func(K, V)) error {
+	return f(ctx, in, emit)
+}
+
+// ReducerFunc adapts a function to Reducer
+type ReducerFunc[K comparable, V, Out any] func(ctx context.Context, key K, values []V) (Out, error)
+
+// Reduce calls f
+func (f ReducerFunc[K, V, Out]) Reduce(ctx context.Context, key K, values []V) (Out, error) {
+	return f(ctx, key, values)
+}
+
+// CombinerFunc adapts a function to Combiner
+type CombinerFunc[K comparable, V any] func(key K, a, b V) V
+
+// Combine calls f
+func (f CombinerFunc[K, V]) Combine(key K, a, b V) V {
+	return f(key, a, b)
+}
+
+// maxCombinedKeys bounds the keys a map worker holds before flushing its
+// combined values to the reducers
+const maxCombinedKeys = 4096
+
+// Job wires a Mapper, an optional Combiner and a Reducer
+// Workers default to runtime.NumCPU when zero
+type Job[In any, K comparable, V, Out any] struct {
+	Mapper        Mapper[In, K, V]
+	Combiner      Combiner[K, V]
+	Reducer       Reducer[K, V, Out]
+	MapWorkers    int
+	ReduceWorkers int
+}
+
+type pair[K comparable, V any] struct {
+	key   K
+	value V
+}
+
+// Run streams src through the job and returns the output of every key
+// The first error of the source, a mapper or a reducer cancels the job
+func (j Job[In, K, V, Out]) Run(ctx context.Context, src Source[In]) (map[K]Out, error) {
+	if j.Mapper == nil || j.Reducer == nil {
+		return nil, errors.New("mapreduce: job needs a Mapper and a Reducer")
+	}
+	mapWorkers, reduceWorkers := j.MapWorkers, j.ReduceWorkers
+	if mapWorkers <= 0 {
+		mapWorkers = runtime.NumCPU()
+	}
+	if reduceWorkers <= 0 {
+		reduceWorkers = runtime.NumCPU()
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	fail := func(err error) {
+		cancel(err)
+	}
+
+	// Read, the only goroutine touching src
+	inputs := make(chan In, mapWorkers)
+	go func() {
+		defer close(inputs)
+		for {
+			in, err := src.Next()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				fail(fmt.Errorf("mapreduce: source: %w", err))
+				return
+			}
+			select {
+			case inputs <- in:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// Shuffle, one channel per reduce worker
+	seed := maphash.MakeSeed()
+	partitions := make([]chan pair[K, V], reduceWorkers)
+	for i := range partitions {
+		partitions[i] = make(chan pair[K, V], 64)
+	}
+	send := func(k K, v V) bool {
+		p := partitions[maphash.Comparable(seed, k)%uint64(reduceWorkers)]
+		select {
+		case p <- pair[K, V]{k, v}:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	// Map
+	var mappers sync.WaitGroup
+	for w := 0; w < mapWorkers; w++ {
+		mappers.Add(1)
+		go func() {
+			defer mappers.Done()
+			j.mapWorker(ctx, inputs, send, fail)
+		}()
+	}
+	go func() {
+		mappers.Wait()
+		for _, p := range partitions {
+			close(p)
+		}
+	}()
+
+	// Reduce
+	var mu sync.Mutex
+	result := make(map[K]Out)
+	var reducers sync.WaitGroup
+	for _, p := range partitions {
+		reducers.Add(1)
+		go func(p <-chan pair[K, V]) {
+			defer reducers.Done()
+			j.reduceWorker(ctx, p, func(k K, out Out) {
+				mu.Lock()
+				result[k] = out
+				mu.Unlock()
+			}, fail)
+		}(p)
+	}
+	reducers.Wait()
+
+	if err := context.Cause(ctx); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (j Job[In, K, V, Out]) mapWorker(ctx context.Context, inputs <-chan In, send func(K, V) bool, fail func(error)) {
+	var combined map[K]V
+	if j.Combiner != nil {
+		combined = make(map[K]V)
+	}
+	flush := func() bool {
+		for k, v := range combined {
+			if !send(k, v) {
+				return false
+			}
+			delete(combined, k)
+		}
+		return true
+	}
+
+	emit := func(k K, v V) {
+		if combined == nil {
+			send(k, v)
+			return
+		}
+		if prev, ok := combined[k]; ok {
+			v = j.Combiner.Combine(k, prev, v)
+		}
+		combined[k] = v
+		if len(combined) >= maxCombinedKeys {
+			flush()
+		}
+	}
+
+	for in := range inputs {
+		if ctx.Err() != nil {
+			// Drain so the reader is not left blocked
+			continue
+		}
+		if err := j.Mapper.Map(ctx, in, emit); err != nil {
+			fail(fmt.Errorf("mapreduce: map: %w", err))
+		}
+	}
+	if ctx.Err() == nil {
+		flush()
+	}
+}
+
+func (j Job[In, K, V, Out]) reduceWorker(ctx context.Context, pairs <-chan pair[K, V], put func(K, Out), fail func(error)) {
+	groups := make(map[K][]V)
+	for p := range pairs {
+		groups[p.key] = append(groups[p.key], p.value)
+	}
+	for k, values := range groups {
+		if ctx.Err() != nil {
+			return
+		}
+		out, err := j.Reducer.Reduce(ctx, k, values)
+		if err != nil {
+			fail(fmt.Errorf("mapreduce: reduce %v: %w", k, err))
+			return
+		}
+		put(k, out)
+	}
+}
+
+// SliceSource streams the elements of a slice, handy for tests and small inputs
+func SliceSource[T any](items []T) Source[T] {
+	return &sliceSource[T]{items: items}
+}
+
+type sliceSource[T any] struct {
+	items []T
+}
+
+func (s *sliceSource[T]) Next() (T, error) {
+	if len(s.items) == 0 {
+		var zero T
+		return zero, io.EOF
+	}
+	item := s.items[0]
+	s.items = s.items[1:]
+	return item, nil
+}
@@ -0,0 +1,100 @@
+package mapreduce
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+var lines = []string{
+	"the quick brown fox",
+	"jumps over the lazy dog",
+	"the dog sleeps",
+}
+
+var wordCount = Job[string, string, int, int]{
+	Mapper: MapperFunc[string, string, int](func(_ context.Context, line string, emit func(string, int)) error {
+		for _, w := range strings.Fields(line) {
+			emit(w, 1)
+		}
+		return nil
+	}),
+	Reducer: ReducerFunc[string, int, int](func(_ context.Context, _ string, counts []int) (int, error) {
+		sum := 0
+		for _, c := range counts {
+			sum += c
+		}
+		return sum, nil
+	}),
+	MapWorkers:    3,
+	ReduceWorkers: 2,
+}
+
+func checkWordCount(t *testing.T, got map[string]int) {
+	t.Helper()
+	want := map[string]int{"the": 3, "dog": 2, "fox": 1, "sleeps": 1}
+	for w, n := range want {
+		if got[w] != n {
+			t.Errorf("got %d for %q, wanted %d", got[w], w, n)
+		}
+	}
+	if len(got) != 9 {
+		t.Errorf("got %d words, wanted 9", len(got))
+	}
+}
+
+func TestRun(t *testing.T) {
+	got, err := wordCount.Run(context.Background(), SliceSource(lines))
+	if err != nil {
+		t.Fatalf("Run returned %v", err)
+	}
+	checkWordCount(t, got)
+}
+
+func TestRunWithCombiner(t *testing.T) {
+	job := wordCount
+	combined := 0
+	job.Combiner = CombinerFunc[string, int](func(_ string, a, b int) int {
+		combined++
+		return a + b
+	})
+	job.MapWorkers = 1
+	got, err := job.Run(context.Background(), SliceSource(lines))
+	if err != nil {
+		t.Fatalf("Run returned %v", err)
+	}
+	checkWordCount(t, got)
+	// With a single map worker "the" is combined twice and "dog" once
+	if combined != 3 {
+		t.Errorf("got %d combines, wanted 3", combined)
+	}
+}
+
+func TestRunMapError(t *testing.T) {
+	errBad := errors.New("bad line")
+	job := wordCount
+	job.Mapper = MapperFunc[string, string, int](func(_ context.Context, line string, emit func(string, int)) error {
+		if strings.Contains(line, "lazy") {
+			return errBad
+		}
+		emit(line, 1)
+		return nil
+	})
+	_, err := job.Run(context.Background(), SliceSource(lines))
+	if !errors.Is(err, errBad) {
+		t.Errorf("got %v, wanted %v", err, errBad)
+	}
+}
+
+type failingSource struct{ err error }
+
+func (s failingSource) Next() (string, error) { return "", s.err }
+
+func TestRunSourceError(t *testing.T) {
+	errRead := errors.New("read failed")
+	_, err := wordCount.Run(context.Background(), failingSource{errRead})
+	if !errors.Is(err, errRead) {
+		t.Errorf("got %v, wanted %v", err, errRead)
+	}
+}
@@ -0,0 +1,65 @@
+package mapreduce
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+)
+
+// Sink receives the output of a job once every key has been reduced
+type Sink[K comparable, Out any] interface {
+	Write(result map[K]Out) error
+}
+
+// SinkFunc adapts a function to Sink
+type SinkFunc[K comparable, Out any] func(result map[K]Out) error
+
+// Write calls f
+func (f SinkFunc[K, Out]) Write(result map[K]Out) error {
+	return f(result)
+}
+
+// TextSink writes "key value" lines sorted by key
+func TextSink[K cmp.Ordered, Out any](w io.Writer) Sink[K, Out] {
+	return SinkFunc[K, Out](func(result map[K]Out) error {
+		keys := make([]K, 0, len(result))
+		for k := range result {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			if _, err := fmt.Fprintf(w, "%v\t%v\n", k, result[k]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// JSONSink writes the result as one indented JSON object
+func JSONSink[K comparable, Out any](w io.Writer) Sink[K, Out] {
+	return SinkFunc[K, Out](func(result map[K]Out) error {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(result)
+	})
+}
+
+// TableSink writes the rows of an Aggregate job as a table
+func TableSink[K cmp.Ordered](w io.Writer, keyName string) Sink[K, Row] {
+	return SinkFunc[K, Row](func(result map[K]Row) error {
+		return Results[K](result).WriteTable(w, keyName)
+	})
+}
+
+// RunTo runs the job and writes its result to sink
+func (j Job[In, K, V, Out]) RunTo(ctx context.Context, src Source[In], sink Sink[K, Out]) error {
+	result, err := j.Run(ctx, src)
+	if err != nil {
+		return err
+	}
+	return sink.Write(result)
+}
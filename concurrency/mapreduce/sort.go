@@ -0,0 +1,99 @@
+package mapreduce
+
+import (
+	"cmp"
+	"errors"
+	"strconv"
+
+	"github.com/vrnvu/go-examples/lang/sorting"
+)
+
+// SortRecords sorts the records of r with an external merge sort, so inputs
+// larger than memory can be sorted before they are mapped. The records keep
+// their File and Line, errors found after sorting still point at the input
+// Malformed records, the *PositionError of r, are read first, before the
+// sorted records. Close the result to remove the temp files
+func SortRecords(r RecordReader, c sorting.Comparator[Record], opts sorting.ExternalOptions) (*SortedRecords, error) {
+	sr := &SortedRecords{}
+	sorted, err := sorting.External[Record](skipMalformed{r, &sr.malformed}, c, sorting.Gob[Record](), opts)
+	if err != nil {
+		return nil, err
+	}
+	sr.sorted = sorted
+	return sr, nil
+}
+
+// SortedRecords is the RecordReader returned by SortRecords
+type SortedRecords struct {
+	malformed []error
+	sorted    *sorting.Sorted[Record]
+}
+
+// Read returns the malformed records of the input then the sorted records
+func (s *SortedRecords) Read() (Record, error) {
+	if len(s.malformed) > 0 {
+		err := s.malformed[0]
+		s.malformed = s.malformed[1:]
+		return Record{}, err
+	}
+	return s.sorted.Read()
+}
+
+// Runs is the number of runs written on disk, 0 when it fit in memory
+func (s *SortedRecords) Runs() int {
+	return s.sorted.Runs
+}
+
+// Close removes the temp files
+func (s *SortedRecords) Close() error {
+	return s.sorted.Close()
+}
+
+// skipMalformed keeps the *PositionError of r aside so they do not stop the sort
+type skipMalformed struct {
+	r         RecordReader
+	malformed *[]error
+}
+
+func (s skipMalformed) Read() (Record, error) {
+	for {
+		record, err := s.r.Read()
+		var posErr *PositionError
+		if !errors.As(err, &posErr) {
+			return record, err
+		}
+		*s.malformed = append(*s.malformed, err)
+	}
+}
+
+// ByField orders records by the field called name, or at index when the
+// records have no names. Records without the field come first, then the
+// integers in numeric order and then the other values as strings
+func ByField(name string, index int) sorting.Comparator[Record] {
+	return func(a, b Record) int {
+		x, _, okA := a.Field(name, index)
+		y, _, okB := b.Field(name, index)
+		if !okA || !okB {
+			return compareBool(okA, okB)
+		}
+		n, errX := strconv.ParseInt(x, 10, 64)
+		m, errY := strconv.ParseInt(y, 10, 64)
+		switch {
+		case errX == nil && errY == nil:
+			return cmp.Compare(n, m)
+		case errX == nil || errY == nil:
+			return compareBool(errX != nil, errY != nil)
+		}
+		return cmp.Compare(x, y)
+	}
+}
+
+func compareBool(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case a:
+		return 1
+	}
+	return -1
+}
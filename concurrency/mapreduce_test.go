@@ -0,0 +1,49 @@
+package concurrency
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunSortStudents(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "students.csv")
+	input := "name,age\nkai,30\nann,9\nbob,x\neve,100\nal,9\nzoe,30\n"
+	if err := os.WriteFile(path, []byte(input), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		sortBy   string
+		runSize  int
+		expected string
+	}{
+		{"", 0, "al\t9\nann\t9\nkai\t30\nzoe\t30\neve\t100\n"},
+		{"age", 2, "al\t9\nann\t9\nkai\t30\nzoe\t30\neve\t100\n"},
+		{"-age", 2, "eve\t100\nzoe\t30\nkai\t30\nann\t9\nal\t9\n"},
+		{"name", 1, "al\t9\nann\t9\neve\t100\nkai\t30\nzoe\t30\n"},
+	}
+	for _, test := range tests {
+		var out bytes.Buffer
+		c := MapReduceConfig{
+			Inputs:      []string{path},
+			Load:        LoadOptions{Header: true, Policy: SkipBadRows},
+			Output:      &out,
+			SortBy:      test.sortBy,
+			SortRunSize: test.runSize,
+		}
+		if err := RunSortStudents(c); err != nil {
+			t.Fatalf("sort by %q: %v", test.sortBy, err)
+		}
+		if out.String() != test.expected {
+			t.Errorf("sort by %q: got\n%swanted\n%s", test.sortBy, out.String(), test.expected)
+		}
+	}
+
+	err := RunSortStudents(MapReduceConfig{Inputs: []string{path}, SortBy: "grade"})
+	if err == nil {
+		t.Errorf("sort by grade got no error")
+	}
+}
@@ -0,0 +1,281 @@
+// Package pool is the reusable version of the concurrency.Worker and
+// concurrency.WorkerPools example: a fixed number of workers consume jobs
+// from a channel and send their results into another channel
+package pool
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+
+	"github.com/vrnvu/go-examples/lang/errs"
+)
+
+// ErrClosed is returned by Submit once Close has been called, wrapped in an
+// errs.Unavailable error
+var ErrClosed = errors.New("pool: submit on closed pool")
+
+// Handler processes one job, it plays the role of the body of concurrency.Worker
+type Handler[In, Out any] func(ctx context.Context, in In) (Out, error)
+
+// Result is the outcome of one job
+// Seq is the submission order of the job starting at 0, Err wraps the error
+// of the handler or of the context with an errs.Error whose "job" field is Seq
+type Result[In, Out any] struct {
+	Seq   int
+	Input In
+	Value Out
+	Err   error
+}
+
+type config struct {
+	workers int
+	buffer  int
+	ordered bool
+}
+
+// Option configures a Pool
+type Option func(*config)
+
+// WithWorkers sets the number of workers, it defaults to runtime.NumCPU
+func WithWorkers(n int) Option {
+	return func(c *config) {
+		if n > 0 {
+			c.workers = n
+		}
+	}
+}
+
+// WithBuffer sets the buffer size of the jobs and results channels
+// It defaults to the number of workers
+func WithBuffer(n int) Option {
+	return func(c *config) {
+		if n >= 0 {
+			c.buffer = n
+		}
+	}
+}
+
+// WithOrdered delivers the results in submission order instead of completion order
+// Results that finish early are held until all the previous ones are delivered
+func WithOrdered() Option {
+	return func(c *config) {
+		c.ordered = true
+	}
+}
+
+type job[In any] struct {
+	seq int
+	in  In
+}
+
+// Pool runs a Handler over the submitted jobs with a fixed number of workers
+//
+// The usual life cycle is a goroutine that Submits the jobs and Closes the
+// pool while the caller ranges over Results until it is closed, as Map does
+//
+//	go func() {
+//		defer p.Close()
+//		for _, in := range inputs {
+//			p.Submit(in)
+//		}
+//	}()
+//	for r := range p.Results() {
+//		...
+//	}
+//
+// Results must be drained while the jobs are submitted, otherwise the
+// workers block on the full results channel, Submit on the full jobs
+// buffer, and a caller that submits everything before reading deadlocks
+type Pool[In, Out any] struct {
+	ctx     context.Context
+	handler Handler[In, Out]
+	cfg     config
+
+	mu     sync.Mutex
+	seq    int
+	closed bool
+	// sending counts the Submit calls sending outside mu, the last one
+	// closes jobs once the pool is closed
+	sending int
+
+	jobs    chan job[In]
+	raw     chan Result[In, Out]
+	results chan Result[In, Out]
+	workers sync.WaitGroup
+	done    chan struct{}
+
+	errOnce sync.Once
+	err     error
+}
+
+// New starts the workers of a pool
+// Once ctx is cancelled pending jobs are not handled anymore, they are
+// reported as results with ctx.Err()
+func New[In, Out any](ctx context.Context, handler Handler[In, Out], opts ...Option) *Pool[In, Out] {
+	cfg := config{workers: runtime.NumCPU(), buffer: -1}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.buffer < 0 {
+		cfg.buffer = cfg.workers
+	}
+
+	p := &Pool[In, Out]{
+		ctx:     ctx,
+		handler: handler,
+		cfg:     cfg,
+		jobs:    make(chan job[In], cfg.buffer),
+		raw:     make(chan Result[In, Out], cfg.buffer),
+		results: make(chan Result[In, Out], cfg.buffer),
+		done:    make(chan struct{}),
+	}
+
+	p.workers.Add(cfg.workers)
+	for w := 0; w < cfg.workers; w++ {
+		go p.work()
+	}
+	go func() {
+		p.workers.Wait()
+		close(p.raw)
+	}()
+	go p.collect()
+	return p
+}
+
+// Submit queues a job, it blocks while the jobs buffer is full
+// The send happens outside the lock, so Close and the other Submit calls
+// do not wait behind a full queue
+func (p *Pool[In, Out]) Submit(in In) error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return errs.Wrap(ErrClosed, errs.Unavailable, "")
+	}
+	if err := p.ctx.Err(); err != nil {
+		p.mu.Unlock()
+		return errs.Wrap(err, errs.Other, "", "job", p.seq)
+	}
+	// the seq is taken before the send, an ordered pool waits for every seq
+	j := job[In]{seq: p.seq, in: in}
+	p.seq++
+	p.sending++
+	p.mu.Unlock()
+
+	// The workers drain the queue even once ctx is done, reporting
+	// ctx.Err(), so the send always completes
+	p.jobs <- j
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.sending--
+	if p.closed && p.sending == 0 {
+		close(p.jobs)
+	}
+	return nil
+}
+
+// Results returns the channel of results, it is closed after Close once
+// every submitted job has been delivered
+func (p *Pool[In, Out]) Results() <-chan Result[In, Out] {
+	return p.results
+}
+
+// Close stops accepting jobs, the workers exit once the queued jobs are done
+// It is safe to call Close more than once
+func (p *Pool[In, Out]) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.closed {
+		p.closed = true
+		// with sends in flight the last one closes jobs
+		if p.sending == 0 {
+			close(p.jobs)
+		}
+	}
+}
+
+// Wait blocks until every result has been delivered and returns the first
+// error reported by a job, Results must be drained concurrently
+func (p *Pool[In, Out]) Wait() error {
+	<-p.done
+	return p.err
+}
+
+func (p *Pool[In, Out]) work() {
+	defer p.workers.Done()
+	// Same pattern as Worker, range over jobs until it gets closed
+	for j := range p.jobs {
+		r := Result[In, Out]{Seq: j.seq, Input: j.in}
+		var err error
+		if err = p.ctx.Err(); err == nil {
+			r.Value, err = p.handler(p.ctx, j.in)
+		}
+		r.Err = errs.Wrap(err, errs.Other, "", "job", j.seq)
+		p.raw <- r
+	}
+}
+
+func (p *Pool[In, Out]) collect() {
+	defer close(p.done)
+	defer close(p.results)
+
+	if !p.cfg.ordered {
+		for r := range p.raw {
+			p.deliver(r)
+		}
+		return
+	}
+
+	// Every seq is eventually produced, so holding the early ones until
+	// next arrives never waits forever
+	pending := make(map[int]Result[In, Out])
+	next := 0
+	for r := range p.raw {
+		pending[r.Seq] = r
+		for {
+			r, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			p.deliver(r)
+			next++
+		}
+	}
+}
+
+func (p *Pool[In, Out]) deliver(r Result[In, Out]) {
+	if r.Err != nil {
+		p.errOnce.Do(func() { p.err = r.Err })
+	}
+	p.results <- r
+}
+
+// Map runs handler over every input and returns the outputs in input order
+// It returns the first error once all the inputs have been processed
+func Map[In, Out any](ctx context.Context, inputs []In, handler Handler[In, Out], opts ...Option) ([]Out, error) {
+	p := New(ctx, handler, append(opts, WithOrdered())...)
+	go func() {
+		defer p.Close()
+		for _, in := range inputs {
+			if p.Submit(in) != nil {
+				return
+			}
+		}
+	}()
+
+	outs := make([]Out, 0, len(inputs))
+	for r := range p.Results() {
+		outs = append(outs, r.Value)
+	}
+	if err := p.Wait(); err != nil {
+		return outs, err
+	}
+	// Submit only fails when ctx is done
+	if len(outs) < len(inputs) {
+		return outs, errs.Wrap(ctx.Err(), errs.Other, "", "job", len(outs))
+	}
+	return outs, nil
+}
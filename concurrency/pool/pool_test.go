@@ -0,0 +1,159 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/vrnvu/go-examples/lang/errs"
+)
+
+func double(_ context.Context, j int) (int, error) {
+	return j * 2, nil
+}
+
+func TestPoolUnordered(t *testing.T) {
+	p := New(context.Background(), double, WithWorkers(3))
+	go func() {
+		for j := 1; j <= 5; j++ {
+			p.Submit(j)
+		}
+		p.Close()
+	}()
+
+	got := make([]int, 0)
+	for r := range p.Results() {
+		got = append(got, r.Value)
+	}
+	if err := p.Wait(); err != nil {
+		t.Fatalf("Wait returned %v", err)
+	}
+	sort.Ints(got)
+	want := []int{2, 4, 6, 8, 10}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, wanted %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %v, wanted %v", got, want)
+		}
+	}
+}
+
+func TestPoolOrdered(t *testing.T) {
+	// Later jobs finish first, the ordered pool has to hold them back
+	slow := func(_ context.Context, j int) (int, error) {
+		time.Sleep(time.Duration(10-j) * time.Millisecond)
+		return j, nil
+	}
+	got, err := Map(context.Background(), []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, slow, WithWorkers(4))
+	if err != nil {
+		t.Fatalf("Map returned %v", err)
+	}
+	for i, v := range got {
+		if v != i {
+			t.Fatalf("got %v, wanted results in submission order", got)
+		}
+	}
+}
+
+func TestPoolError(t *testing.T) {
+	errOdd := errors.New("odd")
+	handler := func(_ context.Context, j int) (int, error) {
+		if j%2 == 1 {
+			return 0, errOdd
+		}
+		return j, nil
+	}
+	_, err := Map(context.Background(), []int{0, 1, 2}, handler, WithWorkers(2))
+	if !errors.Is(err, errOdd) {
+		t.Errorf("got %v, wanted %v", err, errOdd)
+	}
+	if job, _ := errs.FieldOf(err, "job"); job != 1 {
+		t.Errorf("job field got %v, wanted 1", job)
+	}
+}
+
+func TestPoolSubmitAfterClose(t *testing.T) {
+	p := New(context.Background(), double, WithWorkers(1))
+	p.Close()
+	p.Close()
+	if err := p.Submit(1); !errors.Is(err, ErrClosed) || !errors.Is(err, errs.Unavailable) {
+		t.Errorf("got %v, wanted %v", err, ErrClosed)
+	}
+	for range p.Results() {
+	}
+	if err := p.Wait(); err != nil {
+		t.Errorf("Wait returned %v", err)
+	}
+}
+
+func TestPoolCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	p := New(ctx, double, WithWorkers(2), WithBuffer(0))
+	if err := p.Submit(1); err != nil && !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v, wanted %v", err, context.Canceled)
+	}
+	p.Close()
+	for r := range p.Results() {
+		if !errors.Is(r.Err, context.Canceled) || errs.KindOf(r.Err) != errs.Canceled {
+			t.Errorf("got %v, wanted %v", r.Err, context.Canceled)
+		}
+	}
+}
+
+func TestPoolCloseWhileSubmitBlocked(t *testing.T) {
+	release := make(chan struct{})
+	blocked := func(_ context.Context, j int) (int, error) {
+		<-release
+		return j, nil
+	}
+	p := New(context.Background(), blocked, WithWorkers(1), WithBuffer(0))
+	if err := p.Submit(1); err != nil {
+		t.Fatal(err)
+	}
+	// the worker is busy with 1 and there is no buffer, 2 blocks
+	submitted := make(chan error)
+	go func() {
+		submitted <- p.Submit(2)
+	}()
+	for {
+		p.mu.Lock()
+		sending := p.sending
+		p.mu.Unlock()
+		if sending == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	closed := make(chan struct{})
+	go func() {
+		p.Close()
+		if err := p.Submit(3); !errors.Is(err, ErrClosed) {
+			t.Errorf("Submit after Close got %v", err)
+		}
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("Close and Submit waited behind a blocked Submit")
+	}
+
+	close(release)
+	if err := <-submitted; err != nil {
+		t.Errorf("blocked Submit got %v", err)
+	}
+	var got []int
+	for r := range p.Results() {
+		got = append(got, r.Value)
+	}
+	sort.Ints(got)
+	if len(got) != 2 || got[0] != 1 || got[1] != 2 {
+		t.Errorf("got %v, wanted [1 2]", got)
+	}
+}
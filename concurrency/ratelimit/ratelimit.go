@@ -0,0 +1,284 @@
+// Package ratelimit is the reusable version of the RateLimiting example
+//
+// The example uses time.Tick for a steady limiter and a buffered channel
+// refilled by a goroutine for a bursty one. Limiter implements both as a
+// token bucket: the bucket holds up to burst tokens, it is refilled at a
+// constant rate and every event takes one token. A burst of 1 behaves like
+// the steady limiter. Tokens are computed from the elapsed time when they
+// are needed, so there is no refill goroutine to leak
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/vrnvu/go-examples/concurrency/clock"
+)
+
+// ErrStopped is returned by Wait once the limiter has been stopped
+var ErrStopped = errors.New("ratelimit: limiter stopped")
+
+// ErrExceedsBurst is returned by Wait when the event can never be allowed,
+// for example with a zero burst
+var ErrExceedsBurst = errors.New("ratelimit: event exceeds limiter burst")
+
+// Limit is the number of events per second
+type Limit float64
+
+// Inf allows every event
+const Inf = Limit(math.MaxFloat64)
+
+// Every converts the interval between events into a Limit
+// Every(200 * time.Millisecond) is the limiter of the RateLimiting example
+func Every(interval time.Duration) Limit {
+	if interval <= 0 {
+		return Inf
+	}
+	return 1 / Limit(interval.Seconds())
+}
+
+// durationFor returns the time needed to accumulate tokens
+func (l Limit) durationFor(tokens float64) time.Duration {
+	if l <= 0 {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(tokens / float64(l) * float64(time.Second))
+}
+
+// Option configures a Limiter
+type Option func(*Limiter)
+
+// WithClock replaces the real clock, tests use a clock.Fake
+func WithClock(c clock.Clock) Option {
+	return func(l *Limiter) {
+		l.clock = c
+	}
+}
+
+// Limiter is a token bucket rate limiter safe for concurrent use
+type Limiter struct {
+	clock clock.Clock
+
+	mu      sync.Mutex
+	limit   Limit
+	burst   int
+	tokens  float64
+	last    time.Time
+	stopped bool
+	stop    chan struct{}
+}
+
+// New returns a limiter allowing r events per second with bursts of up
+// to burst events, the bucket starts full like the prefilled burstyLimiter
+func New(r Limit, burst int, opts ...Option) *Limiter {
+	l := &Limiter{
+		clock: clock.New(),
+		limit: r,
+		burst: burst,
+		stop:  make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	l.tokens = float64(burst)
+	l.last = l.clock.Now()
+	return l
+}
+
+// Limit returns the current rate
+func (l *Limiter) Limit() Limit {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.limit
+}
+
+// Burst returns the current burst size
+func (l *Limiter) Burst() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.burst
+}
+
+// SetLimit changes the rate, the tokens accumulated so far are kept
+func (l *Limiter) SetLimit(r Limit) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.advance(l.clock.Now())
+	l.limit = r
+}
+
+// SetBurst changes the bucket size, extra tokens are dropped
+func (l *Limiter) SetBurst(burst int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.advance(l.clock.Now())
+	l.burst = burst
+	if l.tokens > float64(burst) {
+		l.tokens = float64(burst)
+	}
+}
+
+// Allow reports whether an event may happen now and takes a token if so
+func (l *Limiter) Allow() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.stopped {
+		return false
+	}
+	if l.limit == Inf {
+		return true
+	}
+	l.advance(l.clock.Now())
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
+
+// Reserve takes a token now, even if it is not available yet, and returns
+// when the event is allowed to happen. Check OK before using the reservation
+func (l *Limiter) Reserve() *Reservation {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.clock.Now()
+	r := &Reservation{limiter: l, timeToAct: now}
+	switch {
+	case l.stopped:
+		return r
+	case l.limit == Inf:
+		r.ok = true
+		return r
+	case l.burst < 1, l.limit <= 0 && l.advanced(now) < 1:
+		// The token would never arrive
+		return r
+	}
+	l.advance(now)
+	l.tokens--
+	r.ok = true
+	r.tokens = 1
+	if l.tokens < 0 {
+		r.timeToAct = now.Add(l.limit.durationFor(-l.tokens))
+	}
+	return r
+}
+
+// Wait blocks until an event is allowed, ctx is done or the limiter is stopped
+// When Wait returns an error the token is given back
+func (l *Limiter) Wait(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r := l.Reserve()
+	if !r.OK() {
+		if l.isStopped() {
+			return ErrStopped
+		}
+		return ErrExceedsBurst
+	}
+	delay := r.DelayFrom(l.clock.Now())
+	if delay <= 0 {
+		return nil
+	}
+	if deadline, ok := ctx.Deadline(); ok && deadline.Before(r.timeToAct) {
+		r.Cancel()
+		return context.DeadlineExceeded
+	}
+
+	timer := l.clock.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C():
+		return nil
+	case <-ctx.Done():
+		r.Cancel()
+		return ctx.Err()
+	case <-l.stop:
+		return ErrStopped
+	}
+}
+
+// Stop makes every pending and future Wait return ErrStopped and every
+// Allow return false. It is safe to call Stop more than once
+func (l *Limiter) Stop() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.stopped {
+		l.stopped = true
+		close(l.stop)
+	}
+}
+
+func (l *Limiter) isStopped() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.stopped
+}
+
+// advanced returns the tokens available at now without updating the bucket
+func (l *Limiter) advanced(now time.Time) float64 {
+	tokens := l.tokens
+	if elapsed := now.Sub(l.last); elapsed > 0 && l.limit > 0 {
+		tokens += elapsed.Seconds() * float64(l.limit)
+	}
+	if burst := float64(l.burst); tokens > burst {
+		tokens = burst
+	}
+	return tokens
+}
+
+// advance refills the bucket up to now, the caller holds l.mu
+func (l *Limiter) advance(now time.Time) {
+	l.tokens = l.advanced(now)
+	if now.After(l.last) {
+		l.last = now
+	}
+}
+
+// Reservation is a token taken by Reserve
+type Reservation struct {
+	limiter   *Limiter
+	ok        bool
+	tokens    float64
+	timeToAct time.Time
+}
+
+// OK reports whether the limiter can ever allow the event
+func (r *Reservation) OK() bool {
+	return r.ok
+}
+
+// Delay returns how long to wait before acting on the reservation
+func (r *Reservation) Delay() time.Duration {
+	return r.DelayFrom(r.limiter.clock.Now())
+}
+
+// DelayFrom returns how long to wait from now before acting
+func (r *Reservation) DelayFrom(now time.Time) time.Duration {
+	if !r.ok {
+		return time.Duration(math.MaxInt64)
+	}
+	if d := r.timeToAct.Sub(now); d > 0 {
+		return d
+	}
+	return 0
+}
+
+// Cancel gives the token back when the event will not happen
+func (r *Reservation) Cancel() {
+	if !r.ok || r.tokens == 0 {
+		return
+	}
+	l := r.limiter
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.advance(l.clock.Now())
+	l.tokens += r.tokens
+	if burst := float64(l.burst); l.tokens > burst {
+		l.tokens = burst
+	}
+	r.tokens = 0
+}
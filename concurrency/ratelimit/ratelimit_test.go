@@ -0,0 +1,165 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/vrnvu/go-examples/concurrency/clock"
+)
+
+var epoch = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestAllowBurstThenRate(t *testing.T) {
+	c := clock.NewFake(epoch)
+	l := New(Every(200*time.Millisecond), 3, WithClock(c))
+
+	for i := 0; i < 3; i++ {
+		if !l.Allow() {
+			t.Fatalf("event %d of the burst was not allowed", i)
+		}
+	}
+	if l.Allow() {
+		t.Fatalf("event after the burst was allowed")
+	}
+
+	c.Advance(100 * time.Millisecond)
+	if l.Allow() {
+		t.Errorf("event allowed before the token was refilled")
+	}
+	c.Advance(100 * time.Millisecond)
+	if !l.Allow() {
+		t.Errorf("event not allowed after the refill")
+	}
+
+	// The bucket never holds more than burst tokens
+	c.Advance(time.Hour)
+	allowed := 0
+	for l.Allow() {
+		allowed++
+	}
+	if allowed != 3 {
+		t.Errorf("got %d events after a long idle time, wanted 3", allowed)
+	}
+}
+
+func TestReserve(t *testing.T) {
+	c := clock.NewFake(epoch)
+	l := New(Every(time.Second), 1, WithClock(c))
+
+	if r := l.Reserve(); !r.OK() || r.Delay() != 0 {
+		t.Fatalf("first reservation got ok %v delay %v, wanted true 0", r.OK(), r.Delay())
+	}
+	r := l.Reserve()
+	if !r.OK() || r.Delay() != time.Second {
+		t.Fatalf("second reservation got ok %v delay %v, wanted true 1s", r.OK(), r.Delay())
+	}
+	r.Cancel()
+	c.Advance(time.Second)
+	if !l.Allow() {
+		t.Errorf("cancelled reservation did not give back its token")
+	}
+
+	if r := New(10, 0, WithClock(c)).Reserve(); r.OK() {
+		t.Errorf("reservation on a zero burst limiter was ok")
+	}
+}
+
+func TestWait(t *testing.T) {
+	c := clock.NewFake(epoch)
+	l := New(Every(200*time.Millisecond), 1, WithClock(c))
+	if err := l.Wait(context.Background()); err != nil {
+		t.Fatalf("first Wait returned %v", err)
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- l.Wait(context.Background())
+	}()
+	c.BlockUntil(1)
+	select {
+	case err := <-done:
+		t.Fatalf("Wait returned %v before the clock moved", err)
+	default:
+	}
+	c.Advance(200 * time.Millisecond)
+	if err := <-done; err != nil {
+		t.Errorf("Wait returned %v", err)
+	}
+}
+
+func TestWaitCancel(t *testing.T) {
+	c := clock.NewFake(epoch)
+	l := New(Every(time.Second), 1, WithClock(c))
+	l.Allow()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- l.Wait(ctx)
+	}()
+	c.BlockUntil(1)
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v, wanted %v", err, context.Canceled)
+	}
+
+	// The token taken by the cancelled Wait was given back
+	c.Advance(time.Second)
+	if !l.Allow() {
+		t.Errorf("token of the cancelled Wait was not given back")
+	}
+}
+
+func TestStop(t *testing.T) {
+	c := clock.NewFake(epoch)
+	l := New(Every(time.Second), 1, WithClock(c))
+	l.Allow()
+
+	done := make(chan error)
+	go func() {
+		done <- l.Wait(context.Background())
+	}()
+	c.BlockUntil(1)
+	l.Stop()
+	l.Stop()
+	if err := <-done; !errors.Is(err, ErrStopped) {
+		t.Errorf("got %v, wanted %v", err, ErrStopped)
+	}
+	if err := l.Wait(context.Background()); !errors.Is(err, ErrStopped) {
+		t.Errorf("Wait after Stop got %v, wanted %v", err, ErrStopped)
+	}
+	if l.Allow() {
+		t.Errorf("Allow after Stop returned true")
+	}
+}
+
+func TestReconfigure(t *testing.T) {
+	c := clock.NewFake(epoch)
+	l := New(Every(time.Second), 1, WithClock(c))
+	l.Allow()
+
+	l.SetLimit(Every(100 * time.Millisecond))
+	c.Advance(100 * time.Millisecond)
+	if !l.Allow() {
+		t.Errorf("faster limit was not applied")
+	}
+
+	l.SetBurst(5)
+	c.Advance(time.Second)
+	allowed := 0
+	for l.Allow() {
+		allowed++
+	}
+	if allowed != 5 {
+		t.Errorf("got %d events, wanted the new burst of 5", allowed)
+	}
+
+	l.SetLimit(Inf)
+	for i := 0; i < 100; i++ {
+		if !l.Allow() {
+			t.Fatalf("Inf limit refused an event")
+		}
+	}
+}
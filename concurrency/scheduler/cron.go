@@ -0,0 +1,247 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vrnvu/go-examples/lang/errs"
+)
+
+// Schedule gives the run times of a job, Next returns the first one
+// strictly after t, or the zero time when there is none
+type Schedule interface {
+	Next(t time.Time) time.Time
+}
+
+// Every runs every d, starting d after the job is added
+type Every time.Duration
+
+func (e Every) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(e))
+}
+
+// once is the Schedule of At
+type once time.Time
+
+func (o once) Next(t time.Time) time.Time {
+	if at := time.Time(o); at.After(t) {
+		return at
+	}
+	return time.Time{}
+}
+
+// Cron is a parsed cron expression
+type Cron struct {
+	expr                                  string
+	second, minute, hour, dom, month, dow bits
+	// a restricted day of month and day of week match if either matches,
+	// like in Vixie cron, a star in one of them leaves only the other
+	domStar, dowStar bool
+}
+
+// bits has bit i set when value i is allowed
+type bits uint64
+
+func (b bits) has(i int) bool {
+	return b&(1<<uint(i)) != 0
+}
+
+type cronField struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+var (
+	secondField = cronField{name: "second", max: 59}
+	minuteField = cronField{name: "minute", max: 59}
+	hourField   = cronField{name: "hour", max: 23}
+	domField    = cronField{name: "day of month", min: 1, max: 31}
+	monthField  = cronField{name: "month", min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	// 7 is Sunday too
+	dowField = cronField{name: "day of week", max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// ParseCron parses a cron expression, five fields "minute hour dom month
+// dow" or six with the seconds first. A field is * or a comma separated
+// list of values, ranges a-b and steps */n or a-b/n, months and days of
+// week can be names. The descriptors @yearly, @monthly, @weekly, @daily,
+// @hourly and "@every 1h30m" are accepted too
+// The errors are errs.Invalid
+func ParseCron(expr string) (Schedule, error) {
+	spec := strings.TrimSpace(expr)
+	if d, ok := strings.CutPrefix(spec, "@every "); ok {
+		every, err := time.ParseDuration(strings.TrimSpace(d))
+		if err != nil || every <= 0 {
+			return nil, errs.New(errs.Invalid, fmt.Sprintf("cron %q: bad duration", expr), "expr", expr)
+		}
+		return Every(every), nil
+	}
+	if d, ok := cronDescriptors[strings.ToLower(spec)]; ok {
+		spec = d
+	} else if strings.HasPrefix(spec, "@") {
+		return nil, errs.New(errs.Invalid, fmt.Sprintf("cron %q: unknown descriptor", expr), "expr", expr)
+	}
+
+	fields := strings.Fields(spec)
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return nil, errs.New(errs.Invalid, fmt.Sprintf("cron %q: got %d fields, wanted 5 or 6", expr, len(fields)), "expr", expr)
+	}
+	c := &Cron{expr: expr}
+	var err error
+	parse := func(dst *bits, text string, f cronField) {
+		if err == nil {
+			*dst, err = f.parse(text)
+			if err != nil {
+				err = errs.Wrap(err, errs.Invalid, fmt.Sprintf("cron %q: %s", expr, f.name), "expr", expr, "field", f.name)
+			}
+		}
+	}
+	parse(&c.second, fields[0], secondField)
+	parse(&c.minute, fields[1], minuteField)
+	parse(&c.hour, fields[2], hourField)
+	parse(&c.dom, fields[3], domField)
+	parse(&c.month, fields[4], monthField)
+	parse(&c.dow, fields[5], dowField)
+	if err != nil {
+		return nil, err
+	}
+	if c.dow.has(7) {
+		c.dow |= 1
+	}
+	c.domStar = fields[3] == "*" || fields[3] == "?"
+	c.dowStar = fields[5] == "*" || fields[5] == "?"
+	return c, nil
+}
+
+// MustParseCron is ParseCron for expressions known to be valid
+func MustParseCron(expr string) Schedule {
+	s, err := ParseCron(expr)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+func (f cronField) parse(text string) (bits, error) {
+	var b bits
+	for _, item := range strings.Split(text, ",") {
+		rng, stepText, hasStep := strings.Cut(item, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepText)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("bad step %q", stepText)
+			}
+			step = n
+		}
+		lo, hi := f.min, f.max
+		switch {
+		case rng == "*" || rng == "?":
+		case strings.Contains(rng, "-"):
+			a, z, _ := strings.Cut(rng, "-")
+			var err error
+			if lo, err = f.value(a); err != nil {
+				return 0, err
+			}
+			if hi, err = f.value(z); err != nil {
+				return 0, err
+			}
+			if lo > hi {
+				return 0, fmt.Errorf("empty range %q", rng)
+			}
+		default:
+			v, err := f.value(rng)
+			if err != nil {
+				return 0, err
+			}
+			lo = v
+			// a/n is a-max/n, a alone is just a
+			if !hasStep {
+				hi = v
+			}
+		}
+		for v := lo; v <= hi; v += step {
+			b |= 1 << uint(v)
+		}
+	}
+	return b, nil
+}
+
+func (f cronField) value(text string) (int, error) {
+	if v, ok := f.names[strings.ToLower(text)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(text)
+	if err != nil {
+		return 0, fmt.Errorf("bad value %q", text)
+	}
+	if v < f.min || v > f.max {
+		return 0, fmt.Errorf("%d out of [%d, %d]", v, f.min, f.max)
+	}
+	return v, nil
+}
+
+func (c *Cron) String() string {
+	return c.expr
+}
+
+func (c *Cron) dayMatches(t time.Time) bool {
+	dom, dow := c.dom.has(t.Day()), c.dow.has(int(t.Weekday()))
+	switch {
+	case c.domStar:
+		return dow
+	case c.dowStar:
+		return dom
+	}
+	return dom || dow
+}
+
+// Next returns the first time after t matching c, in the location of t
+// It gives up and returns the zero time after 5 years, for expressions
+// like February 30th that never match
+func (c *Cron) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Second).Add(time.Second)
+	limit := t.Year() + 5
+	for t.Year() <= limit {
+		y, mo, d := t.Date()
+		h, mi, s := t.Clock()
+		switch {
+		case !c.month.has(int(mo)):
+			t = time.Date(y, mo+1, 1, 0, 0, 0, 0, loc)
+		case !c.dayMatches(t):
+			t = time.Date(y, mo, d+1, 0, 0, 0, 0, loc)
+		case !c.hour.has(h):
+			t = time.Date(y, mo, d, h+1, 0, 0, 0, loc)
+		case !c.minute.has(mi):
+			t = time.Date(y, mo, d, h, mi+1, 0, 0, loc)
+		case !c.second.has(s):
+			t = time.Date(y, mo, d, h, mi, s+1, 0, loc)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,105 @@
+package scheduler
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/vrnvu/go-examples/lang/errs"
+)
+
+// Monday 2024-01-15 10:30:00 UTC
+var monday = time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+
+func date(s string) time.Time {
+	t, err := time.Parse("2006-01-02 15:04:05", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestCronNext(t *testing.T) {
+	tests := []struct {
+		expr string
+		from time.Time
+		want []string
+	}{
+		{"* * * * *", monday, []string{"2024-01-15 10:31:00", "2024-01-15 10:32:00"}},
+		{"*/15 * * * *", monday, []string{"2024-01-15 10:45:00", "2024-01-15 11:00:00"}},
+		{"0 9-17/4 * * *", monday, []string{"2024-01-15 13:00:00", "2024-01-15 17:00:00", "2024-01-16 09:00:00"}},
+		{"30 8 * * mon-fri", monday, []string{"2024-01-16 08:30:00", "2024-01-17 08:30:00"}},
+		{"0 0 * * sat,7", monday, []string{"2024-01-20 00:00:00", "2024-01-21 00:00:00", "2024-01-27 00:00:00"}},
+		{"0 12 1 */3 *", monday, []string{"2024-04-01 12:00:00", "2024-07-01 12:00:00"}},
+		{"0 0 29 feb *", monday, []string{"2024-02-29 00:00:00", "2028-02-29 00:00:00"}},
+		// day of month or day of week when both are restricted
+		{"0 0 13 * fri", monday, []string{"2024-01-19 00:00:00", "2024-01-26 00:00:00", "2024-02-02 00:00:00", "2024-02-09 00:00:00", "2024-02-13 00:00:00"}},
+		{"*/20 * * * * *", monday, []string{"2024-01-15 10:30:20", "2024-01-15 10:30:40", "2024-01-15 10:31:00"}},
+		{"@hourly", monday, []string{"2024-01-15 11:00:00", "2024-01-15 12:00:00"}},
+		{"@weekly", monday, []string{"2024-01-21 00:00:00"}},
+		{"@yearly", monday, []string{"2025-01-01 00:00:00"}},
+		{"@every 90m", monday, []string{"2024-01-15 12:00:00", "2024-01-15 13:30:00"}},
+		{"0 0 31 dec *", date("2024-12-31 00:00:00"), []string{"2025-12-31 00:00:00"}},
+		// from the middle of a second
+		{"* * * * * *", monday.Add(500 * time.Millisecond), []string{"2024-01-15 10:30:01"}},
+	}
+	for _, test := range tests {
+		s, err := ParseCron(test.expr)
+		if err != nil {
+			t.Errorf("%s: %v", test.expr, err)
+			continue
+		}
+		at := test.from
+		for _, want := range test.want {
+			at = s.Next(at)
+			if !at.Equal(date(want)) {
+				t.Errorf("%s: got %v, wanted %s", test.expr, at, want)
+				break
+			}
+		}
+	}
+}
+
+func TestCronNever(t *testing.T) {
+	if next := MustParseCron("0 0 30 feb *").Next(monday); !next.IsZero() {
+		t.Errorf("February 30th got %v", next)
+	}
+}
+
+func TestCronLocation(t *testing.T) {
+	loc := time.FixedZone("UTC+2", 2*60*60)
+	next := MustParseCron("0 9 * * *").Next(monday.In(loc))
+	if want := time.Date(2024, 1, 16, 9, 0, 0, 0, loc); !next.Equal(want) || next.Location() != loc {
+		t.Errorf("got %v, wanted %v", next, want)
+	}
+}
+
+func TestParseCronErrors(t *testing.T) {
+	tests := []struct {
+		expr, field string
+	}{
+		{"* * * *", ""},
+		{"* * * * * * *", ""},
+		{"60 * * * *", "minute"},
+		{"* 24 * * *", "hour"},
+		{"* * 0 * *", "day of month"},
+		{"* * * 13 *", "month"},
+		{"* * * foo *", "month"},
+		{"* * * * 8", "day of week"},
+		{"*/0 * * * *", "minute"},
+		{"5-1 * * * *", "minute"},
+		{"@fortnightly", ""},
+		{"@every soon", ""},
+		{"@every -1s", ""},
+	}
+	for _, test := range tests {
+		_, err := ParseCron(test.expr)
+		if !errors.Is(err, errs.Invalid) {
+			t.Errorf("%q: got %v, wanted an Invalid error", test.expr, err)
+			continue
+		}
+		if field, _ := errs.FieldOf(err, "field"); test.field != "" && field != test.field {
+			t.Errorf("%q: got field %v, wanted %s", test.expr, field, test.field)
+		}
+	}
+}
@@ -0,0 +1,332 @@
+// Package scheduler runs one-shot and recurring jobs, what Timers and
+// Tickers do for one timer with a done channel, for thousands of jobs
+//
+// The jobs wait in a hierarchical timer wheel driven by a single timer of
+// the clock, so a job costs no goroutine and no runtime timer while it
+// waits. Jobs run on Every durations or cron expressions, with optional
+// jitter, can be paused, resumed and cancelled by ID, and a MissedPolicy
+// decides what happens to the runs that were due while the scheduler was
+// late or the job paused. The clock is injectable, tests drive it with
+// clock.Fake
+package scheduler
+
+import (
+	"context"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/vrnvu/go-examples/concurrency/clock"
+)
+
+// ID identifies a job of a Scheduler
+type ID uint64
+
+// Job is the work of a job, at is the time the run was scheduled for,
+// without jitter. ctx is the one given to Run
+type Job func(ctx context.Context, at time.Time)
+
+// MissedPolicy decides which of the runs due at once are run, a run is
+// missed when it was due more than Options.Grace ago
+type MissedPolicy int
+
+const (
+	// RunOnce coalesces the missed runs into a single run, for the latest
+	RunOnce MissedPolicy = iota
+	// RunAll runs every due run in order, to catch up
+	RunAll
+	// Skip drops the missed runs, only a run on time is run
+	Skip
+)
+
+// maxCatchUp bounds the runs RunAll catches up at once
+const maxCatchUp = 1000
+
+// Options configures a Scheduler, the zero value is usable
+type Options struct {
+	// Clock is clock.New() by default
+	Clock clock.Clock
+	// Tick is the resolution of the timer wheel, 10ms by default. Jobs
+	// run at most a tick late
+	Tick time.Duration
+	// Grace is how late a run can be without being missed, one second by
+	// default and never less than Tick
+	Grace time.Duration
+	// Rand returns a random duration in [0, n) for the jitter
+	Rand func(n int64) int64
+}
+
+// JobOption configures a job
+type JobOption func(*job)
+
+// WithJitter delays every run by a random duration in [0, d), so jobs
+// scheduled at the same time do not all start at once
+func WithJitter(d time.Duration) JobOption {
+	return func(j *job) {
+		j.jitter = d
+	}
+}
+
+// WithMissed sets the MissedPolicy of the job, RunOnce by default
+func WithMissed(p MissedPolicy) JobOption {
+	return func(j *job) {
+		j.policy = p
+	}
+}
+
+// Info is the state of a job
+type Info struct {
+	ID ID
+	// Next is the next run, without jitter
+	Next   time.Time
+	Paused bool
+	// Runs counts the runs started and Missed the runs dropped by the
+	// MissedPolicy
+	Runs, Missed int
+}
+
+type job struct {
+	id       ID
+	schedule Schedule
+	run      Job
+	jitter   time.Duration
+	policy   MissedPolicy
+	// next is the next run without jitter, timer.deadline has the jitter
+	next         time.Time
+	paused       bool
+	runs, missed int
+	timer        wheelTimer
+}
+
+// Scheduler runs jobs once Run is called, its methods are safe for
+// concurrent use
+type Scheduler struct {
+	opts  Options
+	start time.Time
+
+	mu     sync.Mutex
+	wheel  wheel
+	jobs   map[ID]*job
+	nextID ID
+	// running tracks the goroutines of the runs, Run waits for them
+	running sync.WaitGroup
+}
+
+// New returns a scheduler, the jobs can be added before Run
+func New(opts Options) *Scheduler {
+	if opts.Clock == nil {
+		opts.Clock = clock.New()
+	}
+	if opts.Tick <= 0 {
+		opts.Tick = 10 * time.Millisecond
+	}
+	if opts.Grace <= 0 {
+		opts.Grace = time.Second
+	}
+	opts.Grace = max(opts.Grace, opts.Tick)
+	if opts.Rand == nil {
+		opts.Rand = rand.Int64N
+	}
+	return &Scheduler{opts: opts, start: opts.Clock.Now(), jobs: make(map[ID]*job)}
+}
+
+// At runs job once at t, right away when t has passed
+func (s *Scheduler) At(t time.Time, run Job, opts ...JobOption) ID {
+	return s.add(once(t), t, run, opts)
+}
+
+// After runs job once after d
+func (s *Scheduler) After(d time.Duration, run Job, opts ...JobOption) ID {
+	return s.At(s.opts.Clock.Now().Add(d), run, opts...)
+}
+
+// Every runs job every d, the first run is d from now
+func (s *Scheduler) Every(d time.Duration, run Job, opts ...JobOption) ID {
+	return s.Schedule(Every(d), run, opts...)
+}
+
+// Cron runs job on the times of a cron expression, see ParseCron
+func (s *Scheduler) Cron(expr string, run Job, opts ...JobOption) (ID, error) {
+	schedule, err := ParseCron(expr)
+	if err != nil {
+		return 0, err
+	}
+	return s.Schedule(schedule, run, opts...), nil
+}
+
+// Schedule runs job on the times of schedule after now, a schedule with
+// no time left is added but never runs
+func (s *Scheduler) Schedule(schedule Schedule, run Job, opts ...JobOption) ID {
+	return s.add(schedule, schedule.Next(s.opts.Clock.Now()), run, opts)
+}
+
+func (s *Scheduler) add(schedule Schedule, first time.Time, run Job, opts []JobOption) ID {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	j := &job{id: s.nextID, schedule: schedule, run: run, next: first}
+	j.timer.job = j
+	for _, opt := range opts {
+		opt(j)
+	}
+	s.jobs[j.id] = j
+	s.arm(j)
+	return j.id
+}
+
+// arm puts j in the wheel for j.next plus jitter, the caller holds s.mu
+func (s *Scheduler) arm(j *job) {
+	if j.next.IsZero() || j.paused {
+		return
+	}
+	at := j.next
+	if j.jitter > 0 {
+		at = at.Add(time.Duration(s.opts.Rand(int64(j.jitter))))
+	}
+	j.timer.deadline = s.tickOf(at)
+	s.wheel.add(&j.timer)
+}
+
+// tickOf is the first tick at or after t
+func (s *Scheduler) tickOf(t time.Time) uint64 {
+	d := t.Sub(s.start)
+	if d <= 0 {
+		return 0
+	}
+	return uint64((d + s.opts.Tick - 1) / s.opts.Tick)
+}
+
+// Cancel removes the job and reports whether it was there, a run already
+// started is not stopped
+func (s *Scheduler) Cancel(id ID) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.jobs[id]
+	if ok {
+		s.wheel.remove(&j.timer)
+		delete(s.jobs, id)
+	}
+	return ok
+}
+
+// Pause stops running the job until Resume
+func (s *Scheduler) Pause(id ID) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.jobs[id]
+	if ok && !j.paused {
+		s.wheel.remove(&j.timer)
+		j.paused = true
+	}
+	return ok
+}
+
+// Resume runs a paused job again, the runs that were due meanwhile are
+// handled by its MissedPolicy on the next tick
+func (s *Scheduler) Resume(id ID) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.jobs[id]
+	if ok && j.paused {
+		j.paused = false
+		s.arm(j)
+	}
+	return ok
+}
+
+// Job returns the state of a job
+func (s *Scheduler) Job(id ID) (Info, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.jobs[id]
+	if !ok {
+		return Info{}, false
+	}
+	return Info{ID: j.id, Next: j.next, Paused: j.paused, Runs: j.runs, Missed: j.missed}, true
+}
+
+// Len is the number of jobs, one-shot jobs leave once they have run
+func (s *Scheduler) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.jobs)
+}
+
+// Run fires the jobs until ctx is done, then waits for the runs in
+// progress and returns ctx.Err(). Every run has its own goroutine, a job
+// slower than its period overlaps with itself
+func (s *Scheduler) Run(ctx context.Context) error {
+	timer := s.opts.Clock.NewTimer(s.opts.Tick)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			s.running.Wait()
+			return ctx.Err()
+		case <-timer.C():
+		}
+		s.advance(ctx)
+		timer.Reset(s.opts.Tick)
+	}
+}
+
+// advance moves the wheel to the current tick and starts the due runs
+// The wheel catches up on every tick it missed, the timer of the loop
+// only says when to look
+func (s *Scheduler) advance(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.opts.Clock.Now()
+	current := uint64(0)
+	if d := now.Sub(s.start); d > 0 {
+		current = uint64(d / s.opts.Tick)
+	}
+	s.wheel.advance(current, func(t *wheelTimer) {
+		s.fire(ctx, t.job, now)
+	})
+}
+
+// fire runs j according to its MissedPolicy and arms its next run, the
+// caller holds s.mu
+func (s *Scheduler) fire(ctx context.Context, j *job, now time.Time) {
+	var due []time.Time
+	next := j.next
+	for !next.IsZero() && !next.After(now) && len(due) < maxCatchUp {
+		due = append(due, next)
+		next = j.schedule.Next(next)
+	}
+	if len(due) == 0 {
+		s.arm(j)
+		return
+	}
+
+	var runs []time.Time
+	switch j.policy {
+	case RunAll:
+		runs = due
+	case Skip:
+		if last := due[len(due)-1]; now.Sub(last) <= s.opts.Grace {
+			runs = due[len(due)-1:]
+		}
+	default:
+		runs = due[len(due)-1:]
+	}
+	j.runs += len(runs)
+	j.missed += len(due) - len(runs)
+	if len(runs) > 0 {
+		s.running.Add(1)
+		go func() {
+			defer s.running.Done()
+			for _, at := range runs {
+				j.run(ctx, at)
+			}
+		}()
+	}
+
+	j.next = next
+	if next.IsZero() {
+		delete(s.jobs, j.id)
+		return
+	}
+	s.arm(j)
+}
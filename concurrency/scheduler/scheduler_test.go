@@ -0,0 +1,232 @@
+package scheduler
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vrnvu/go-examples/concurrency/clock"
+)
+
+// harness runs a Scheduler on a fake clock with a one second tick
+type harness struct {
+	t     *testing.T
+	fake  *clock.Fake
+	s     *Scheduler
+	start time.Time
+
+	mu   sync.Mutex
+	runs map[string][]time.Duration
+}
+
+func newHarness(t *testing.T, opts Options) *harness {
+	h := &harness{t: t, start: monday, runs: make(map[string][]time.Duration)}
+	h.fake = clock.NewFake(monday)
+	opts.Clock = h.fake
+	if opts.Tick == 0 {
+		opts.Tick = time.Second
+	}
+	h.s = New(opts)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- h.s.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != context.Canceled {
+			t.Errorf("Run got %v", err)
+		}
+	})
+	return h
+}
+
+// job records the time of every run under name, as an offset of the start
+func (h *harness) job(name string) Job {
+	return func(ctx context.Context, at time.Time) {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		h.runs[name] = append(h.runs[name], at.Sub(h.start))
+	}
+}
+
+// advance moves the clock by d and waits for the loop to rearm its timer
+// and for the runs it started
+func (h *harness) advance(d time.Duration) {
+	h.fake.BlockUntil(1)
+	h.fake.Advance(d)
+	h.fake.BlockUntil(1)
+	h.s.running.Wait()
+}
+
+// advanceBy moves the clock by d one step at a time
+func (h *harness) advanceBy(d, step time.Duration) {
+	for ; d > 0; d -= step {
+		h.advance(step)
+	}
+}
+
+func (h *harness) check(name string, want ...time.Duration) {
+	h.t.Helper()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if got := h.runs[name]; !slices.Equal(got, want) {
+		h.t.Errorf("%s ran at %v, wanted %v", name, got, want)
+	}
+}
+
+func (h *harness) info(id ID) Info {
+	h.t.Helper()
+	info, ok := h.s.Job(id)
+	if !ok {
+		h.t.Fatalf("job %d not found", id)
+	}
+	return info
+}
+
+const sec = time.Second
+
+func TestSchedulerEvery(t *testing.T) {
+	h := newHarness(t, Options{})
+	id := h.s.Every(3*sec, h.job("every"))
+	h.advanceBy(10*sec, sec)
+	h.check("every", 3*sec, 6*sec, 9*sec)
+	if info := h.info(id); info.Runs != 3 || !info.Next.Equal(monday.Add(12*sec)) {
+		t.Errorf("got %+v", info)
+	}
+}
+
+func TestSchedulerOneShot(t *testing.T) {
+	h := newHarness(t, Options{})
+	h.s.After(2*sec, h.job("after"))
+	h.s.At(monday.Add(5*sec), h.job("at"))
+	h.s.At(monday.Add(-time.Hour), h.job("past"))
+	h.advanceBy(6*sec, sec)
+	h.check("after", 2*sec)
+	h.check("at", 5*sec)
+	h.check("past", -time.Hour)
+	if n := h.s.Len(); n != 0 {
+		t.Errorf("got %d jobs left, wanted none", n)
+	}
+}
+
+func TestSchedulerCron(t *testing.T) {
+	h := newHarness(t, Options{})
+	if _, err := h.s.Cron("*/20 * * * * *", h.job("cron")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.s.Cron("61 * * * *", h.job("bad")); err == nil {
+		t.Errorf("bad expression got no error")
+	}
+	h.advanceBy(time.Minute, 5*sec)
+	h.check("cron", 20*sec, 40*sec, 60*sec)
+}
+
+func TestSchedulerJitter(t *testing.T) {
+	var mu sync.Mutex
+	var ns []int64
+	h := newHarness(t, Options{Rand: func(n int64) int64 {
+		mu.Lock()
+		defer mu.Unlock()
+		ns = append(ns, n)
+		return n - 1
+	}})
+	var started []time.Duration
+	h.s.Every(10*sec, func(ctx context.Context, at time.Time) {
+		started = append(started, h.fake.Now().Sub(monday))
+		h.job("jitter")(ctx, at)
+	}, WithJitter(3*sec))
+	h.advanceBy(35*sec, sec)
+	// the runs start late but keep their time and period
+	h.check("jitter", 10*sec, 20*sec, 30*sec)
+	if want := []time.Duration{13 * sec, 23 * sec, 33 * sec}; !slices.Equal(started, want) {
+		t.Errorf("started at %v, wanted %v", started, want)
+	}
+	if len(ns) == 0 || ns[0] != int64(3*sec) {
+		t.Errorf("Rand got %v", ns)
+	}
+}
+
+func TestSchedulerMissed(t *testing.T) {
+	tests := []struct {
+		policy MissedPolicy
+		runs   []time.Duration
+		missed int
+	}{
+		{RunOnce, []time.Duration{4 * sec, 12 * sec, 16 * sec}, 1},
+		{RunAll, []time.Duration{4 * sec, 8 * sec, 12 * sec, 16 * sec}, 0},
+		{Skip, []time.Duration{4 * sec, 16 * sec}, 2},
+	}
+	for _, test := range tests {
+		h := newHarness(t, Options{})
+		id := h.s.Every(4*sec, h.job("job"), WithMissed(test.policy))
+		h.advanceBy(5*sec, sec)
+		h.s.Pause(id)
+		if !h.info(id).Paused {
+			t.Errorf("policy %d: not paused", test.policy)
+		}
+		h.advanceBy(9*sec, sec)
+		// 8 and 12 are due on resume, 12 is 3 seconds late
+		h.s.Resume(id)
+		h.advanceBy(3*sec, sec)
+		h.check("job", test.runs...)
+		if info := h.info(id); info.Missed != test.missed || info.Runs != len(test.runs) {
+			t.Errorf("policy %d: got %+v", test.policy, info)
+		}
+	}
+}
+
+func TestSchedulerLate(t *testing.T) {
+	h := newHarness(t, Options{})
+	h.s.Every(sec, h.job("once"))
+	h.s.Every(sec, h.job("all"), WithMissed(RunAll))
+	// the loop is blocked for 5 seconds, a single timer fire
+	h.advance(5 * sec)
+	h.check("once", 5*sec)
+	h.check("all", sec, 2*sec, 3*sec, 4*sec, 5*sec)
+}
+
+func TestSchedulerCancel(t *testing.T) {
+	h := newHarness(t, Options{})
+	id := h.s.Every(sec, h.job("cancel"))
+	h.advanceBy(2*sec, sec)
+	if !h.s.Cancel(id) {
+		t.Errorf("Cancel got false")
+	}
+	h.advanceBy(2*sec, sec)
+	h.check("cancel", sec, 2*sec)
+	if h.s.Cancel(id) || h.s.Pause(id) || h.s.Resume(id) {
+		t.Errorf("cancelled job still found")
+	}
+	if _, ok := h.s.Job(id); ok {
+		t.Errorf("Job of a cancelled job got true")
+	}
+}
+
+func TestSchedulerMany(t *testing.T) {
+	h := newHarness(t, Options{Tick: 10 * time.Millisecond})
+	const n = 5000
+	var mu sync.Mutex
+	late := 0
+	fired := make(map[int]bool)
+	for i := range n {
+		d := time.Duration(i%500) * 10 * time.Millisecond
+		h.s.After(d, func(ctx context.Context, at time.Time) {
+			mu.Lock()
+			defer mu.Unlock()
+			if now := h.fake.Now(); now.Before(at) || now.Sub(at) > 10*time.Millisecond {
+				late++
+			}
+			fired[i] = true
+		})
+	}
+	h.advanceBy(5*sec, 10*time.Millisecond)
+	if len(fired) != n || late != 0 {
+		t.Errorf("got %d of %d fired, %d late", len(fired), n, late)
+	}
+	if h.s.Len() != 0 {
+		t.Errorf("got %d jobs left", h.s.Len())
+	}
+}
@@ -0,0 +1,116 @@
+package scheduler
+
+// wheel is a hierarchical timer wheel, the structure the Linux kernel and
+// Kafka use to keep many timers: adding, removing and firing a timer is
+// O(1) instead of O(log n) for a heap
+//
+// Time is counted in ticks. Level 0 has one slot per tick for the next 64
+// ticks, level 1 one slot per 64 ticks for the next 64*64 and so on. A
+// timer goes into the lowest level whose range holds it, and when the
+// wheel reaches the slot of a higher level its timers are cascaded down
+// into finer slots. Timers further than the top level are parked in its
+// farthest slot and cascaded again until they are in range
+//
+// The wheel is not safe for concurrent use, Scheduler guards it
+type wheel struct {
+	// current is the last tick advanced to, every timer before it has fired
+	current uint64
+	levels  [wheelLevels][wheelSlots][]*wheelTimer
+	size    int
+}
+
+const (
+	wheelBits   = 6
+	wheelSlots  = 1 << wheelBits
+	wheelMask   = wheelSlots - 1
+	wheelLevels = 6
+)
+
+// wheelTimer is a timer in the wheel, deadline is its tick
+type wheelTimer struct {
+	deadline uint64
+	job      *job
+	// level and slot locate the timer for remove, pos is its index in the slot
+	level, slot, pos int
+	added            bool
+}
+
+func (w *wheel) len() int {
+	return w.size
+}
+
+// add puts t in its slot, a deadline already passed fires on the next tick
+func (w *wheel) add(t *wheelTimer) {
+	w.place(t, max(t.deadline, w.current+1))
+}
+
+// place puts t in the slot of deadline, which is not before current
+func (w *wheel) place(t *wheelTimer, deadline uint64) {
+	delta := deadline - w.current
+	level := 0
+	for level < wheelLevels-1 && delta >= 1<<(wheelBits*(level+1)) {
+		level++
+	}
+	if delta >= 1<<(wheelBits*wheelLevels) {
+		// out of range, the farthest slot of the top level
+		deadline = w.current + 1<<(wheelBits*wheelLevels) - 1
+	}
+	slot := int(deadline>>(wheelBits*level)) & wheelMask
+	t.level, t.slot, t.pos, t.added = level, slot, len(w.levels[level][slot]), true
+	w.levels[level][slot] = append(w.levels[level][slot], t)
+	w.size++
+}
+
+// remove takes t out of the wheel and reports whether it was in it
+func (w *wheel) remove(t *wheelTimer) bool {
+	if !t.added {
+		return false
+	}
+	timers := w.levels[t.level][t.slot]
+	last := timers[len(timers)-1]
+	timers[t.pos], last.pos = last, t.pos
+	timers[len(timers)-1] = nil
+	w.levels[t.level][t.slot] = timers[:len(timers)-1]
+	t.added = false
+	w.size--
+	return true
+}
+
+// advance moves the wheel to tick and calls fire with every timer whose
+// deadline is reached, in deadline order. fire may add timers
+func (w *wheel) advance(tick uint64, fire func(*wheelTimer)) {
+	for w.current < tick {
+		if w.size == 0 {
+			// nothing to cascade or fire, jump
+			w.current = tick
+			return
+		}
+		w.current++
+		c := w.current
+		// cascade the higher levels whose slot starts at this tick
+		for level := 1; level < wheelLevels; level++ {
+			if c&(1<<(wheelBits*level)-1) != 0 {
+				break
+			}
+			slot := int(c>>(wheelBits*level)) & wheelMask
+			// a deadline of this very tick lands in the level 0 slot
+			// fired right below
+			for _, t := range w.take(level, slot) {
+				w.place(t, max(t.deadline, c))
+			}
+		}
+		for _, t := range w.take(0, int(c)&wheelMask) {
+			fire(t)
+		}
+	}
+}
+
+func (w *wheel) take(level, slot int) []*wheelTimer {
+	timers := w.levels[level][slot]
+	w.levels[level][slot] = nil
+	w.size -= len(timers)
+	for _, t := range timers {
+		t.added = false
+	}
+	return timers
+}
@@ -0,0 +1,102 @@
+package scheduler
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestWheel(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	var w wheel
+	timers := make([]*wheelTimer, 3000)
+	fired := make(map[*wheelTimer]uint64)
+	for i := range timers {
+		// every level up to 64^3 ticks, and a few exact slot boundaries
+		deadline := uint64(rng.Int63n(1 << 18))
+		if i%100 == 0 {
+			deadline = uint64(1 << (6 * (i / 100 % 4)))
+		}
+		timers[i] = &wheelTimer{deadline: deadline}
+		w.add(timers[i])
+	}
+	// remove one in ten, a removed timer never fires
+	removed := make(map[*wheelTimer]bool)
+	for i := 0; i < len(timers); i += 10 {
+		if !w.remove(timers[i]) {
+			t.Fatalf("remove of timer %d got false", i)
+		}
+		removed[timers[i]] = true
+	}
+	if w.remove(timers[0]) {
+		t.Errorf("second remove got true")
+	}
+
+	last := uint64(0)
+	for w.len() > 0 {
+		// steps of a few ticks and big jumps
+		to := w.current + uint64(rng.Int63n(5000)) + 1
+		w.advance(to, func(timer *wheelTimer) {
+			if timer.deadline > w.current {
+				t.Errorf("timer of tick %d fired at %d", timer.deadline, w.current)
+			}
+			if w.current < last {
+				t.Errorf("tick %d fired after tick %d", w.current, last)
+			}
+			last = w.current
+			fired[timer] = w.current
+		})
+	}
+	for i, timer := range timers {
+		at, ok := fired[timer]
+		switch {
+		case removed[timer]:
+			if ok {
+				t.Errorf("removed timer %d fired", i)
+			}
+		case !ok:
+			t.Errorf("timer %d of tick %d did not fire", i, timer.deadline)
+		case at != max(timer.deadline, 1):
+			t.Errorf("timer %d of tick %d fired at %d", i, timer.deadline, at)
+		}
+	}
+}
+
+func TestWheelAddWhileFiring(t *testing.T) {
+	var w wheel
+	w.advance(100, nil)
+	// a timer in the past fires on the next tick
+	past := &wheelTimer{deadline: 10}
+	w.add(past)
+	var ticks []uint64
+	var fire func(*wheelTimer)
+	fire = func(timer *wheelTimer) {
+		ticks = append(ticks, w.current)
+		if len(ticks) < 3 {
+			timer.deadline = w.current + 70
+			w.add(timer)
+		}
+	}
+	w.advance(1000, fire)
+	want := []uint64{101, 171, 241}
+	if len(ticks) != len(want) || ticks[0] != want[0] || ticks[1] != want[1] || ticks[2] != want[2] {
+		t.Errorf("fired at %v, wanted %v", ticks, want)
+	}
+}
+
+func BenchmarkWheel(b *testing.B) {
+	rng := rand.New(rand.NewSource(1))
+	var w wheel
+	timers := make([]wheelTimer, 10000)
+	fire := func(t *wheelTimer) {
+		t.deadline = w.current + uint64(rng.Int63n(10000)) + 1
+		w.add(t)
+	}
+	for i := range timers {
+		timers[i].deadline = uint64(rng.Int63n(10000))
+		w.add(&timers[i])
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		w.advance(w.current+1, fire)
+	}
+}
@@ -0,0 +1,65 @@
+package concurrency
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/vrnvu/go-examples/concurrency/clock"
+	"github.com/vrnvu/go-examples/concurrency/scheduler"
+)
+
+// Timers and Tickers cost a runtime timer and usually a goroutine each.
+// The scheduler keeps every job in a timer wheel behind a single timer
+
+// ScheduledJobs runs a recurring job, a cron job, a one-shot and a jittered
+// batch of jobs for two seconds, and pauses and cancels some on the way
+func ScheduledJobs() {
+	start := clk.Now()
+	var mu sync.Mutex
+	logRun := func(name string) scheduler.Job {
+		return func(ctx context.Context, at time.Time) {
+			mu.Lock()
+			defer mu.Unlock()
+			fmt.Printf("%-8s due %4dms ran %4dms\n", name,
+				at.Sub(start).Milliseconds(), clk.Now().Sub(start).Milliseconds())
+		}
+	}
+
+	s := scheduler.New(scheduler.Options{Clock: clk})
+	every := s.Every(400*time.Millisecond, logRun("every"))
+	// every second on the second, with seconds in the expression
+	if _, err := s.Cron("* * * * * *", logRun("cron")); err != nil {
+		fmt.Println(err)
+	}
+	s.After(time.Second, logRun("after"))
+	// a thousand jobs due at the same time, spread over 100ms
+	var batch sync.WaitGroup
+	batch.Add(1000)
+	for range 1000 {
+		s.After(500*time.Millisecond, func(ctx context.Context, at time.Time) {
+			batch.Done()
+		}, scheduler.WithJitter(100*time.Millisecond))
+	}
+	fmt.Println("jobs:", s.Len())
+
+	ctx, cancel := clock.WithTimeout(clk, context.Background(), 2*time.Second)
+	defer cancel()
+	go func() {
+		batch.Wait()
+		fmt.Println("batch done at", clk.Now().Sub(start).Round(10*time.Millisecond))
+	}()
+	go func() {
+		clk.Sleep(900 * time.Millisecond)
+		s.Pause(every)
+		fmt.Println("every paused")
+		clk.Sleep(800 * time.Millisecond)
+		// the runs missed while paused become one, the default RunOnce
+		s.Resume(every)
+		fmt.Println("every resumed")
+	}()
+	err := s.Run(ctx)
+	info, _ := s.Job(every)
+	fmt.Printf("every runs %d missed %d, jobs left %d, %v\n", info.Runs, info.Missed, s.Len(), err)
+}
@@ -0,0 +1,205 @@
+package concurrency
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/vrnvu/go-examples/concurrency/mapreduce"
+	"github.com/vrnvu/go-examples/lang/errs"
+)
+
+// Student is one row of students.csv
+type Student struct {
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+}
+
+// Errors wrapped by RowError, check them with errors.Is
+var (
+	ErrMissingField  = errors.New("missing field")
+	ErrEmptyName     = errors.New("empty name")
+	ErrAgeOutOfRange = errors.New("age out of range")
+)
+
+// RowError is a bad row of a students file
+// Line and Column are 1 based, Column is 0 when the whole row is wrong
+type RowError struct {
+	File   string
+	Line   int
+	Column int
+	Field  string
+	Value  string
+	Err    error
+}
+
+func (e *RowError) Error() string {
+	pos := fmt.Sprintf("line %d", e.Line)
+	if e.Column > 0 {
+		pos += fmt.Sprintf(", column %d", e.Column)
+	}
+	if e.File != "" {
+		pos = e.File + ": " + pos
+	}
+	if e.Field == "" {
+		return fmt.Sprintf("%s: %v", pos, e.Err)
+	}
+	return fmt.Sprintf("%s: %s %q: %v", pos, e.Field, e.Value, e.Err)
+}
+
+func (e *RowError) Unwrap() error {
+	return e.Err
+}
+
+// rowError reports e through errs as Invalid with its position as fields
+func rowError(e *RowError) error {
+	return errs.Wrap(e, errs.Invalid, "", "file", e.File, "line", e.Line, "column", e.Column, "field", e.Field)
+}
+
+// BadRowPolicy decides what the loader does with a RowError
+type BadRowPolicy int
+
+const (
+	// FailOnBadRow stops at the first bad row
+	FailOnBadRow BadRowPolicy = iota
+	// SkipBadRows drops bad rows silently
+	SkipBadRows
+	// CollectBadRows drops bad rows and keeps their errors
+	CollectBadRows
+)
+
+// LoadOptions configures a StudentReader
+// A zero MaxAge means 150
+type LoadOptions struct {
+	// Header means the first row holds the column names, the name and
+	// age columns are then found by name in any order
+	Header bool
+	Policy BadRowPolicy
+	MinAge int
+	MaxAge int
+	// File is only used in the error messages
+	File string
+}
+
+// StudentReader decodes the students of a mapreduce.RecordReader, it is the
+// Source of the MapReduce examples
+type StudentReader struct {
+	r    mapreduce.RecordReader
+	opts LoadOptions
+	errs errs.List
+}
+
+// NewStudentReader returns a reader of the students csv in r
+func NewStudentReader(r io.Reader, opts LoadOptions) *StudentReader {
+	csv := mapreduce.CSV(mapreduce.CSVOptions{Header: opts.Header})
+	return NewStudentRecordReader(csv(r, opts.File), opts)
+}
+
+// NewStudentRecordReader returns a reader of the students of any format
+// The fields are looked up by name, "name" and "age", when the format has
+// names and by position otherwise
+func NewStudentRecordReader(r mapreduce.RecordReader, opts LoadOptions) *StudentReader {
+	if opts.MaxAge == 0 {
+		opts.MaxAge = 150
+	}
+	return &StudentReader{r: r, opts: opts}
+}
+
+// Next returns the next valid student and io.EOF after the last one
+// Bad rows are handled according to the BadRowPolicy, with FailOnBadRow
+// the error is a *RowError
+func (s *StudentReader) Next() (Student, error) {
+	for {
+		student, err := s.next()
+		if err == nil || err == io.EOF {
+			return student, err
+		}
+		var rowErr *RowError
+		if !errors.As(err, &rowErr) {
+			return Student{}, err
+		}
+		switch s.opts.Policy {
+		case SkipBadRows:
+		case CollectBadRows:
+			s.errs.Add(err)
+		default:
+			return Student{}, err
+		}
+	}
+}
+
+// Errors returns the errors of the bad rows seen so far with CollectBadRows
+func (s *StudentReader) Errors() []error {
+	return s.errs.Errors()
+}
+
+func (s *StudentReader) next() (Student, error) {
+	record, err := s.r.Read()
+	var posErr *mapreduce.PositionError
+	if errors.As(err, &posErr) {
+		return Student{}, rowError(&RowError{File: posErr.File, Line: posErr.Line, Column: posErr.Column, Err: posErr.Err})
+	}
+	if err != nil {
+		return Student{}, err
+	}
+
+	name, nameCol, okName := record.Field("name", 0)
+	age, ageCol, okAge := record.Field("age", 1)
+	if !okName || !okAge {
+		err := fmt.Errorf("%w: got %d fields", ErrMissingField, len(record.Fields))
+		if record.Names != nil {
+			err = fmt.Errorf("%w: got columns %v", ErrMissingField, record.Names)
+		}
+		return Student{}, rowError(&RowError{File: record.File, Line: record.Line, Err: err})
+	}
+	fieldError := func(column int, field, value string, err error) error {
+		return rowError(&RowError{File: record.File, Line: record.Line, Column: column, Field: field, Value: value, Err: err})
+	}
+
+	if strings.TrimSpace(name) == "" {
+		return Student{}, fieldError(nameCol, "name", name, ErrEmptyName)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(age))
+	if err != nil {
+		return Student{}, fieldError(ageCol, "age", age, err)
+	}
+	if n < s.opts.MinAge || n > s.opts.MaxAge {
+		return Student{}, fieldError(ageCol, "age", age, fmt.Errorf("%w [%d, %d]", ErrAgeOutOfRange, s.opts.MinAge, s.opts.MaxAge))
+	}
+	return Student{Name: strings.TrimSpace(name), Age: n}, nil
+}
+
+// LoadStudents reads every student of r
+// With CollectBadRows the valid students are returned together with the
+// errors of the bad rows, an *errs.Multi when there are several
+func LoadStudents(r io.Reader, opts LoadOptions) ([]Student, error) {
+	reader := NewStudentReader(r, opts)
+	students := make([]Student, 0)
+	for {
+		student, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return students, err
+		}
+		students = append(students, student)
+	}
+	return students, reader.errs.Err()
+}
+
+// LoadStudentsFile opens path and loads its students
+func LoadStudentsFile(path string, opts LoadOptions) ([]Student, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if opts.File == "" {
+		opts.File = path
+	}
+	return LoadStudents(f, opts)
+}
@@ -0,0 +1,119 @@
+package concurrency
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/vrnvu/go-examples/lang/errs"
+)
+
+func TestLoadStudents(t *testing.T) {
+	students, err := LoadStudents(strings.NewReader("\"a\",30\n\"b\",20\n"), LoadOptions{})
+	if err != nil {
+		t.Fatalf("LoadStudents returned %v", err)
+	}
+	want := []Student{{"a", 30}, {"b", 20}}
+	if len(students) != len(want) || students[0] != want[0] || students[1] != want[1] {
+		t.Errorf("got %v, wanted %v", students, want)
+	}
+}
+
+func TestLoadStudentsHeader(t *testing.T) {
+	input := "age,name\n30,a\n20,b\n"
+	students, err := LoadStudents(strings.NewReader(input), LoadOptions{Header: true})
+	if err != nil {
+		t.Fatalf("LoadStudents returned %v", err)
+	}
+	if len(students) != 2 || students[0] != (Student{"a", 30}) {
+		t.Errorf("got %v, wanted the columns found by name", students)
+	}
+
+	_, err = LoadStudents(strings.NewReader("first,last\na,b\n"), LoadOptions{Header: true})
+	var rowErr *RowError
+	if !errors.As(err, &rowErr) || rowErr.Line != 2 || !errors.Is(err, ErrMissingField) {
+		t.Errorf("got %v, wanted a missing field RowError on line 2", err)
+	}
+}
+
+var badStudents = "a,30\nb,abc\nc\nd,200\n,10\ne,40\n"
+
+func TestLoadStudentsFail(t *testing.T) {
+	students, err := LoadStudents(strings.NewReader(badStudents), LoadOptions{File: "students.csv"})
+	var rowErr *RowError
+	if !errors.As(err, &rowErr) {
+		t.Fatalf("got %v, wanted a *RowError", err)
+	}
+	if rowErr.Line != 2 || rowErr.Column != 3 || rowErr.Field != "age" || rowErr.Value != "abc" {
+		t.Errorf("got %+v, wanted line 2 column 3 of field age", rowErr)
+	}
+	if !errors.Is(err, strconv.ErrSyntax) || !errors.Is(err, errs.Invalid) {
+		t.Errorf("got %v, wanted an Invalid error wrapping strconv.ErrSyntax", err)
+	}
+	if line, _ := errs.FieldOf(err, "line"); line != 2 {
+		t.Errorf("line field got %v, wanted 2", line)
+	}
+	if got := err.Error(); got != `students.csv: line 2, column 3: age "abc": strconv.Atoi: parsing "abc": invalid syntax` {
+		t.Errorf("got message %q", got)
+	}
+	if len(students) != 1 {
+		t.Errorf("got %v, wanted the student before the bad row", students)
+	}
+}
+
+func TestLoadStudentsSkipAndCollect(t *testing.T) {
+	students, err := LoadStudents(strings.NewReader(badStudents), LoadOptions{Policy: SkipBadRows})
+	if err != nil || len(students) != 2 {
+		t.Errorf("got %v %v, wanted 2 students and no error", students, err)
+	}
+
+	students, err = LoadStudents(strings.NewReader(badStudents), LoadOptions{Policy: CollectBadRows, MaxAge: 120})
+	if len(students) != 2 {
+		t.Errorf("got %v, wanted 2 students", students)
+	}
+	for _, target := range []error{strconv.ErrSyntax, ErrMissingField, ErrAgeOutOfRange, ErrEmptyName} {
+		if !errors.Is(err, target) {
+			t.Errorf("got %v, wanted it to contain %v", err, target)
+		}
+	}
+
+	reader := NewStudentReader(strings.NewReader(badStudents), LoadOptions{Policy: CollectBadRows, MaxAge: 120})
+	for {
+		if _, err := reader.Next(); err != nil {
+			break
+		}
+	}
+	lines := make([]int, 0)
+	for _, err := range reader.Errors() {
+		var rowErr *RowError
+		errors.As(err, &rowErr)
+		lines = append(lines, rowErr.Line)
+	}
+	if len(lines) != 4 || lines[0] != 2 || lines[1] != 3 || lines[2] != 4 || lines[3] != 5 {
+		t.Errorf("got bad rows on lines %v, wanted [2 3 4 5]", lines)
+	}
+}
+
+func TestLoadStudentsFileMissing(t *testing.T) {
+	_, err := LoadStudentsFile("missing.csv", LoadOptions{})
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("got %v, wanted %v", err, os.ErrNotExist)
+	}
+}
+
+func TestStudentJSON(t *testing.T) {
+	students, err := LoadStudentsFile("students.csv", LoadOptions{})
+	if err != nil {
+		t.Fatalf("LoadStudentsFile returned %v", err)
+	}
+	b, err := json.Marshal(students[0])
+	if err != nil {
+		t.Fatalf("Marshal returned %v", err)
+	}
+	if got, want := string(b), `{"name":"a","age":30}`; got != want {
+		t.Errorf("got %s, wanted %s", got, want)
+	}
+}
@@ -0,0 +1,133 @@
+package concurrency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/vrnvu/go-examples/concurrency/supervisor"
+)
+
+// A panic in Worker or in the StatefulGoroutines owner crashes the whole
+// program. Under a supervisor the panic becomes an error and the goroutine
+// is started again
+
+// SupervisedWorker is a Worker child that panics on a poison job
+// The job is lost but the worker is restarted and goes on with the next ones
+func SupervisedWorker(id int, jobs <-chan int, results chan<- int, poison int) supervisor.Child {
+	return func(ctx context.Context) error {
+		for {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case j, more := <-jobs:
+				if !more {
+					return nil
+				}
+				if j == poison {
+					panic(fmt.Sprintf("worker %d can not handle job %d", id, j))
+				}
+				results <- j * 2
+			}
+		}
+	}
+}
+
+// ownerChild is the StatefulGoroutines owner as a child, it panics on a
+// negative value. The state lives in the child so a restart loses it
+func ownerChild(reads chan readOp, writes chan writeOp) supervisor.Child {
+	return func(ctx context.Context) error {
+		state := make(map[int]int)
+		for {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case read := <-reads:
+				read.resp <- state[read.key]
+			case write := <-writes:
+				if write.val < 0 {
+					panic(fmt.Sprintf("negative value %d", write.val))
+				}
+				state[write.key] = write.val
+				write.resp <- true
+			}
+		}
+	}
+}
+
+// Supervisors runs workers under a one-for-one supervisor and the state
+// owner with its client under a one-for-all supervisor
+func Supervisors() {
+	jobs := make(chan int, 9)
+	results := make(chan int, 9)
+	for j := 1; j <= 9; j++ {
+		jobs <- j
+	}
+	close(jobs)
+
+	logEvent := func(e supervisor.Event) {
+		if e.Err != nil {
+			fmt.Printf("%s failed: %v, restart %v\n", e.Child, e.Err, e.Restart)
+		}
+	}
+	// Transient, the workers are done for good once jobs is closed
+	var specs []supervisor.Spec
+	for w := 1; w <= 3; w++ {
+		specs = append(specs, supervisor.Spec{
+			Name:    fmt.Sprintf("worker %d", w),
+			Run:     SupervisedWorker(w, jobs, results, 5),
+			Restart: supervisor.Transient,
+		})
+	}
+	err := supervisor.New(supervisor.Options{Clock: clk, OnEvent: logEvent}, specs...).Run(context.Background())
+	close(results)
+	var got []int
+	for r := range results {
+		got = append(got, r)
+	}
+	sort.Ints(got)
+	fmt.Println("results:", got, "err:", err)
+
+	// The client writes 42, reads it back and writes a bad value that
+	// crashes the owner. One-for-all restarts both and the restarted client
+	// finds the state lost
+	reads, writes := make(chan readOp), make(chan writeOp)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// the supervisor never runs two clients at once, starts needs no lock
+	starts := 0
+	client := func(ctx context.Context) error {
+		starts++
+		send := func(val int) {
+			select {
+			case writes <- writeOp{key: 1, val: val, resp: make(chan bool, 1)}:
+			case <-ctx.Done():
+			}
+		}
+		read := func() int {
+			op := readOp{key: 1, resp: make(chan int, 1)}
+			select {
+			case reads <- op:
+				return <-op.resp
+			case <-ctx.Done():
+				return 0
+			}
+		}
+		if starts > 1 {
+			fmt.Println("client start", starts, "reads state[1]:", read())
+			cancel()
+			return nil
+		}
+		send(42)
+		fmt.Println("client start", starts, "reads state[1]:", read())
+		send(-1)
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	err = supervisor.New(supervisor.Options{Strategy: supervisor.OneForAll, Clock: clk, OnEvent: logEvent},
+		supervisor.Spec{Name: "owner", Run: ownerChild(reads, writes)},
+		supervisor.Spec{Name: "client", Run: client, Restart: supervisor.Transient},
+	).Run(ctx)
+	fmt.Println("stopped:", errors.Is(err, context.Canceled))
+}
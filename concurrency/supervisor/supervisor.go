@@ -0,0 +1,321 @@
+// Package supervisor runs goroutines the way Erlang supervisors run
+// processes: a child that fails, by returning an error or by panicking, is
+// restarted according to a strategy, and the supervisor gives up once the
+// children restart too often
+//
+// It is what Worker and the StatefulGoroutines owner are missing, a panic
+// in one of them no longer crashes the program
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/vrnvu/go-examples/concurrency/clock"
+	"github.com/vrnvu/go-examples/lang/errs"
+)
+
+// ErrTooManyRestarts is returned by Run once MaxRestarts is exceeded, it
+// wraps the failure that caused the last restart
+var ErrTooManyRestarts = errors.New("supervisor: too many restarts")
+
+// PanicError is a recovered panic, Value is what was given to panic
+// Safe wraps it in an errs.Internal error whose stack is the one of the panic
+type PanicError struct {
+	Value any
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Unwrap returns the value of panic(err), so errors.Is finds err
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// Safe calls fn and turns a panic into an error instead of crashing
+// The error is an *errs.Error of kind Internal wrapping a *PanicError,
+// errs.Stack returns the frames of the panic
+func Safe(fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			// the deferred call runs on top of the panicking frames, so
+			// the stack captured by Wrap starts where panic was called
+			err = errs.Wrap(&PanicError{Value: r}, errs.Internal, "")
+		}
+	}()
+	return fn()
+}
+
+// Restart says which exits of a child are restarted
+type Restart int
+
+const (
+	// Permanent children are always restarted, even when they return nil
+	Permanent Restart = iota
+	// Transient children are restarted when they fail
+	Transient
+	// Temporary children are never restarted
+	Temporary
+)
+
+func (r Restart) restarts(err error) bool {
+	switch r {
+	case Permanent:
+		return true
+	case Transient:
+		return err != nil
+	}
+	return false
+}
+
+// Strategy says which children restart when one of them fails
+type Strategy int
+
+const (
+	// OneForOne restarts only the child that exited
+	OneForOne Strategy = iota
+	// OneForAll stops every other child and restarts them all, for
+	// children that depend on each other
+	OneForAll
+)
+
+// Child is the body of a supervised goroutine, it must return once ctx is done
+type Child func(ctx context.Context) error
+
+// Spec describes a child
+type Spec struct {
+	Name    string
+	Run     Child
+	Restart Restart
+}
+
+// Backoff delays the restarts, the nth restart within the window waits
+// Initial * 2^(n-1), at most Max. A zero Initial restarts at once
+type Backoff struct {
+	Initial time.Duration
+	Max     time.Duration
+}
+
+func (b Backoff) delay(n int) time.Duration {
+	if b.Initial <= 0 || n <= 0 {
+		return 0
+	}
+	d := b.Initial
+	for i := 1; i < n; i++ {
+		if b.Max > 0 && d >= b.Max {
+			break
+		}
+		d *= 2
+	}
+	if b.Max > 0 && d > b.Max {
+		d = b.Max
+	}
+	return d
+}
+
+// Event is reported to Options.OnEvent every time a child exits
+type Event struct {
+	Child string
+	// Err is nil when the child returned nil, a *PanicError when it panicked
+	Err error
+	// Restart is set when the child is restarted, after Delay
+	Restart bool
+	Delay   time.Duration
+}
+
+// Options configures a Supervisor, the zero value is one-for-one with at
+// most 3 restarts in 5 seconds and no backoff
+type Options struct {
+	Strategy Strategy
+	// MaxRestarts within Window, more make Run return ErrTooManyRestarts
+	MaxRestarts int
+	Window      time.Duration
+	Backoff     Backoff
+	// Clock measures the window and the backoff, clock.New() by default
+	Clock clock.Clock
+	// OnEvent is called from the goroutine of Run
+	OnEvent func(Event)
+}
+
+// Supervisor runs and restarts its children
+type Supervisor struct {
+	opts     Options
+	children []*child
+	exits    chan exit
+	restarts []time.Time
+}
+
+type child struct {
+	spec    Spec
+	cancel  context.CancelFunc
+	running bool
+}
+
+type exit struct {
+	index int
+	err   error
+}
+
+// New returns a supervisor of the children of specs, Run starts them
+func New(opts Options, specs ...Spec) *Supervisor {
+	if opts.MaxRestarts <= 0 {
+		opts.MaxRestarts = 3
+	}
+	if opts.Window <= 0 {
+		opts.Window = 5 * time.Second
+	}
+	if opts.Clock == nil {
+		opts.Clock = clock.New()
+	}
+	s := &Supervisor{opts: opts, exits: make(chan exit, len(specs))}
+	for _, spec := range specs {
+		s.children = append(s.children, &child{spec: spec})
+	}
+	return s
+}
+
+// Run starts the children and supervises them until they have all exited
+// for good, it then returns nil. It returns ctx.Err() once ctx is done and
+// an error wrapping ErrTooManyRestarts when the children fail too often
+// Every child has exited when Run returns
+//
+// While it waits for a backoff the supervisor does not handle other exits,
+// they are handled in order afterwards
+func (s *Supervisor) Run(ctx context.Context) error {
+	for i := range s.children {
+		s.start(ctx, i)
+	}
+	for s.running() > 0 {
+		var e exit
+		select {
+		case e = <-s.exits:
+		case <-ctx.Done():
+			return s.shutdown(ctx.Err())
+		}
+		c := s.children[e.index]
+		c.running = false
+		c.cancel()
+		if ctx.Err() != nil {
+			return s.shutdown(ctx.Err())
+		}
+		if !c.spec.Restart.restarts(e.err) {
+			s.event(Event{Child: c.spec.Name, Err: e.err})
+			continue
+		}
+
+		delay, err := s.restart(c.spec.Name, e.err)
+		if err != nil {
+			s.event(Event{Child: c.spec.Name, Err: e.err})
+			return s.shutdown(err)
+		}
+		s.event(Event{Child: c.spec.Name, Err: e.err, Restart: true, Delay: delay})
+		restart := []int{e.index}
+		if s.opts.Strategy == OneForAll {
+			restart = append(restart, s.stopOthers(e.index)...)
+		}
+		if err := s.wait(ctx, delay); err != nil {
+			return s.shutdown(err)
+		}
+		for _, i := range restart {
+			s.start(ctx, i)
+		}
+	}
+	return nil
+}
+
+func (s *Supervisor) start(ctx context.Context, i int) {
+	c := s.children[i]
+	childCtx, cancel := context.WithCancel(ctx)
+	c.cancel, c.running = cancel, true
+	go func() {
+		err := Safe(func() error { return c.spec.Run(childCtx) })
+		s.exits <- exit{i, errs.Wrap(err, errs.Other, "", "child", c.spec.Name)}
+	}()
+}
+
+func (s *Supervisor) running() int {
+	n := 0
+	for _, c := range s.children {
+		if c.running {
+			n++
+		}
+	}
+	return n
+}
+
+// restart records a restart and returns its backoff, or an error when the
+// restarts within the window exceed MaxRestarts
+func (s *Supervisor) restart(name string, cause error) (time.Duration, error) {
+	now := s.opts.Clock.Now()
+	recent := s.restarts[:0]
+	for _, t := range s.restarts {
+		if now.Sub(t) < s.opts.Window {
+			recent = append(recent, t)
+		}
+	}
+	s.restarts = append(recent, now)
+	if len(s.restarts) > s.opts.MaxRestarts {
+		if cause == nil {
+			cause = errors.New("exited")
+		}
+		return 0, errs.Wrap(fmt.Errorf("%w: %d in %v, last %s: %w", ErrTooManyRestarts, len(s.restarts), s.opts.Window, name, cause),
+			errs.Unavailable, "", "restarts", len(s.restarts))
+	}
+	return s.opts.Backoff.delay(len(s.restarts)), nil
+}
+
+// stopOthers cancels the running children but i, waits for them and
+// returns their indexes. Their exits are not restarts
+func (s *Supervisor) stopOthers(i int) []int {
+	var stopped []int
+	for j, c := range s.children {
+		if j != i && c.running {
+			c.cancel()
+			stopped = append(stopped, j)
+		}
+	}
+	for range stopped {
+		e := <-s.exits
+		s.children[e.index].running = false
+	}
+	return stopped
+}
+
+func (s *Supervisor) wait(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := s.opts.Clock.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C():
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// shutdown stops every child and returns err once they have exited
+func (s *Supervisor) shutdown(err error) error {
+	for _, c := range s.children {
+		if c.running {
+			c.cancel()
+		}
+	}
+	for s.running() > 0 {
+		e := <-s.exits
+		s.children[e.index].running = false
+	}
+	return err
+}
+
+func (s *Supervisor) event(e Event) {
+	if s.opts.OnEvent != nil {
+		s.opts.OnEvent(e)
+	}
+}
@@ -0,0 +1,284 @@
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"io"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/vrnvu/go-examples/concurrency/clock"
+	"github.com/vrnvu/go-examples/lang/errs"
+)
+
+func panicky() error {
+	panic("i panic")
+}
+
+func TestSafe(t *testing.T) {
+	err := Safe(panicky)
+	var pe *PanicError
+	if !errors.As(err, &pe) || pe.Value != "i panic" || err.Error() != "panic: i panic" {
+		t.Fatalf("got %v, wanted a *PanicError of i panic", err)
+	}
+	if !errors.Is(err, errs.Internal) {
+		t.Errorf("got kind %v, wanted internal", errs.KindOf(err))
+	}
+	if stack := errs.Stack(err); len(stack) < 3 || !strings.Contains(stackFunctions(stack), "supervisor.panicky") {
+		t.Errorf("stack does not hold the panic: %s", stackFunctions(stack))
+	}
+
+	if err := Safe(func() error { panic(io.EOF) }); !errors.Is(err, io.EOF) {
+		t.Errorf("panic(io.EOF) got %v", err)
+	}
+	if err := Safe(func() error { return io.EOF }); err != io.EOF {
+		t.Errorf("without a panic got %v, wanted the error of fn", err)
+	}
+	var m map[string]int
+	if err := Safe(func() error { m["a"] = 1; return nil }); err == nil {
+		t.Errorf("a runtime panic was not recovered")
+	}
+}
+
+func stackFunctions(stack []runtime.Frame) string {
+	var names []string
+	for _, f := range stack {
+		names = append(names, f.Function)
+	}
+	return strings.Join(names, " ")
+}
+
+func TestBackoff(t *testing.T) {
+	b := Backoff{Initial: 10 * time.Millisecond, Max: 50 * time.Millisecond}
+	want := []time.Duration{0, 10, 20, 40, 50, 50}
+	for n, w := range want {
+		if got := b.delay(n); got != w*time.Millisecond {
+			t.Errorf("delay(%d) got %v, wanted %v", n, got, w*time.Millisecond)
+		}
+	}
+	if d := (Backoff{}).delay(5); d != 0 {
+		t.Errorf("zero Backoff got %v", d)
+	}
+}
+
+// counted returns a child that counts its starts and runs body
+func counted(starts *atomic.Int32, body func(ctx context.Context, start int32) error) Child {
+	return func(ctx context.Context) error {
+		return body(ctx, starts.Add(1))
+	}
+}
+
+func untilDone(ctx context.Context) error {
+	<-ctx.Done()
+	return nil
+}
+
+// events collects the events of a supervisor
+type events struct {
+	mu  sync.Mutex
+	all []Event
+}
+
+func (e *events) add(ev Event) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.all = append(e.all, ev)
+}
+
+func (e *events) restarts(name string) int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	n := 0
+	for _, ev := range e.all {
+		if ev.Child == name && ev.Restart {
+			n++
+		}
+	}
+	return n
+}
+
+func TestOneForOne(t *testing.T) {
+	var flaky, steady atomic.Int32
+	ctx, cancel := context.WithCancel(context.Background())
+	var evs events
+	s := New(Options{OnEvent: evs.add},
+		Spec{Name: "flaky", Run: counted(&flaky, func(ctx context.Context, start int32) error {
+			if start < 3 {
+				panic("flaky")
+			}
+			cancel()
+			return untilDone(ctx)
+		})},
+		Spec{Name: "steady", Run: counted(&steady, func(ctx context.Context, _ int32) error { return untilDone(ctx) })},
+	)
+	if err := s.Run(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Run got %v, wanted context.Canceled", err)
+	}
+	if flaky.Load() != 3 || steady.Load() != 1 {
+		t.Errorf("got %d flaky and %d steady starts, wanted 3 and 1", flaky.Load(), steady.Load())
+	}
+	if evs.restarts("flaky") != 2 {
+		t.Errorf("got events %v", evs.all)
+	}
+	var pe *PanicError
+	if !errors.As(evs.all[0].Err, &pe) {
+		t.Errorf("event error got %v, wanted a *PanicError", evs.all[0].Err)
+	}
+	if name, _ := errs.FieldOf(evs.all[0].Err, "child"); name != "flaky" {
+		t.Errorf("child field got %v", name)
+	}
+}
+
+func TestRestartPolicies(t *testing.T) {
+	var permanent, transient, temporary atomic.Int32
+	s := New(Options{MaxRestarts: 10},
+		// fails twice, then returns nil and is not restarted again
+		Spec{Name: "transient", Restart: Transient, Run: counted(&transient, func(_ context.Context, start int32) error {
+			if start < 3 {
+				return io.ErrUnexpectedEOF
+			}
+			return nil
+		})},
+		Spec{Name: "temporary", Restart: Temporary, Run: counted(&temporary, func(context.Context, int32) error {
+			return io.ErrUnexpectedEOF
+		})},
+		Spec{Name: "permanent", Restart: Permanent, Run: counted(&permanent, func(_ context.Context, start int32) error {
+			if start < 3 {
+				return nil
+			}
+			// the last start waits for the others to be done for good
+			for transient.Load() < 3 || temporary.Load() < 1 {
+				time.Sleep(time.Millisecond)
+			}
+			return nil
+		})},
+	)
+	// permanent never stops by itself, it is stopped by MaxRestarts
+	err := s.Run(context.Background())
+	if !errors.Is(err, ErrTooManyRestarts) {
+		t.Fatalf("Run got %v, wanted ErrTooManyRestarts", err)
+	}
+	if transient.Load() != 3 || temporary.Load() != 1 {
+		t.Errorf("got %d transient and %d temporary starts, wanted 3 and 1", transient.Load(), temporary.Load())
+	}
+
+	// without permanent children Run returns once they are all done
+	var n atomic.Int32
+	s = New(Options{}, Spec{Name: "once", Restart: Transient, Run: counted(&n, func(context.Context, int32) error { return nil })})
+	if err := s.Run(context.Background()); err != nil || n.Load() != 1 {
+		t.Errorf("Run got %v after %d starts, wanted nil after 1", err, n.Load())
+	}
+}
+
+func TestOneForAll(t *testing.T) {
+	var a, b atomic.Int32
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := New(Options{Strategy: OneForAll},
+		Spec{Name: "a", Run: counted(&a, func(ctx context.Context, start int32) error {
+			if start == 1 {
+				// wait for b to run so it has something to stop
+				for b.Load() == 0 {
+					time.Sleep(time.Millisecond)
+				}
+				return errors.New("a failed")
+			}
+			return untilDone(ctx)
+		})},
+		Spec{Name: "b", Run: counted(&b, func(ctx context.Context, start int32) error {
+			if start == 2 {
+				cancel()
+			}
+			return untilDone(ctx)
+		})},
+	)
+	if err := s.Run(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Run got %v", err)
+	}
+	if a.Load() != 2 || b.Load() != 2 {
+		t.Errorf("got %d a and %d b starts, wanted both restarted once", a.Load(), b.Load())
+	}
+}
+
+func TestMaxRestarts(t *testing.T) {
+	cause := errors.New("always")
+	var n atomic.Int32
+	s := New(Options{MaxRestarts: 2}, Spec{Name: "bad", Run: counted(&n, func(context.Context, int32) error { return cause })})
+	err := s.Run(context.Background())
+	if !errors.Is(err, ErrTooManyRestarts) || !errors.Is(err, cause) || !errors.Is(err, errs.Unavailable) {
+		t.Fatalf("Run got %v, wanted ErrTooManyRestarts wrapping the cause", err)
+	}
+	if n.Load() != 3 {
+		t.Errorf("got %d starts, wanted 3", n.Load())
+	}
+	if !strings.Contains(err.Error(), "3 in 5s, last bad: always") {
+		t.Errorf("got message %q", err)
+	}
+}
+
+// fakeRun runs s with a fake clock and calls step every time the
+// supervisor waits for a backoff, Run is cancelled after steps waits
+func fakeRun(t *testing.T, s *Supervisor, fake *clock.Fake, steps int, step func(i int)) error {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- s.Run(ctx) }()
+	for i := 0; i < steps; i++ {
+		fake.BlockUntil(1)
+		step(i)
+	}
+	fake.BlockUntil(1)
+	cancel()
+	return <-done
+}
+
+func TestBackoffWithFakeClock(t *testing.T) {
+	fake := clock.NewFake(time.Unix(0, 0))
+	var evs events
+	s := New(Options{
+		MaxRestarts: 10,
+		Window:      time.Hour,
+		Backoff:     Backoff{Initial: time.Second, Max: 5 * time.Second},
+		Clock:       fake,
+		OnEvent:     evs.add,
+	}, Spec{Name: "bad", Run: func(context.Context) error { return io.EOF }})
+
+	want := []time.Duration{1, 2, 4, 5, 5}
+	err := fakeRun(t, s, fake, len(want), func(i int) {
+		// nothing restarts a moment before the backoff is over
+		fake.Advance(want[i]*time.Second - time.Millisecond)
+		if got := evs.restarts("bad"); got != i+1 {
+			t.Errorf("step %d: %d restarts before the backoff was over", i, got)
+		}
+		fake.Advance(time.Millisecond)
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Run got %v", err)
+	}
+	for i, w := range want {
+		if d := evs.all[i].Delay; d != w*time.Second {
+			t.Errorf("restart %d waited %v, wanted %v", i+1, d, w*time.Second)
+		}
+	}
+}
+
+func TestRestartWindow(t *testing.T) {
+	fake := clock.NewFake(time.Unix(0, 0))
+	// the restarts are a second apart and the window is shorter, so they
+	// never add up to MaxRestarts
+	s := New(Options{
+		MaxRestarts: 1,
+		Window:      500 * time.Millisecond,
+		Backoff:     Backoff{Initial: time.Second},
+		Clock:       fake,
+	}, Spec{Name: "bad", Run: func(context.Context) error { return io.EOF }})
+	err := fakeRun(t, s, fake, 5, func(int) { fake.Advance(time.Second) })
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Run got %v, wanted the restarts to stay under MaxRestarts", err)
+	}
+}
@@ -1,3 +1,3 @@
 module github.com/vrnvu/go-examples
 
-go 1.13
+go 1.24
@@ -0,0 +1,164 @@
+// Package collections has the generic helpers Go did not have before generics
+// The functions never modify their input and always return new slices
+package collections
+
+import (
+	"cmp"
+	"slices"
+)
+
+// Map returns f of every element
+func Map[T, U any](xs []T, f func(T) U) []U {
+	result := make([]U, 0, len(xs))
+	for _, x := range xs {
+		result = append(result, f(x))
+	}
+	return result
+}
+
+// Filter returns the elements keep returns true for, in order
+func Filter[T any](xs []T, keep func(T) bool) []T {
+	result := make([]T, 0)
+	for _, x := range xs {
+		if keep(x) {
+			result = append(result, x)
+		}
+	}
+	return result
+}
+
+// Reduce folds the elements from left to right starting with init
+func Reduce[T, A any](xs []T, init A, f func(A, T) A) A {
+	acc := init
+	for _, x := range xs {
+		acc = f(acc, x)
+	}
+	return acc
+}
+
+// GroupBy groups the elements by key, each group keeps the input order
+func GroupBy[T any, K comparable](xs []T, key func(T) K) map[K][]T {
+	groups := make(map[K][]T)
+	for _, x := range xs {
+		k := key(x)
+		groups[k] = append(groups[k], x)
+	}
+	return groups
+}
+
+// Partition splits the elements in the ones pred returns true for and the rest
+func Partition[T any](xs []T, pred func(T) bool) (in, out []T) {
+	in, out = make([]T, 0), make([]T, 0)
+	for _, x := range xs {
+		if pred(x) {
+			in = append(in, x)
+		} else {
+			out = append(out, x)
+		}
+	}
+	return in, out
+}
+
+// Chunk splits xs in slices of size elements, the last one can be shorter
+// It panics if size is not positive
+func Chunk[T any](xs []T, size int) [][]T {
+	if size <= 0 {
+		panic("collections: Chunk size must be positive")
+	}
+	chunks := make([][]T, 0, (len(xs)+size-1)/size)
+	for start := 0; start < len(xs); start += size {
+		end := min(start+size, len(xs))
+		chunks = append(chunks, slices.Clone(xs[start:end]))
+	}
+	return chunks
+}
+
+// Window returns every run of size consecutive elements, sliding by one
+// It is empty when xs is shorter than size and panics if size is not positive
+func Window[T any](xs []T, size int) [][]T {
+	if size <= 0 {
+		panic("collections: Window size must be positive")
+	}
+	windows := make([][]T, 0, max(len(xs)-size+1, 0))
+	for start := 0; start+size <= len(xs); start++ {
+		windows = append(windows, slices.Clone(xs[start:start+size]))
+	}
+	return windows
+}
+
+// Pair is an element of Zip
+type Pair[A, B any] struct {
+	First  A
+	Second B
+}
+
+// Zip pairs the elements of as and bs, it stops at the shorter one
+func Zip[A, B any](as []A, bs []B) []Pair[A, B] {
+	n := min(len(as), len(bs))
+	pairs := make([]Pair[A, B], 0, n)
+	for i := 0; i < n; i++ {
+		pairs = append(pairs, Pair[A, B]{as[i], bs[i]})
+	}
+	return pairs
+}
+
+// Distinct drops the repeated elements, the first one is kept
+func Distinct[T comparable](xs []T) []T {
+	seen := make(map[T]bool, len(xs))
+	result := make([]T, 0)
+	for _, x := range xs {
+		if !seen[x] {
+			seen[x] = true
+			result = append(result, x)
+		}
+	}
+	return result
+}
+
+// FlatMap concatenates f of every element
+func FlatMap[T, U any](xs []T, f func(T) []U) []U {
+	result := make([]U, 0, len(xs))
+	for _, x := range xs {
+		result = append(result, f(x)...)
+	}
+	return result
+}
+
+// SortBy returns a copy of xs sorted by key, equal keys keep their order
+func SortBy[T any, K cmp.Ordered](xs []T, key func(T) K) []T {
+	result := slices.Clone(xs)
+	slices.SortStableFunc(result, func(a, b T) int {
+		return cmp.Compare(key(a), key(b))
+	})
+	return result
+}
+
+// Keys returns the keys of m sorted
+func Keys[K cmp.Ordered, V any](m map[K]V) []K {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// MapValues returns a map with the same keys and f of every value
+func MapValues[K comparable, V, W any](m map[K]V, f func(V) W) map[K]W {
+	result := make(map[K]W, len(m))
+	for k, v := range m {
+		result[k] = f(v)
+	}
+	return result
+}
+
+// FilterMap returns the entries keep returns true for
+func FilterMap[K comparable, V any](m map[K]V, keep func(K, V) bool) map[K]V {
+	result := make(map[K]V)
+	for k, v := range m {
+		if keep(k, v) {
+			result[k] = v
+		}
+	}
+	return result
+}
@@ -0,0 +1,115 @@
+package collections
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func check(t *testing.T, name string, got, want interface{}) {
+	t.Helper()
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("%s: got %v, wanted %v", name, got, want)
+	}
+}
+
+var ints = []int{1, 2, 3, 4, 5}
+
+func isEven(x int) bool { return x%2 == 0 }
+
+func TestMapFilterReduce(t *testing.T) {
+	tests := []struct {
+		name      string
+		got, want interface{}
+	}{
+		{"map", Map(ints, strconv.Itoa), []string{"1", "2", "3", "4", "5"}},
+		{"map empty", Map(nil, strconv.Itoa), []string{}},
+		{"filter", Filter(ints, isEven), []int{2, 4}},
+		{"filter none", Filter(ints, func(int) bool { return false }), []int{}},
+		{"reduce sum", Reduce(ints, 0, func(acc, x int) int { return acc + x }), 15},
+		{"reduce order", Reduce(ints, "", func(acc string, x int) string { return acc + strconv.Itoa(x) }), "12345"},
+		{"reduce empty", Reduce(nil, 7, func(acc, x int) int { return acc + x }), 7},
+		{"flat map", FlatMap([]string{"a b", "", "c"}, strings.Fields), []string{"a", "b", "c"}},
+		{"distinct", Distinct([]int{3, 1, 3, 2, 1}), []int{3, 1, 2}},
+		{"distinct empty", Distinct([]string(nil)), []string{}},
+	}
+	for _, test := range tests {
+		check(t, test.name, test.got, test.want)
+	}
+}
+
+func TestGroupByPartition(t *testing.T) {
+	words := []string{"go", "is", "fun", "and", "fast"}
+	check(t, "group by", GroupBy(words, func(w string) int { return len(w) }),
+		map[int][]string{2: {"go", "is"}, 3: {"fun", "and"}, 4: {"fast"}})
+	check(t, "group by empty", GroupBy(nil, func(w string) int { return len(w) }), map[int][]string{})
+
+	even, odd := Partition(ints, isEven)
+	check(t, "partition in", even, []int{2, 4})
+	check(t, "partition out", odd, []int{1, 3, 5})
+}
+
+func TestChunkWindow(t *testing.T) {
+	tests := []struct {
+		name      string
+		size      int
+		got, want [][]int
+	}{
+		{"chunk 2", 2, Chunk(ints, 2), [][]int{{1, 2}, {3, 4}, {5}}},
+		{"chunk 5", 5, Chunk(ints, 5), [][]int{{1, 2, 3, 4, 5}}},
+		{"chunk 9", 9, Chunk(ints, 9), [][]int{{1, 2, 3, 4, 5}}},
+		{"chunk empty", 3, Chunk([]int{}, 3), [][]int{}},
+		{"window 3", 3, Window(ints, 3), [][]int{{1, 2, 3}, {2, 3, 4}, {3, 4, 5}}},
+		{"window 1", 1, Window(ints[:2], 1), [][]int{{1}, {2}}},
+		{"window too big", 6, Window(ints, 6), [][]int{}},
+	}
+	for _, test := range tests {
+		check(t, test.name, test.got, test.want)
+	}
+
+	// the chunks are copies
+	chunks := Chunk(ints, 2)
+	chunks[0][0] = 100
+	check(t, "chunk copy", ints[0], 1)
+
+	for _, f := range []func(){func() { Chunk(ints, 0) }, func() { Window(ints, -1) }} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("size 0 did not panic")
+				}
+			}()
+			f()
+		}()
+	}
+}
+
+func TestZip(t *testing.T) {
+	check(t, "zip", Zip(ints, []string{"a", "b"}), []Pair[int, string]{{1, "a"}, {2, "b"}})
+	check(t, "zip empty", Zip([]int{}, []string{"a"}), []Pair[int, string]{})
+}
+
+type person struct {
+	name string
+	age  int
+}
+
+func TestSortBy(t *testing.T) {
+	people := []person{{"eve", 30}, {"ann", 25}, {"bob", 30}, {"kai", 20}}
+	byAge := SortBy(people, func(p person) int { return p.age })
+	check(t, "sort by age", fmt.Sprint(byAge), "[{kai 20} {ann 25} {eve 30} {bob 30}]")
+	byName := SortBy(people, func(p person) string { return p.name })
+	check(t, "sort by name", fmt.Sprint(byName), "[{ann 25} {bob 30} {eve 30} {kai 20}]")
+	check(t, "sort copy", people[0].name, "eve")
+}
+
+func TestMaps(t *testing.T) {
+	ages := map[string]int{"eve": 30, "ann": 25, "kai": 20}
+	check(t, "keys", Keys(ages), []string{"ann", "eve", "kai"})
+	check(t, "map values", MapValues(ages, func(age int) bool { return age >= 25 }),
+		map[string]bool{"eve": true, "ann": true, "kai": false})
+	check(t, "filter map", FilterMap(ages, func(name string, age int) bool { return age > 20 && name != "eve" }),
+		map[string]int{"ann": 25})
+}
@@ -0,0 +1,175 @@
+package lang
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// FieldChange is one field of an employee with different values
+type FieldChange struct {
+	Field    string
+	Old, New string
+}
+
+// EmployeeChange is an employee found on both sides with different fields
+// Index is the position in the slices, -1 when the order is ignored
+type EmployeeChange struct {
+	Index    int
+	Old, New Employee
+	Fields   []FieldChange
+}
+
+// EmployeeDiff is what changes from the old slice to the new one
+type EmployeeDiff struct {
+	Added   []Employee
+	Removed []Employee
+	Changed []EmployeeChange
+}
+
+// Empty reports whether both slices were equal
+func (d EmployeeDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// String prints one line per difference, - removed, + added and ~ changed
+func (d EmployeeDiff) String() string {
+	var b strings.Builder
+	for _, e := range d.Removed {
+		fmt.Fprintf(&b, "- %v\n", e)
+	}
+	for _, e := range d.Added {
+		fmt.Fprintf(&b, "+ %v\n", e)
+	}
+	for _, c := range d.Changed {
+		fields := make([]string, 0, len(c.Fields))
+		for _, f := range c.Fields {
+			fields = append(fields, fmt.Sprintf("%s %s -> %s", f.Field, f.Old, f.New))
+		}
+		at := ""
+		if c.Index >= 0 {
+			at = fmt.Sprintf("[%d] ", c.Index)
+		}
+		fmt.Fprintf(&b, "~ %s%s: %s\n", at, c.Old.name, strings.Join(fields, ", "))
+	}
+	return b.String()
+}
+
+func (e Employee) String() string {
+	return fmt.Sprintf("%s salary %v sales %d bonus %v", e.name, e.salary, e.sales, e.bonus)
+}
+
+// fieldChanges compares every field, amounts are compared with Cmp so the
+// zero Money is equal to a zero amount of any currency
+func fieldChanges(old, new Employee) []FieldChange {
+	var changes []FieldChange
+	if old.name != new.name {
+		changes = append(changes, FieldChange{"name", old.name, new.name})
+	}
+	if old.salary.Cmp(new.salary) != 0 {
+		changes = append(changes, FieldChange{"salary", old.salary.String(), new.salary.String()})
+	}
+	if old.sales != new.sales {
+		changes = append(changes, FieldChange{"sales", strconv.Itoa(old.sales), strconv.Itoa(new.sales)})
+	}
+	if old.bonus.Cmp(new.bonus) != 0 {
+		changes = append(changes, FieldChange{"bonus", old.bonus.String(), new.bonus.String()})
+	}
+	return changes
+}
+
+// DiffOption changes how Diff compares the slices
+type DiffOption func(*diffConfig)
+
+type diffConfig struct {
+	ignoreOrder bool
+}
+
+// IgnoreOrder compares the slices as multisets, [a, b] equals [b, a] but
+// [a, a] does not equal [a, b]
+func IgnoreOrder() DiffOption {
+	return func(c *diffConfig) {
+		c.ignoreOrder = true
+	}
+}
+
+// Diff compares all the fields of the employees of old and new
+// By default the employees are compared position by position
+// With IgnoreOrder the equal employees are paired first, the rest are
+// paired by name in order and changed, and what is left is added or removed
+func Diff(old, new []Employee, opts ...DiffOption) EmployeeDiff {
+	var c diffConfig
+	for _, opt := range opts {
+		opt(&c)
+	}
+	if c.ignoreOrder {
+		return diffUnordered(old, new)
+	}
+
+	var d EmployeeDiff
+	for i := 0; i < len(old) && i < len(new); i++ {
+		if fields := fieldChanges(old[i], new[i]); len(fields) > 0 {
+			d.Changed = append(d.Changed, EmployeeChange{i, old[i], new[i], fields})
+		}
+	}
+	if len(old) > len(new) {
+		d.Removed = append(d.Removed, old[len(new):]...)
+	}
+	if len(new) > len(old) {
+		d.Added = append(d.Added, new[len(old):]...)
+	}
+	return d
+}
+
+func diffUnordered(old, new []Employee) EmployeeDiff {
+	var d EmployeeDiff
+	paired := make([]bool, len(new))
+	var unpaired []Employee
+	for _, o := range old {
+		found := false
+		for j, n := range new {
+			if !paired[j] && len(fieldChanges(o, n)) == 0 {
+				paired[j], found = true, true
+				break
+			}
+		}
+		if !found {
+			unpaired = append(unpaired, o)
+		}
+	}
+
+	for _, o := range unpaired {
+		found := false
+		for j, n := range new {
+			if !paired[j] && o.name == n.name {
+				paired[j], found = true, true
+				d.Changed = append(d.Changed, EmployeeChange{-1, o, n, fieldChanges(o, n)})
+				break
+			}
+		}
+		if !found {
+			d.Removed = append(d.Removed, o)
+		}
+	}
+	for j, n := range new {
+		if !paired[j] {
+			d.Added = append(d.Added, n)
+		}
+	}
+	return d
+}
+
+// DiffPointers is Diff of the employees pointed to, nil is the zero Employee
+func DiffPointers(old, new []*Employee, opts ...DiffOption) EmployeeDiff {
+	return Diff(derefEmployees(old), derefEmployees(new), opts...)
+}
+
+func derefEmployees(pes []*Employee) []Employee {
+	result := make([]Employee, len(pes))
+	for i, pe := range pes {
+		if pe != nil {
+			result[i] = *pe
+		}
+	}
+	return result
+}
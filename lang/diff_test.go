@@ -0,0 +1,85 @@
+package lang
+
+import (
+	"testing"
+
+	"github.com/vrnvu/go-examples/lang/money"
+)
+
+var (
+	diffA  = *NewEmployee("a", euros("5000"), 5, money.Money{})
+	diffA2 = *NewEmployee("a", euros("5000"), 5, euros("0"))
+	diffB  = *NewEmployee("b", euros("6000"), 2, money.Money{})
+	diffC  = *NewEmployee("c", euros("7000"), 1, money.Money{})
+	// diffA with another salary and sales
+	diffAx = *NewEmployee("a", euros("5500"), 6, money.Money{})
+)
+
+type diffTest struct {
+	name        string
+	old, new    []Employee
+	ignoreOrder bool
+	expected    string
+}
+
+var diffTests = []diffTest{
+	{"equal", []Employee{diffA, diffB}, []Employee{diffA, diffB}, false, ""},
+	{"zero bonus", []Employee{diffA}, []Employee{diffA2}, false, ""},
+	{"both empty", nil, []Employee{}, false, ""},
+	{"order", []Employee{diffA, diffB}, []Employee{diffB, diffA}, false,
+		"~ [0] a: name a -> b, salary 5000.00 EUR -> 6000.00 EUR, sales 5 -> 2\n" +
+			"~ [1] b: name b -> a, salary 6000.00 EUR -> 5000.00 EUR, sales 2 -> 5\n"},
+	{"order ignored", []Employee{diffA, diffB}, []Employee{diffB, diffA}, true, ""},
+	{"duplicates", []Employee{diffA, diffA}, []Employee{diffA, diffB}, true,
+		"- a salary 5000.00 EUR sales 5 bonus 0\n+ b salary 6000.00 EUR sales 2 bonus 0\n"},
+	{"same names", []Employee{diffA, diffB}, []Employee{diffB, diffAx}, true,
+		"~ a: salary 5000.00 EUR -> 5500.00 EUR, sales 5 -> 6\n"},
+	{"added", []Employee{diffA}, []Employee{diffA, diffC}, false,
+		"+ c salary 7000.00 EUR sales 1 bonus 0\n"},
+	{"removed", []Employee{diffA, diffB, diffC}, []Employee{diffC, diffA}, true,
+		"- b salary 6000.00 EUR sales 2 bonus 0\n"},
+	{"changed in place", []Employee{diffA, diffB}, []Employee{diffAx, diffB}, false,
+		"~ [0] a: salary 5000.00 EUR -> 5500.00 EUR, sales 5 -> 6\n"},
+}
+
+func TestDiff(t *testing.T) {
+	for _, test := range diffTests {
+		var opts []DiffOption
+		if test.ignoreOrder {
+			opts = append(opts, IgnoreOrder())
+		}
+		d := Diff(test.old, test.new, opts...)
+		if got := d.String(); got != test.expected {
+			t.Errorf("%s: got\n%swanted\n%s", test.name, got, test.expected)
+		}
+		if d.Empty() != (test.expected == "") {
+			t.Errorf("%s: Empty got %v", test.name, d.Empty())
+		}
+	}
+}
+
+func TestEquals(t *testing.T) {
+	tests := []struct {
+		xs, ys   []Employee
+		expected bool
+	}{
+		{[]Employee{diffA, diffB}, []Employee{diffB, diffA}, true},
+		{[]Employee{diffA, diffA}, []Employee{diffA, diffB}, false},
+		{[]Employee{diffA}, []Employee{diffAx}, false},
+		{[]Employee{diffA}, []Employee{diffA, diffA}, false},
+	}
+	for _, test := range tests {
+		if got := Equals(test.xs, test.ys); got != test.expected {
+			t.Errorf("Equals(%v, %v) got %v, wanted %v", test.xs, test.ys, got, test.expected)
+		}
+	}
+
+	pa, pb := &diffA, &diffB
+	if !EqualsPointers([]*Employee{pa, pb}, []*Employee{pb, pa}) {
+		t.Errorf("EqualsPointers of the same pointers got false")
+	}
+	other := diffAx
+	if d := DiffPointers([]*Employee{pa}, []*Employee{&other}); len(d.Changed) != 1 {
+		t.Errorf("DiffPointers got\n%swanted one change", d)
+	}
+}
@@ -1,18 +1,24 @@
 package lang
 
+import (
+	"github.com/vrnvu/go-examples/lang/collections"
+	"github.com/vrnvu/go-examples/lang/money"
+)
+
+// Employee salary and bonus are exact amounts, see the money package
 type Employee struct {
 	name   string
-	salary int
+	salary money.Money
 	sales  int
-	bonus  int
+	bonus  money.Money
 }
 
-func NewEmployee(name string, salary, sales, bonus int) *Employee {
+func NewEmployee(name string, salary money.Money, sales int, bonus money.Money) *Employee {
 	var e Employee
 	return newEmployee(&e, name, salary, sales, bonus)
 }
 
-func newEmployee(e *Employee, name string, salary, sales, bonus int) *Employee {
+func newEmployee(e *Employee, name string, salary money.Money, sales int, bonus money.Money) *Employee {
 	e.name = name
 	e.salary = salary
 	e.sales = sales
@@ -20,80 +26,61 @@ func newEmployee(e *Employee, name string, salary, sales, bonus int) *Employee {
 	return e
 }
 
-const BONUS_PERCENTAGE = 10
-
-func getBonusPercentage(salary int) int {
-	return (salary * BONUS_PERCENTAGE) / 100
+// Name returns the name of the employee
+func (e Employee) Name() string {
+	return e.name
 }
 
-func FindEmployeeBonus(salary, numberOfSales int) int {
-	bonusPercentage := getBonusPercentage(salary)
-	bonus := bonusPercentage * numberOfSales
-	return bonus
+// Salary returns the salary of the employee
+func (e Employee) Salary() money.Money {
+	return e.salary
 }
 
-func Filter(employees []Employee, filter func(Employee) bool) []Employee {
-	result := make([]Employee, 0)
-	for _, e := range employees {
-		if filter(e) {
-			result = append(result, e)
-		}
-	}
-	return result
+// Sales returns the number of sales of the employee
+func (e Employee) Sales() int {
+	return e.sales
 }
 
-// Return true if both slices contain the same employees
-func Equals(xs, ys []Employee) bool {
+// Bonus returns the bonus computed by the last payroll run
+func (e Employee) Bonus() money.Money {
+	return e.bonus
+}
 
-	if len(xs) != len(ys) {
-		return false
-	}
+// TotalPay returns the salary plus the bonus
+func (e Employee) TotalPay() (money.Money, error) {
+	return e.salary.Add(e.bonus)
+}
 
-	m := make(map[string]bool)
+const BONUS_PERCENTAGE = 10
 
-	for _, x := range xs {
-		m[x.name] = true
-	}
+// getBonusPercentage keeps the cents, 10% of 864.00 is 86.40
+func getBonusPercentage(salary money.Money) (money.Money, error) {
+	return salary.Percent(BONUS_PERCENTAGE, money.HalfEven)
+}
 
-	for _, y := range ys {
-		if _, exists := m[y.name]; !exists {
-			return false
-		}
-	}
+// FindEmployeeBonus is the FlatPercentage policy with BONUS_PERCENTAGE
+func FindEmployeeBonus(salary money.Money, numberOfSales int) (money.Money, error) {
+	e := Employee{salary: salary, sales: numberOfSales}
+	return FlatPercentage{Percent: BONUS_PERCENTAGE}.Bonus(e)
+}
 
-	return true
+func Filter(employees []Employee, filter func(Employee) bool) []Employee {
+	return collections.Filter(employees, filter)
+}
 
+// Return true if both slices contain the same employees, in any order
+// Every field is compared and duplicates count, see Diff
+func Equals(xs, ys []Employee) bool {
+	return Diff(xs, ys, IgnoreOrder()).Empty()
 }
 
 func FilterPointers(employees []*Employee, filter func(Employee) bool) []*Employee {
-	result := make([]*Employee, 0)
-	for _, pe := range employees {
-		e := *pe
-		if filter(e) {
-			result = append(result, pe)
-		}
-	}
-	return result
+	return collections.Filter(employees, func(pe *Employee) bool {
+		return filter(*pe)
+	})
 }
 
+// EqualsPointers is Equals of the employees pointed to
 func EqualsPointers(xs, ys []*Employee) bool {
-
-	if len(xs) != len(ys) {
-		return false
-	}
-
-	m := make(map[string]bool)
-
-	for _, x := range xs {
-		m[(*x).name] = true
-	}
-
-	for _, y := range ys {
-		if _, exists := m[(*y).name]; !exists {
-			return false
-		}
-	}
-
-	return true
-
+	return DiffPointers(xs, ys, IgnoreOrder()).Empty()
 }
@@ -3,6 +3,9 @@ package lang
 import (
 	"fmt"
 	"testing"
+
+	"github.com/vrnvu/go-examples/lang/money"
+	"github.com/vrnvu/go-examples/lang/sorting"
 )
 
 func assert(t *testing.T, got, want int) {
@@ -11,69 +14,100 @@ func assert(t *testing.T, got, want int) {
 	}
 }
 
+func assertMoney(t *testing.T, got money.Money, err error, want string) {
+	t.Helper()
+	if err != nil {
+		t.Errorf("got error %v, wanted %s", err, want)
+		return
+	}
+	if got.String() != want {
+		t.Errorf("got %s, wanted %s", got, want)
+	}
+}
+
+// euros parses an amount in EUR, "864.50"
+func euros(amount string) money.Money {
+	return money.MustParse(amount, money.EUR)
+}
+
 type findEmployeeTest struct {
-	arg1, arg2, arg3, expected int
+	salary   string
+	sales    int
+	expected string
 }
 
 type getBonusPercentageTest struct {
-	arg1, expected int
+	salary, expected string
 }
 
 var findEmployeeTests = []findEmployeeTest{
-	findEmployeeTest{5000, 5, 0, 2500},
-	findEmployeeTest{8500, 3, 0, 2550},
+	findEmployeeTest{"5000", 5, "2500.00 EUR"},
+	findEmployeeTest{"8500", 3, "2550.00 EUR"},
+	findEmployeeTest{"864", 3, "259.20 EUR"},
 }
 
+// The cents are kept, integer division used to turn 864 into 86
 var getBonusPercentageTests = []getBonusPercentageTest{
-	getBonusPercentageTest{100, 10},
-	getBonusPercentageTest{864, 86},
+	getBonusPercentageTest{"100", "10.00 EUR"},
+	getBonusPercentageTest{"864", "86.40 EUR"},
+	getBonusPercentageTest{"864.25", "86.42 EUR"},
+	getBonusPercentageTest{"864.35", "86.44 EUR"},
 }
 
 func TestFindEmpoyeeBonus(t *testing.T) {
 	for _, test := range findEmployeeTests {
-		e := NewEmployee("Employee", test.arg1, test.arg2, test.arg3)
-		got := FindEmployeeBonus(e.salary, e.sales)
-		want := test.expected
-		assert(t, got, want)
+		e := NewEmployee("Employee", euros(test.salary), test.sales, money.Money{})
+		got, err := FindEmployeeBonus(e.salary, e.sales)
+		assertMoney(t, got, err, test.expected)
 	}
 }
 
 func TestGetBonusPercentage(t *testing.T) {
 	for _, test := range getBonusPercentageTests {
-		got := getBonusPercentage(test.arg1)
-		want := test.expected
-		assert(t, got, want)
+		got, err := getBonusPercentage(euros(test.salary))
+		assertMoney(t, got, err, test.expected)
 	}
 }
 
 func TestFilter(t *testing.T) {
 
-	e0 := *NewEmployee("e0", 5000, 5, 0)
-	e1 := *NewEmployee("e1", 6000, 5, 0)
-	e2 := *NewEmployee("e2", 7000, 5, 0)
+	e0 := *NewEmployee("e0", euros("5000"), 5, money.Money{})
+	e1 := *NewEmployee("e1", euros("6000"), 5, money.Money{})
+	e2 := *NewEmployee("e2", euros("7000"), 5, money.Money{})
 	employees := []Employee{e1, e0, e2}
 	got := Filter(employees, func(e Employee) bool {
-		return e.salary > 5500
+		return e.salary.Cmp(euros("5500")) > 0
 	})
 	want := []Employee{e2, e1}
-	// want := []Employee{e2, e0}
-	if !Equals(want, got) {
-		t.Errorf("got %#v, wanted %#v", got, want)
+	if d := Diff(want, got, IgnoreOrder()); !d.Empty() {
+		t.Errorf("Filter wanted - got +\n%s", d)
 	}
 }
 
 func TestFilterPointers(t *testing.T) {
 	// Playing around with pointers in slices
-	pe0 := NewEmployee("pe0", 5000, 5, 0)
-	pe1 := NewEmployee("pe1", 6000, 5, 0)
-	pe2 := NewEmployee("pe2", 7000, 5, 0)
+	pe0 := NewEmployee("pe0", euros("5000"), 5, money.Money{})
+	pe1 := NewEmployee("pe1", euros("6000"), 5, money.Money{})
+	pe2 := NewEmployee("pe2", euros("7000"), 5, money.Money{})
 	pemployees := []*Employee{pe1, pe0, pe2}
 	fmt.Println(pemployees)
 	got := FilterPointers(pemployees, func(e Employee) bool {
-		return e.salary > 5500
+		return e.salary.Cmp(euros("5500")) > 0
 	})
 	want := []*Employee{pe2, pe1}
-	if !EqualsPointers(want, got) {
-		t.Errorf("got %#v, wanted %#v", got, want)
+	if d := DiffPointers(want, got, IgnoreOrder()); !d.Empty() {
+		t.Errorf("FilterPointers wanted - got +\n%s", d)
+	}
+}
+
+func TestSortEmployees(t *testing.T) {
+	employees := []Employee{
+		*NewEmployee("eve", euros("5000"), 1, money.Money{}),
+		*NewEmployee("bob", euros("7000"), 2, money.Money{}),
+		*NewEmployee("ann", euros("5000"), 3, money.Money{}),
+	}
+	sorting.Sort(employees, sorting.ByCmp(Employee.Salary, money.Money.Cmp).Reversed().ThenBy(sorting.By(Employee.Name)))
+	if got := names(employees); got != "bob,ann,eve" {
+		t.Errorf("got %s, wanted bob,ann,eve", got)
 	}
 }
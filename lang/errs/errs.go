@@ -0,0 +1,299 @@
+// Package errs is the grown up version of argError in structs.go
+//
+// An *Error adds to a cause what callers need without a type assertion:
+// a Kind to branch on with errors.Is(err, errs.Invalid), key value Fields
+// for logs and the stack where it was created, printed with %+v. It wraps
+// its cause so errors.Is and errors.As see through it, and List gathers
+// many errors into one
+package errs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+)
+
+// Kind is the class of an error, it is an error itself so
+// errors.Is(err, errs.NotFound) tells whether err has that kind
+type Kind uint8
+
+const (
+	// Other is the zero Kind, wrapping with it keeps the kind of the cause
+	Other Kind = iota
+	// Invalid is bad input, a malformed row or a value out of range
+	Invalid
+	NotFound
+	Duplicate
+	// Unavailable is a closed or stopped resource
+	Unavailable
+	Canceled
+	Timeout
+	// Internal is a broken invariant, a bug rather than bad input
+	Internal
+)
+
+var kindNames = [...]string{"other", "invalid", "not found", "duplicate", "unavailable", "canceled", "timeout", "internal"}
+
+func (k Kind) String() string {
+	if int(k) < len(kindNames) {
+		return kindNames[k]
+	}
+	return fmt.Sprintf("kind(%d)", k)
+}
+
+func (k Kind) Error() string {
+	return k.String()
+}
+
+// Field is a key value pair attached to an error
+type Field struct {
+	Key   string
+	Value any
+}
+
+// Error is an error with a kind, fields and a stack
+// Msg and Err are both optional, Error prints "Msg: Err"
+type Error struct {
+	Kind   Kind
+	Msg    string
+	Err    error
+	Fields []Field
+	stack  []uintptr
+}
+
+func (e *Error) Error() string {
+	switch {
+	case e.Err == nil && e.Msg == "":
+		return e.Kind.String()
+	case e.Err == nil:
+		return e.Msg
+	case e.Msg == "":
+		return e.Err.Error()
+	}
+	return e.Msg + ": " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is makes errors.Is(err, kind) true for the kind of e
+func (e *Error) Is(target error) bool {
+	k, ok := target.(Kind)
+	return ok && k != Other && k == e.Kind
+}
+
+// Stack returns the frames where e was created, nil when e wraps an
+// *Error that already has one
+func (e *Error) Stack() []runtime.Frame {
+	if len(e.stack) == 0 {
+		return nil
+	}
+	var stack []runtime.Frame
+	frames := runtime.CallersFrames(e.stack)
+	for {
+		frame, more := frames.Next()
+		stack = append(stack, frame)
+		if !more {
+			return stack
+		}
+	}
+}
+
+// Format prints Error with %v and %s, %+v adds the fields of the whole
+// chain and the stack
+func (e *Error) Format(f fmt.State, verb rune) {
+	switch {
+	case verb == 'v' && f.Flag('+'):
+		io.WriteString(f, e.Error())
+		for _, field := range Fields(e) {
+			fmt.Fprintf(f, "\n    %s=%v", field.Key, field.Value)
+		}
+		for _, frame := range Stack(e) {
+			fmt.Fprintf(f, "\n%s\n\t%s:%d", frame.Function, frame.File, frame.Line)
+		}
+	case verb == 'q':
+		fmt.Fprintf(f, "%q", e.Error())
+	default:
+		io.WriteString(f, e.Error())
+	}
+}
+
+// New returns an error of kind with a message and fields, kv holds pairs
+// of a string key and any value
+func New(kind Kind, msg string, kv ...any) error {
+	return &Error{Kind: kind, Msg: msg, Fields: fields(kv), stack: callers()}
+}
+
+// Wrap adds a kind, an optional message and fields to err, it returns nil
+// when err is nil. The stack is only captured when err does not carry one
+func Wrap(err error, kind Kind, msg string, kv ...any) error {
+	if err == nil {
+		return nil
+	}
+	e := &Error{Kind: kind, Msg: msg, Err: err, Fields: fields(kv)}
+	if !hasStack(err) {
+		e.stack = callers()
+	}
+	return e
+}
+
+// hasStack reports whether an *Error of the chain captured a stack,
+// without resolving its frames like Stack does
+func hasStack(err error) bool {
+	for ; err != nil; err = errors.Unwrap(err) {
+		if e, ok := err.(*Error); ok && len(e.stack) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// callers skips runtime.Callers, callers and New or Wrap
+func callers() []uintptr {
+	pcs := make([]uintptr, 32)
+	return pcs[:runtime.Callers(3, pcs)]
+}
+
+// fields pairs up kv like log/slog, a key that is not a string or that has
+// no value is reported under the key "!BADKEY"
+func fields(kv []any) []Field {
+	if len(kv) == 0 {
+		return nil
+	}
+	fs := make([]Field, 0, (len(kv)+1)/2)
+	for len(kv) > 0 {
+		key, ok := kv[0].(string)
+		if !ok || len(kv) == 1 {
+			fs = append(fs, Field{"!BADKEY", kv[0]})
+			kv = kv[1:]
+			continue
+		}
+		fs = append(fs, Field{key, kv[1]})
+		kv = kv[2:]
+	}
+	return fs
+}
+
+// KindOf returns the first kind other than Other in the chain of err
+// Context errors without a kind are Canceled and Timeout
+func KindOf(err error) Kind {
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		if x, ok := e.(*Error); ok && x.Kind != Other {
+			return x.Kind
+		}
+		if k, ok := e.(Kind); ok {
+			return k
+		}
+	}
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return Timeout
+	case errors.Is(err, context.Canceled):
+		return Canceled
+	}
+	return Other
+}
+
+// Fields returns the fields of every *Error in the chain of err, the
+// outermost first
+func Fields(err error) []Field {
+	var fs []Field
+	for err != nil {
+		if e, ok := err.(*Error); ok {
+			fs = append(fs, e.Fields...)
+		}
+		err = errors.Unwrap(err)
+	}
+	return fs
+}
+
+// FieldOf returns the value of the first field called key in the chain of err
+func FieldOf(err error, key string) (any, bool) {
+	for _, f := range Fields(err) {
+		if f.Key == key {
+			return f.Value, true
+		}
+	}
+	return nil, false
+}
+
+// Stack returns the stack of the innermost *Error of err that has one
+func Stack(err error) []runtime.Frame {
+	var stack []runtime.Frame
+	for err != nil {
+		if e, ok := err.(*Error); ok && len(e.stack) > 0 {
+			stack = e.Stack()
+		}
+		err = errors.Unwrap(err)
+	}
+	return stack
+}
+
+// List gathers errors, its zero value is empty and ready to use
+//
+//	var l errs.List
+//	for ... { l.Add(check(x)) }
+//	return l.Err()
+type List struct {
+	errs []error
+}
+
+// Add appends err unless it is nil
+func (l *List) Add(err error) {
+	if err != nil {
+		l.errs = append(l.errs, err)
+	}
+}
+
+// Len is the number of errors added
+func (l *List) Len() int {
+	return len(l.errs)
+}
+
+// Errors returns the errors added so far
+func (l *List) Errors() []error {
+	return l.errs
+}
+
+// Err returns nil when no error was added, the error itself when there is
+// one and a *Multi otherwise
+func (l *List) Err() error {
+	switch len(l.errs) {
+	case 0:
+		return nil
+	case 1:
+		return l.errs[0]
+	}
+	return &Multi{Errs: append([]error(nil), l.errs...)}
+}
+
+// Multi is many errors, printed one per line like errors.Join
+// errors.Is and errors.As look into every one of them
+type Multi struct {
+	Errs []error
+}
+
+func (m *Multi) Error() string {
+	msgs := make([]string, len(m.Errs))
+	for i, err := range m.Errs {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+func (m *Multi) Unwrap() []error {
+	return m.Errs
+}
+
+// Kinds counts the errors of m by kind
+func (m *Multi) Kinds() map[Kind]int {
+	kinds := make(map[Kind]int)
+	for _, err := range m.Errs {
+		kinds[KindOf(err)]++
+	}
+	return kinds
+}
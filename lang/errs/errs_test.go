@@ -0,0 +1,183 @@
+package errs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type argError struct {
+	arg int
+}
+
+func (e *argError) Error() string {
+	return fmt.Sprintf("bad arg %d", e.arg)
+}
+
+func TestErrorMessage(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{New(Invalid, "value was 42"), "value was 42"},
+		{New(NotFound, ""), "not found"},
+		{Wrap(io.EOF, Other, ""), "EOF"},
+		{Wrap(io.EOF, Invalid, "reading"), "reading: EOF"},
+		{Wrap(Wrap(io.EOF, Invalid, "reading"), Other, "loading"), "loading: reading: EOF"},
+		{Kind(42), "kind(42)"},
+	}
+	for _, test := range tests {
+		if got := test.err.Error(); got != test.want {
+			t.Errorf("got %q, wanted %q", got, test.want)
+		}
+	}
+	if Wrap(nil, Invalid, "nothing") != nil {
+		t.Errorf("Wrap of nil is not nil")
+	}
+}
+
+func TestKinds(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	// is tells whether errors.Is(err, want) holds, context errors have a
+	// kind for KindOf only
+	tests := []struct {
+		name string
+		err  error
+		want Kind
+		is   bool
+	}{
+		{"new", New(Invalid, "x"), Invalid, true},
+		{"wrap keeps the kind", Wrap(New(NotFound, "x"), Other, "y"), NotFound, true},
+		{"outermost kind", Wrap(New(NotFound, "x"), Internal, "y"), Internal, true},
+		{"through fmt", fmt.Errorf("y: %w", New(Duplicate, "x")), Duplicate, true},
+		{"plain", io.EOF, Other, false},
+		{"nil", nil, Other, false},
+		{"canceled", ctx.Err(), Canceled, false},
+		{"timeout", fmt.Errorf("y: %w", context.DeadlineExceeded), Timeout, false},
+		{"canceled wrapped", Wrap(ctx.Err(), Other, ""), Canceled, false},
+	}
+	for _, test := range tests {
+		if got := KindOf(test.err); got != test.want {
+			t.Errorf("%s: KindOf got %v, wanted %v", test.name, got, test.want)
+		}
+		if got := errors.Is(test.err, test.want); got != test.is {
+			t.Errorf("%s: errors.Is %v got %v", test.name, test.want, got)
+		}
+	}
+	if errors.Is(New(Invalid, "x"), NotFound) || errors.Is(io.EOF, Other) {
+		t.Errorf("errors.Is matched another kind")
+	}
+}
+
+func TestWrapIsAs(t *testing.T) {
+	cause := &argError{42}
+	err := fmt.Errorf("calling: %w", Wrap(cause, Invalid, "f2", "arg", 42))
+	var ae *argError
+	if !errors.As(err, &ae) || ae.arg != 42 {
+		t.Errorf("errors.As got %v", ae)
+	}
+	var e *Error
+	if !errors.As(err, &e) || e.Kind != Invalid || e.Err != cause {
+		t.Errorf("errors.As *Error got %+v", e)
+	}
+	if !errors.Is(Wrap(io.EOF, Invalid, ""), io.EOF) {
+		t.Errorf("errors.Is does not see the cause")
+	}
+}
+
+func TestFields(t *testing.T) {
+	inner := New(Invalid, "bad", "line", 3, "field", "age")
+	err := Wrap(fmt.Errorf("load: %w", inner), Other, "", "file", "a.csv", 7)
+	want := []Field{{"file", "a.csv"}, {"!BADKEY", 7}, {"line", 3}, {"field", "age"}}
+	if got := Fields(err); !reflect.DeepEqual(got, want) {
+		t.Errorf("Fields got %v, wanted %v", got, want)
+	}
+	if v, ok := FieldOf(err, "line"); !ok || v != 3 {
+		t.Errorf("FieldOf line got %v %v", v, ok)
+	}
+	if _, ok := FieldOf(err, "column"); ok {
+		t.Errorf("FieldOf found a missing field")
+	}
+	if Fields(io.EOF) != nil {
+		t.Errorf("Fields of a plain error is not nil")
+	}
+}
+
+func newHere() error {
+	return New(Internal, "here")
+}
+
+func TestStack(t *testing.T) {
+	err := Wrap(newHere(), Other, "wrapped")
+	stack := Stack(err)
+	if len(stack) == 0 || !strings.HasSuffix(stack[0].Function, ".newHere") {
+		t.Fatalf("stack got %v, wanted it to start in newHere", stack)
+	}
+	// the wrapper reuses the stack of the cause
+	var e *Error
+	errors.As(err, &e)
+	if e.Stack() != nil {
+		t.Errorf("the wrapper captured its own stack")
+	}
+	if Stack(Wrap(io.EOF, Other, "")) == nil {
+		t.Errorf("Wrap of a plain error has no stack")
+	}
+
+	verbose := fmt.Sprintf("%+v", Wrap(New(Invalid, "bad", "line", 3), Other, "load", "file", "a.csv"))
+	for _, want := range []string{"load: bad", "file=a.csv", "line=3", "errs.TestStack", "errs_test.go:"} {
+		if !strings.Contains(verbose, want) {
+			t.Errorf("%%+v got %q, wanted it to contain %q", verbose, want)
+		}
+	}
+	if got := fmt.Sprintf("%v", newHere()); got != "here" {
+		t.Errorf("%%v got %q", got)
+	}
+}
+
+// BenchmarkWrapDeep wraps an error that already carries a stack, Wrap
+// only checks that it is there
+func BenchmarkWrapDeep(b *testing.B) {
+	err := newHere()
+	for range 20 {
+		err = Wrap(err, Other, "layer")
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Wrap(err, Other, "top")
+	}
+}
+
+func TestList(t *testing.T) {
+	var l List
+	if l.Err() != nil {
+		t.Errorf("empty List has an error")
+	}
+	l.Add(nil)
+	l.Add(New(Invalid, "a"))
+	if l.Len() != 1 || l.Err().Error() != "a" {
+		t.Errorf("one error got %v", l.Err())
+	}
+	l.Add(Wrap(io.EOF, Invalid, "b"))
+	l.Add(New(Duplicate, "c"))
+	err := l.Err()
+	if err.Error() != "a\nb: EOF\nc" {
+		t.Errorf("got %q", err)
+	}
+	if !errors.Is(err, io.EOF) || !errors.Is(err, Duplicate) {
+		t.Errorf("errors.Is does not look into every error")
+	}
+	var m *Multi
+	if !errors.As(err, &m) || !reflect.DeepEqual(m.Kinds(), map[Kind]int{Invalid: 2, Duplicate: 1}) {
+		t.Errorf("Kinds got %v", m.Kinds())
+	}
+	// later adds do not change an error already returned
+	l.Add(New(Invalid, "d"))
+	if len(m.Errs) != 3 || len(l.Errors()) != 4 {
+		t.Errorf("Err shares its errors with the List")
+	}
+}
@@ -0,0 +1,51 @@
+package lang
+
+import (
+	"fmt"
+
+	"github.com/vrnvu/go-examples/registry"
+)
+
+const category = "lang"
+
+// Every example of the package registers itself so main can run it by name
+func init() {
+	for _, e := range []registry.Example{
+		{Name: "ForIter", Description: "for loop calling Hello", Run: ForIter},
+		{Name: "IfElseAndSwitch", Description: "if and switch with an init statement", Run: IfElseAndSwitch},
+		{Name: "Arrays", Description: "fixed size and two dimensional arrays", Run: Arrays},
+		{Name: "Slices", Description: "make, append, copy and slicing", Run: Slices},
+		{Name: "Maps", Description: "map set, get, delete and presence check", Run: Maps},
+		{Name: "Ranges", Description: "range over slices and maps", Run: Ranges},
+		{Name: "Functions", Description: "functions with multiple return values", Run: Functions},
+		{Name: "VariadicFunctions", Description: "variadic arguments and slice expansion", Run: func() {
+			VariadicFunctions(1, 2)
+			VariadicFunctions(1, 2, 3)
+			VariadicFunctions([]int{1, 2, 3, 4}...)
+		}},
+		{Name: "Closures", Description: "a closure keeping its own counter", Run: Closures},
+		{Name: "Recursion", Description: "recursive factorial of 7", Run: func() {
+			fmt.Println(RecursionFact(7))
+		}},
+		{Name: "Pointers", Description: "pass by value against pass by pointer", Run: Pointers},
+		{Name: "Structs", Description: "struct literals and constructors", Run: Structs},
+		{Name: "Methods", Description: "value and pointer receivers", Run: Methods},
+		{Name: "Interfaces", Description: "the geometry interface", Run: Interfaces},
+		{Name: "SpatialIndex", Description: "point, range and nearest queries over an R-tree of shapes", Run: SpatialIndex},
+		{Name: "Errors", Description: "errors.New and a custom error type", Run: Errors},
+		{Name: "Roster", Description: "load a roster file, pay it and save it as csv or json, -h for flags", Main: RosterMain},
+		{Name: "Query", Description: "filter a roster with a query like 'salary > 5500 && sales >= 3'", Main: QueryMain},
+		{Name: "PayrollRun", Description: "bonus policies applied by a payroll run", Run: PayrollRun},
+		{Name: "Sorting", Description: "sort strings and ints", Run: Sorting},
+		{Name: "SortingBy", Description: "sort with a custom sort.Interface", Run: SortingBy},
+		{Name: "Panic", Description: "an unrecovered panic", Run: Panic},
+		{Name: "CollectionFunctions", Description: "collection helpers", Run: CollectionFunctions},
+		{Name: "Streams", Description: "lazy stream over the roster lines with a parallel stage", Run: Streams},
+		{Name: "StringFunctions", Description: "helpers of the strings package", Run: StringFunctions},
+		{Name: "StringFormatting", Description: "fmt verbs", Run: StringFormatting},
+		{Name: "RegularExpressions", Description: "the regexp package", Run: RegularExpressions},
+	} {
+		e.Category = category
+		registry.Register(e)
+	}
+}
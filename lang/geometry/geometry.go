@@ -0,0 +1,301 @@
+// Package geometry is the complete version of the geometry interface of
+// lang.Interfaces: shapes on the plane with their area, perimeter,
+// bounding box and a containment test
+//
+// Points on the border of a shape are contained by it
+package geometry
+
+import (
+	"fmt"
+	"math"
+)
+
+// Shape is a closed figure on the plane
+type Shape interface {
+	Area() float64
+	Perimeter() float64
+	// Bounds is the smallest axis aligned rectangle containing the shape
+	Bounds() Rect
+	Contains(p Point) bool
+}
+
+// Point is a position on the plane
+type Point struct {
+	X, Y float64
+}
+
+// Pt is a shorthand for Point{x, y}
+func Pt(x, y float64) Point {
+	return Point{x, y}
+}
+
+func (p Point) Add(q Point) Point {
+	return Point{p.X + q.X, p.Y + q.Y}
+}
+
+func (p Point) Sub(q Point) Point {
+	return Point{p.X - q.X, p.Y - q.Y}
+}
+
+// Dist is the euclidean distance between p and q
+func (p Point) Dist(q Point) float64 {
+	return math.Hypot(p.X-q.X, p.Y-q.Y)
+}
+
+func (p Point) String() string {
+	return fmt.Sprintf("(%g,%g)", p.X, p.Y)
+}
+
+// cross is the z of the cross product of p and q, positive when q turns
+// counter clockwise from p
+func cross(p, q Point) float64 {
+	return p.X*q.Y - p.Y*q.X
+}
+
+// Rect is an axis aligned rectangle from Min to Max, it is also the
+// bounding box of every shape
+type Rect struct {
+	Min, Max Point
+}
+
+// R builds the rectangle of the corners (x0, y0) and (x1, y1) in any order
+func R(x0, y0, x1, y1 float64) Rect {
+	return Rect{Point{math.Min(x0, x1), math.Min(y0, y1)}, Point{math.Max(x0, x1), math.Max(y0, y1)}}
+}
+
+// Dx is the width
+func (r Rect) Dx() float64 {
+	return r.Max.X - r.Min.X
+}
+
+// Dy is the height
+func (r Rect) Dy() float64 {
+	return r.Max.Y - r.Min.Y
+}
+
+func (r Rect) Area() float64 {
+	return r.Dx() * r.Dy()
+}
+
+func (r Rect) Perimeter() float64 {
+	return 2 * (r.Dx() + r.Dy())
+}
+
+func (r Rect) Bounds() Rect {
+	return r
+}
+
+func (r Rect) Contains(p Point) bool {
+	return r.Min.X <= p.X && p.X <= r.Max.X && r.Min.Y <= p.Y && p.Y <= r.Max.Y
+}
+
+// Center is the middle of the rectangle
+func (r Rect) Center() Point {
+	return Point{(r.Min.X + r.Max.X) / 2, (r.Min.Y + r.Max.Y) / 2}
+}
+
+// Intersects reports whether r and o share at least one point
+func (r Rect) Intersects(o Rect) bool {
+	return r.Min.X <= o.Max.X && o.Min.X <= r.Max.X && r.Min.Y <= o.Max.Y && o.Min.Y <= r.Max.Y
+}
+
+// ContainsRect reports whether o is inside r
+func (r Rect) ContainsRect(o Rect) bool {
+	return r.Contains(o.Min) && r.Contains(o.Max)
+}
+
+// Union is the smallest rectangle containing r and o
+func (r Rect) Union(o Rect) Rect {
+	return Rect{
+		Point{math.Min(r.Min.X, o.Min.X), math.Min(r.Min.Y, o.Min.Y)},
+		Point{math.Max(r.Max.X, o.Max.X), math.Max(r.Max.Y, o.Max.Y)},
+	}
+}
+
+// Dist is the distance from p to the closest point of r, 0 inside r
+func (r Rect) Dist(p Point) float64 {
+	dx := math.Max(0, math.Max(r.Min.X-p.X, p.X-r.Max.X))
+	dy := math.Max(0, math.Max(r.Min.Y-p.Y, p.Y-r.Max.Y))
+	return math.Hypot(dx, dy)
+}
+
+func (r Rect) String() string {
+	return fmt.Sprintf("rect%v-%v", r.Min, r.Max)
+}
+
+// Circle is the disc of Radius around Center
+type Circle struct {
+	Center Point
+	Radius float64
+}
+
+// Area is pi r², circlef64.area used to return the circumference instead
+func (c Circle) Area() float64 {
+	return math.Pi * c.Radius * c.Radius
+}
+
+func (c Circle) Perimeter() float64 {
+	return 2 * math.Pi * c.Radius
+}
+
+func (c Circle) Bounds() Rect {
+	r := Point{c.Radius, c.Radius}
+	return Rect{c.Center.Sub(r), c.Center.Add(r)}
+}
+
+func (c Circle) Contains(p Point) bool {
+	return c.Center.Dist(p) <= c.Radius
+}
+
+func (c Circle) String() string {
+	return fmt.Sprintf("circle%v r=%g", c.Center, c.Radius)
+}
+
+// Triangle has three vertices in any order
+type Triangle struct {
+	A, B, C Point
+}
+
+func (t Triangle) Area() float64 {
+	return math.Abs(cross(t.B.Sub(t.A), t.C.Sub(t.A))) / 2
+}
+
+func (t Triangle) Perimeter() float64 {
+	return t.A.Dist(t.B) + t.B.Dist(t.C) + t.C.Dist(t.A)
+}
+
+func (t Triangle) Bounds() Rect {
+	return boundsOf([]Point{t.A, t.B, t.C})
+}
+
+// Contains checks p is on the same side of the three edges. A triangle
+// with no area is a segment, or a point, and p has to be on one of its edges
+func (t Triangle) Contains(p Point) bool {
+	if cross(t.B.Sub(t.A), t.C.Sub(t.A)) == 0 {
+		return onSegment(t.A, t.B, p) || onSegment(t.B, t.C, p) || onSegment(t.C, t.A, p)
+	}
+	d1 := cross(t.B.Sub(t.A), p.Sub(t.A))
+	d2 := cross(t.C.Sub(t.B), p.Sub(t.B))
+	d3 := cross(t.A.Sub(t.C), p.Sub(t.C))
+	hasNeg := d1 < 0 || d2 < 0 || d3 < 0
+	hasPos := d1 > 0 || d2 > 0 || d3 > 0
+	return !(hasNeg && hasPos)
+}
+
+func (t Triangle) String() string {
+	return fmt.Sprintf("triangle%v%v%v", t.A, t.B, t.C)
+}
+
+// Polygon is a simple polygon, its edges do not cross, given by its
+// vertices in order, clockwise or not. The last vertex joins the first one
+type Polygon struct {
+	Points []Point
+}
+
+// Poly is a shorthand for Polygon{points}
+func Poly(points ...Point) Polygon {
+	return Polygon{points}
+}
+
+// Area uses the shoelace formula
+func (p Polygon) Area() float64 {
+	sum := 0.0
+	for i, a := range p.Points {
+		sum += cross(a, p.Points[(i+1)%len(p.Points)])
+	}
+	return math.Abs(sum) / 2
+}
+
+func (p Polygon) Perimeter() float64 {
+	sum := 0.0
+	for i, a := range p.Points {
+		sum += a.Dist(p.Points[(i+1)%len(p.Points)])
+	}
+	return sum
+}
+
+func (p Polygon) Bounds() Rect {
+	return boundsOf(p.Points)
+}
+
+// Contains casts a ray to the right of q and counts the edges it crosses,
+// odd means inside. Points on an edge are checked first
+func (p Polygon) Contains(q Point) bool {
+	inside := false
+	for i, a := range p.Points {
+		b := p.Points[(i+1)%len(p.Points)]
+		if onSegment(a, b, q) {
+			return true
+		}
+		if (a.Y > q.Y) != (b.Y > q.Y) {
+			x := a.X + (q.Y-a.Y)*(b.X-a.X)/(b.Y-a.Y)
+			if q.X < x {
+				inside = !inside
+			}
+		}
+	}
+	return inside
+}
+
+func (p Polygon) String() string {
+	return fmt.Sprintf("polygon%v", p.Points)
+}
+
+// onSegment reports whether q is on the segment from a to b
+func onSegment(a, b, q Point) bool {
+	const eps = 1e-9
+	if math.Abs(cross(b.Sub(a), q.Sub(a))) > eps*math.Max(1, a.Dist(b)) {
+		return false
+	}
+	return R(a.X, a.Y, b.X, b.Y).Contains(q)
+}
+
+func boundsOf(points []Point) Rect {
+	if len(points) == 0 {
+		return Rect{}
+	}
+	r := Rect{points[0], points[0]}
+	for _, p := range points[1:] {
+		r = r.Union(Rect{p, p})
+	}
+	return r
+}
+
+// Dist is the distance from p to the closest point of s, 0 when s contains p
+// Shapes of other packages are measured from their bounding box
+func Dist(s Shape, p Point) float64 {
+	if s.Contains(p) {
+		return 0
+	}
+	switch s := s.(type) {
+	case Rect:
+		return s.Dist(p)
+	case Circle:
+		return s.Center.Dist(p) - s.Radius
+	case Triangle:
+		return edgesDist([]Point{s.A, s.B, s.C}, p)
+	case Polygon:
+		return edgesDist(s.Points, p)
+	}
+	return s.Bounds().Dist(p)
+}
+
+// edgesDist is the distance from p to the closest edge of a polygon
+func edgesDist(points []Point, p Point) float64 {
+	d := math.Inf(1)
+	for i, a := range points {
+		d = math.Min(d, segmentDist(a, points[(i+1)%len(points)], p))
+	}
+	return d
+}
+
+// segmentDist projects p on the segment from a to b
+func segmentDist(a, b, p Point) float64 {
+	ab, ap := b.Sub(a), p.Sub(a)
+	length := ab.X*ab.X + ab.Y*ab.Y
+	if length == 0 {
+		return a.Dist(p)
+	}
+	t := math.Max(0, math.Min(1, (ap.X*ab.X+ap.Y*ab.Y)/length))
+	return a.Add(Point{ab.X * t, ab.Y * t}).Dist(p)
+}
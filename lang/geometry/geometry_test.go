@@ -0,0 +1,128 @@
+package geometry
+
+import (
+	"math"
+	"testing"
+)
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+type shapeTest struct {
+	name      string
+	shape     Shape
+	area      float64
+	perimeter float64
+	bounds    Rect
+}
+
+var square = Poly(Pt(0, 0), Pt(2, 0), Pt(2, 2), Pt(0, 2))
+
+// an L made of a 2x4 and a 2x2 block
+var ell = Poly(Pt(0, 0), Pt(4, 0), Pt(4, 2), Pt(2, 2), Pt(2, 4), Pt(0, 4))
+
+var shapeTests = []shapeTest{
+	{"rect", R(3, 4, 0, 0), 12, 14, Rect{Pt(0, 0), Pt(3, 4)}},
+	{"unit circle", Circle{Pt(0, 0), 1}, math.Pi, 2 * math.Pi, R(-1, -1, 1, 1)},
+	// circlef64{5} of Interfaces, its area is 25 pi and not 10 pi
+	{"circle", Circle{Pt(1, 2), 5}, 25 * math.Pi, 10 * math.Pi, R(-4, -3, 6, 7)},
+	{"right triangle", Triangle{Pt(0, 0), Pt(3, 0), Pt(0, 4)}, 6, 12, R(0, 0, 3, 4)},
+	{"clockwise triangle", Triangle{Pt(0, 0), Pt(0, 4), Pt(3, 0)}, 6, 12, R(0, 0, 3, 4)},
+	{"collinear triangle", Triangle{Pt(0, 0), Pt(2, 2), Pt(1, 1)}, 0, 4 * math.Sqrt2, R(0, 0, 2, 2)},
+	{"square", square, 4, 8, R(0, 0, 2, 2)},
+	{"clockwise square", Poly(Pt(0, 0), Pt(0, 2), Pt(2, 2), Pt(2, 0)), 4, 8, R(0, 0, 2, 2)},
+	{"ell", ell, 12, 16, R(0, 0, 4, 4)},
+	{"empty polygon", Polygon{}, 0, 0, Rect{}},
+}
+
+func TestShapes(t *testing.T) {
+	for _, test := range shapeTests {
+		if got := test.shape.Area(); !near(got, test.area) {
+			t.Errorf("%s: area got %g, wanted %g", test.name, got, test.area)
+		}
+		if got := test.shape.Perimeter(); !near(got, test.perimeter) {
+			t.Errorf("%s: perimeter got %g, wanted %g", test.name, got, test.perimeter)
+		}
+		if got := test.shape.Bounds(); got != test.bounds {
+			t.Errorf("%s: bounds got %v, wanted %v", test.name, got, test.bounds)
+		}
+	}
+}
+
+type containsTest struct {
+	name  string
+	shape Shape
+	in    []Point
+	out   []Point
+}
+
+var containsTests = []containsTest{
+	{"rect", R(0, 0, 3, 4), []Point{Pt(1, 1), Pt(0, 0), Pt(3, 2)}, []Point{Pt(-1, 1), Pt(3.1, 4)}},
+	{"circle", Circle{Pt(1, 1), 2}, []Point{Pt(1, 1), Pt(3, 1), Pt(2, 2)}, []Point{Pt(3, 3), Pt(-1.1, 1)}},
+	{"triangle", Triangle{Pt(0, 0), Pt(4, 0), Pt(0, 4)}, []Point{Pt(1, 1), Pt(2, 2), Pt(0, 3)}, []Point{Pt(3, 3), Pt(-1, 0)}},
+	// no area, only the points of the segment or the point
+	{"collinear triangle", Triangle{Pt(0, 0), Pt(2, 2), Pt(1, 1)}, []Point{Pt(0, 0), Pt(1.5, 1.5), Pt(2, 2)}, []Point{Pt(50, 50), Pt(-1, -1), Pt(1, 0)}},
+	{"point triangle", Triangle{Pt(1, 1), Pt(1, 1), Pt(1, 1)}, []Point{Pt(1, 1)}, []Point{Pt(100, 100), Pt(1, 1.1)}},
+	{"ell", ell, []Point{Pt(1, 3), Pt(3, 1), Pt(2, 2), Pt(4, 1), Pt(0, 0)}, []Point{Pt(3, 3), Pt(5, 1), Pt(2.5, 2.5)}},
+	// the ray of y=2 goes through the vertex (4, 2)
+	{"vertex ray", Poly(Pt(0, 0), Pt(4, 2), Pt(0, 4)), []Point{Pt(1, 2)}, []Point{Pt(-1, 2), Pt(5, 2)}},
+}
+
+func TestContains(t *testing.T) {
+	for _, test := range containsTests {
+		for _, p := range test.in {
+			if !test.shape.Contains(p) {
+				t.Errorf("%s: %v should be inside", test.name, p)
+			}
+		}
+		for _, p := range test.out {
+			if test.shape.Contains(p) {
+				t.Errorf("%s: %v should be outside", test.name, p)
+			}
+		}
+	}
+}
+
+func TestRect(t *testing.T) {
+	a, b, c := R(0, 0, 2, 2), R(1, 1, 3, 3), R(2.5, 0, 4, 1)
+	if !a.Intersects(b) || a.Intersects(c) || !b.Intersects(c) {
+		t.Errorf("Intersects of %v %v %v", a, b, c)
+	}
+	if got, want := a.Union(c), R(0, 0, 4, 2); got != want {
+		t.Errorf("Union got %v, wanted %v", got, want)
+	}
+	if !R(0, 0, 4, 4).ContainsRect(b) || a.ContainsRect(b) {
+		t.Errorf("ContainsRect of %v", b)
+	}
+	if got := a.Dist(Pt(5, 6)); !near(got, 5) {
+		t.Errorf("Dist got %g, wanted 5", got)
+	}
+	if got := a.Dist(Pt(1, 1)); got != 0 {
+		t.Errorf("Dist inside got %g, wanted 0", got)
+	}
+	if got := a.Center(); got != Pt(1, 1) {
+		t.Errorf("Center got %v", got)
+	}
+}
+
+func TestDist(t *testing.T) {
+	tests := []struct {
+		name  string
+		shape Shape
+		p     Point
+		want  float64
+	}{
+		{"inside", square, Pt(1, 1), 0},
+		{"rect corner", R(0, 0, 2, 2), Pt(5, 6), 5},
+		{"circle", Circle{Pt(0, 0), 1}, Pt(3, 4), 4},
+		{"triangle edge", Triangle{Pt(0, 0), Pt(4, 0), Pt(0, 4)}, Pt(3, 3), math.Sqrt2},
+		{"triangle vertex", Triangle{Pt(0, 0), Pt(4, 0), Pt(0, 4)}, Pt(-3, -4), 5},
+		{"ell notch", ell, Pt(3, 3), 1},
+	}
+	for _, test := range tests {
+		if got := Dist(test.shape, test.p); !near(got, test.want) {
+			t.Errorf("%s: got %g, wanted %g", test.name, got, test.want)
+		}
+	}
+}
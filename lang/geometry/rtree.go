@@ -0,0 +1,337 @@
+package geometry
+
+import (
+	"container/heap"
+	"math"
+)
+
+// Index is an R-tree over shapes identified by keys of type K
+//
+// Every node holds at most maxEntries bounding boxes, the boxes of its
+// children or of its shapes in the leaves. A query only descends into the
+// children whose box can hold an answer, instead of testing every shape
+// like measure would in a loop
+type Index[K comparable] struct {
+	root   *node[K]
+	shapes map[K]Shape
+}
+
+const (
+	maxEntries = 8
+	minEntries = 3
+)
+
+type entry[K comparable] struct {
+	bounds Rect
+	// child is set in inner nodes, id and shape in leaves
+	child *node[K]
+	id    K
+	shape Shape
+}
+
+type node[K comparable] struct {
+	leaf    bool
+	entries []entry[K]
+}
+
+func (n *node[K]) bounds() Rect {
+	r := n.entries[0].bounds
+	for _, e := range n.entries[1:] {
+		r = r.Union(e.bounds)
+	}
+	return r
+}
+
+// NewIndex returns an empty index
+func NewIndex[K comparable]() *Index[K] {
+	return &Index[K]{root: &node[K]{leaf: true}, shapes: make(map[K]Shape)}
+}
+
+// Len is the number of shapes
+func (ix *Index[K]) Len() int {
+	return len(ix.shapes)
+}
+
+// Insert adds s with key id, replacing the shape id had
+func (ix *Index[K]) Insert(id K, s Shape) {
+	if _, ok := ix.shapes[id]; ok {
+		ix.Delete(id)
+	}
+	ix.shapes[id] = s
+	ix.insert(entry[K]{bounds: s.Bounds(), id: id, shape: s})
+}
+
+func (ix *Index[K]) insert(e entry[K]) {
+	if sibling := insertAt(ix.root, e); sibling != nil {
+		old := ix.root
+		ix.root = &node[K]{entries: []entry[K]{
+			{bounds: old.bounds(), child: old},
+			{bounds: sibling.bounds(), child: sibling},
+		}}
+	}
+}
+
+// insertAt adds e to the subtree of n and returns the new sibling of n
+// when n had to be split
+func insertAt[K comparable](n *node[K], e entry[K]) *node[K] {
+	if n.leaf {
+		n.entries = append(n.entries, e)
+	} else {
+		i := chooseSubtree(n, e.bounds)
+		child := n.entries[i].child
+		sibling := insertAt(child, e)
+		n.entries[i].bounds = child.bounds()
+		if sibling != nil {
+			n.entries = append(n.entries, entry[K]{bounds: sibling.bounds(), child: sibling})
+		}
+	}
+	if len(n.entries) > maxEntries {
+		return split(n)
+	}
+	return nil
+}
+
+// chooseSubtree picks the child whose box grows the least to hold r,
+// the smallest one on ties
+func chooseSubtree[K comparable](n *node[K], r Rect) int {
+	best, bestGrowth, bestArea := 0, math.Inf(1), math.Inf(1)
+	for i, e := range n.entries {
+		area := e.bounds.Area()
+		growth := e.bounds.Union(r).Area() - area
+		if growth < bestGrowth || (growth == bestGrowth && area < bestArea) {
+			best, bestGrowth, bestArea = i, growth, area
+		}
+	}
+	return best
+}
+
+// split is Guttman's quadratic split, n keeps one group and the other
+// one is returned as a new node
+func split[K comparable](n *node[K]) *node[K] {
+	entries := n.entries
+	// the seeds are the pair wasting the most area when put together
+	s1, s2, worst := 0, 1, math.Inf(-1)
+	for i := range entries {
+		for j := i + 1; j < len(entries); j++ {
+			a, b := entries[i].bounds, entries[j].bounds
+			waste := a.Union(b).Area() - a.Area() - b.Area()
+			if waste > worst {
+				s1, s2, worst = i, j, waste
+			}
+		}
+	}
+
+	groups := [2][]entry[K]{{entries[s1]}, {entries[s2]}}
+	boxes := [2]Rect{entries[s1].bounds, entries[s2].bounds}
+	rest := make([]entry[K], 0, len(entries)-2)
+	for i, e := range entries {
+		if i != s1 && i != s2 {
+			rest = append(rest, e)
+		}
+	}
+	for len(rest) > 0 {
+		// a group that needs every remaining entry to reach minEntries gets them
+		for g := range groups {
+			if len(groups[g])+len(rest) == minEntries {
+				for _, e := range rest {
+					groups[g] = append(groups[g], e)
+					boxes[g] = boxes[g].Union(e.bounds)
+				}
+				rest = nil
+			}
+		}
+		if len(rest) == 0 {
+			break
+		}
+		// next is the entry with the strongest preference for one group
+		next, g, maxDiff := 0, 0, math.Inf(-1)
+		for i, e := range rest {
+			d0 := boxes[0].Union(e.bounds).Area() - boxes[0].Area()
+			d1 := boxes[1].Union(e.bounds).Area() - boxes[1].Area()
+			if diff := math.Abs(d0 - d1); diff > maxDiff {
+				// ties go to the smaller group box, then to the group with less entries
+				var first bool
+				switch {
+				case d0 != d1:
+					first = d0 < d1
+				case boxes[0].Area() != boxes[1].Area():
+					first = boxes[0].Area() < boxes[1].Area()
+				default:
+					first = len(groups[0]) <= len(groups[1])
+				}
+				next, maxDiff, g = i, diff, 1
+				if first {
+					g = 0
+				}
+			}
+		}
+		groups[g] = append(groups[g], rest[next])
+		boxes[g] = boxes[g].Union(rest[next].bounds)
+		rest = append(rest[:next], rest[next+1:]...)
+	}
+
+	n.entries = groups[0]
+	return &node[K]{leaf: n.leaf, entries: groups[1]}
+}
+
+// Delete removes the shape of id and reports whether it was there
+// Nodes left with less than minEntries are dissolved and their shapes
+// inserted again, which keeps the tree balanced
+func (ix *Index[K]) Delete(id K) bool {
+	s, ok := ix.shapes[id]
+	if !ok {
+		return false
+	}
+	delete(ix.shapes, id)
+
+	var orphans []entry[K]
+	deleteAt(ix.root, id, s.Bounds(), &orphans)
+	for !ix.root.leaf && len(ix.root.entries) == 1 {
+		ix.root = ix.root.entries[0].child
+	}
+	if !ix.root.leaf && len(ix.root.entries) == 0 {
+		ix.root = &node[K]{leaf: true}
+	}
+	for _, e := range orphans {
+		ix.insert(e)
+	}
+	return true
+}
+
+// deleteAt removes id from the subtree of n, the shapes of the dissolved
+// nodes are appended to orphans
+func deleteAt[K comparable](n *node[K], id K, r Rect, orphans *[]entry[K]) bool {
+	for i, e := range n.entries {
+		if !e.bounds.ContainsRect(r) {
+			continue
+		}
+		if n.leaf {
+			if e.id == id {
+				n.entries = append(n.entries[:i], n.entries[i+1:]...)
+				return true
+			}
+			continue
+		}
+		if !deleteAt(e.child, id, r, orphans) {
+			continue
+		}
+		if len(e.child.entries) < minEntries {
+			n.entries = append(n.entries[:i], n.entries[i+1:]...)
+			collectLeaves(e.child, orphans)
+		} else {
+			n.entries[i].bounds = e.child.bounds()
+		}
+		return true
+	}
+	return false
+}
+
+func collectLeaves[K comparable](n *node[K], into *[]entry[K]) {
+	if n.leaf {
+		*into = append(*into, n.entries...)
+		return
+	}
+	for _, e := range n.entries {
+		collectLeaves(e.child, into)
+	}
+}
+
+// Search calls fn with the shapes whose bounding box intersects r until fn
+// returns false
+func (ix *Index[K]) Search(r Rect, fn func(id K, s Shape) bool) {
+	search(ix.root, r, fn)
+}
+
+func search[K comparable](n *node[K], r Rect, fn func(K, Shape) bool) bool {
+	for _, e := range n.entries {
+		if !e.bounds.Intersects(r) {
+			continue
+		}
+		if n.leaf {
+			if !fn(e.id, e.shape) {
+				return false
+			}
+		} else if !search(e.child, r, fn) {
+			return false
+		}
+	}
+	return true
+}
+
+// Range returns the keys of the shapes whose bounding box intersects r
+func (ix *Index[K]) Range(r Rect) []K {
+	ids := make([]K, 0)
+	ix.Search(r, func(id K, _ Shape) bool {
+		ids = append(ids, id)
+		return true
+	})
+	return ids
+}
+
+// At returns the keys of the shapes containing p
+func (ix *Index[K]) At(p Point) []K {
+	ids := make([]K, 0)
+	ix.Search(Rect{p, p}, func(id K, s Shape) bool {
+		if s.Contains(p) {
+			ids = append(ids, id)
+		}
+		return true
+	})
+	return ids
+}
+
+// Nearest returns the keys of the k shapes closest to p, closest first,
+// measured with Dist. It is a best first search: the queue holds nodes by
+// the distance to their box, which is never more than the distance to any
+// shape inside, so a shape popped from it is closer than everything left
+func (ix *Index[K]) Nearest(p Point, k int) []K {
+	ids := make([]K, 0, k)
+	if k <= 0 {
+		return ids
+	}
+	q := &nearestQueue[K]{{dist: 0, node: ix.root}}
+	for q.Len() > 0 && len(ids) < k {
+		item := heap.Pop(q).(nearestItem[K])
+		if item.node == nil {
+			ids = append(ids, item.id)
+			continue
+		}
+		for _, e := range item.node.entries {
+			if item.node.leaf {
+				heap.Push(q, nearestItem[K]{dist: Dist(e.shape, p), id: e.id})
+			} else {
+				heap.Push(q, nearestItem[K]{dist: e.bounds.Dist(p), node: e.child})
+			}
+		}
+	}
+	return ids
+}
+
+// nearestItem is a node to explore, or a shape when node is nil
+type nearestItem[K comparable] struct {
+	dist float64
+	node *node[K]
+	id   K
+}
+
+type nearestQueue[K comparable] []nearestItem[K]
+
+func (q nearestQueue[K]) Len() int { return len(q) }
+
+// Less puts shapes before nodes at the same distance so they are returned first
+func (q nearestQueue[K]) Less(i, j int) bool {
+	if q[i].dist != q[j].dist {
+		return q[i].dist < q[j].dist
+	}
+	return q[i].node == nil && q[j].node != nil
+}
+
+func (q nearestQueue[K]) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+func (q *nearestQueue[K]) Push(x any)   { *q = append(*q, x.(nearestItem[K])) }
+
+func (q *nearestQueue[K]) Pop() any {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
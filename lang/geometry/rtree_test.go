@@ -0,0 +1,251 @@
+package geometry
+
+import (
+	"math/rand"
+	"slices"
+	"sort"
+	"testing"
+)
+
+// randomShapes returns n small shapes spread over a 1000x1000 square
+func randomShapes(rng *rand.Rand, n int) []Shape {
+	shapes := make([]Shape, n)
+	for i := range shapes {
+		p := Pt(rng.Float64()*1000, rng.Float64()*1000)
+		w, h := rng.Float64()*20, rng.Float64()*20
+		switch i % 3 {
+		case 0:
+			shapes[i] = Rect{p, p.Add(Pt(w, h))}
+		case 1:
+			shapes[i] = Circle{p, w / 2}
+		default:
+			shapes[i] = Triangle{p, p.Add(Pt(w, 0)), p.Add(Pt(0, h))}
+		}
+	}
+	return shapes
+}
+
+func indexOf(shapes []Shape) *Index[int] {
+	ix := NewIndex[int]()
+	for i, s := range shapes {
+		ix.Insert(i, s)
+	}
+	return ix
+}
+
+// the linear scans are what the index must agree with
+
+func linearAt(shapes map[int]Shape, p Point) []int {
+	ids := make([]int, 0)
+	for i, s := range shapes {
+		if s.Contains(p) {
+			ids = append(ids, i)
+		}
+	}
+	return ids
+}
+
+func linearRange(shapes map[int]Shape, r Rect) []int {
+	ids := make([]int, 0)
+	for i, s := range shapes {
+		if s.Bounds().Intersects(r) {
+			ids = append(ids, i)
+		}
+	}
+	return ids
+}
+
+func linearNearest(shapes map[int]Shape, p Point, k int) []float64 {
+	dists := make([]float64, 0, len(shapes))
+	for _, s := range shapes {
+		dists = append(dists, Dist(s, p))
+	}
+	sort.Float64s(dists)
+	return dists[:min(k, len(dists))]
+}
+
+func sameIDs(got, want []int) bool {
+	got, want = slices.Clone(got), slices.Clone(want)
+	slices.Sort(got)
+	slices.Sort(want)
+	return slices.Equal(got, want)
+}
+
+func asMap(shapes []Shape) map[int]Shape {
+	m := make(map[int]Shape, len(shapes))
+	for i, s := range shapes {
+		m[i] = s
+	}
+	return m
+}
+
+// checkQueries compares the index with the linear scans on random queries
+func checkQueries(t *testing.T, rng *rand.Rand, ix *Index[int], shapes map[int]Shape) {
+	t.Helper()
+	if ix.Len() != len(shapes) {
+		t.Fatalf("Len got %d, wanted %d", ix.Len(), len(shapes))
+	}
+	for range 50 {
+		p := Pt(rng.Float64()*1000, rng.Float64()*1000)
+		if got, want := ix.At(p), linearAt(shapes, p); !sameIDs(got, want) {
+			t.Errorf("At(%v) got %v, wanted %v", p, got, want)
+		}
+		r := Rect{p, p.Add(Pt(rng.Float64()*100, rng.Float64()*100))}
+		if got, want := ix.Range(r), linearRange(shapes, r); !sameIDs(got, want) {
+			t.Errorf("Range(%v) got %v, wanted %v", r, got, want)
+		}
+		k := rng.Intn(10) + 1
+		ids := ix.Nearest(p, k)
+		want := linearNearest(shapes, p, k)
+		if len(ids) != len(want) {
+			t.Fatalf("Nearest(%v, %d) got %d ids, wanted %d", p, k, len(ids), len(want))
+		}
+		for i, id := range ids {
+			if got := Dist(shapes[id], p); !near(got, want[i]) {
+				t.Errorf("Nearest(%v, %d)[%d] is at %v, wanted %v", p, k, i, got, want[i])
+			}
+		}
+	}
+}
+
+func TestIndexQueries(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, n := range []int{0, 1, 8, 9, 100, 2000} {
+		shapes := randomShapes(rng, n)
+		checkQueries(t, rng, indexOf(shapes), asMap(shapes))
+	}
+}
+
+func TestIndexDelete(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	shapes := asMap(randomShapes(rng, 1000))
+	ix := NewIndex[int]()
+	for i, s := range shapes {
+		ix.Insert(i, s)
+	}
+
+	// delete every other shape, then replace some of the rest
+	for i := 0; i < 1000; i += 2 {
+		if !ix.Delete(i) {
+			t.Fatalf("Delete(%d) got false", i)
+		}
+		delete(shapes, i)
+	}
+	if ix.Delete(0) {
+		t.Errorf("second Delete(0) got true")
+	}
+	for i := 1; i < 1000; i += 10 {
+		shapes[i] = Circle{Pt(rng.Float64()*1000, rng.Float64()*1000), 5}
+		ix.Insert(i, shapes[i])
+	}
+	checkQueries(t, rng, ix, shapes)
+
+	for i := range shapes {
+		ix.Delete(i)
+	}
+	if ix.Len() != 0 || len(ix.Range(R(0, 0, 1000, 1000))) != 0 || len(ix.Nearest(Pt(0, 0), 3)) != 0 {
+		t.Errorf("index not empty after deleting everything")
+	}
+	ix.Insert(7, square)
+	if got := ix.At(Pt(1, 1)); !slices.Equal(got, []int{7}) {
+		t.Errorf("At after emptying got %v", got)
+	}
+}
+
+func TestIndexSearchStops(t *testing.T) {
+	ix := indexOf(randomShapes(rand.New(rand.NewSource(3)), 100))
+	calls := 0
+	ix.Search(R(0, 0, 1000, 1000), func(int, Shape) bool {
+		calls++
+		return calls < 5
+	})
+	if calls != 5 {
+		t.Errorf("Search called fn %d times after returning false, wanted 5", calls)
+	}
+}
+
+// the benchmarks compare the index with iterating over every shape like
+// measure does
+
+const benchShapes = 10000
+
+func benchSetup() ([]Shape, *Index[int], []Point) {
+	rng := rand.New(rand.NewSource(4))
+	shapes := randomShapes(rng, benchShapes)
+	points := make([]Point, 1024)
+	for i := range points {
+		points[i] = Pt(rng.Float64()*1000, rng.Float64()*1000)
+	}
+	return shapes, indexOf(shapes), points
+}
+
+func BenchmarkIndexAt(b *testing.B) {
+	_, ix, points := benchSetup()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ix.At(points[i%len(points)])
+	}
+}
+
+func BenchmarkLinearAt(b *testing.B) {
+	shapes, _, points := benchSetup()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p := points[i%len(points)]
+		var ids []int
+		for j, s := range shapes {
+			if s.Contains(p) {
+				ids = append(ids, j)
+			}
+		}
+	}
+}
+
+func BenchmarkIndexRange(b *testing.B) {
+	_, ix, points := benchSetup()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p := points[i%len(points)]
+		ix.Range(Rect{p, p.Add(Pt(50, 50))})
+	}
+}
+
+func BenchmarkLinearRange(b *testing.B) {
+	shapes, _, points := benchSetup()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p := points[i%len(points)]
+		r := Rect{p, p.Add(Pt(50, 50))}
+		var ids []int
+		for j, s := range shapes {
+			if s.Bounds().Intersects(r) {
+				ids = append(ids, j)
+			}
+		}
+	}
+}
+
+func BenchmarkIndexNearest(b *testing.B) {
+	_, ix, points := benchSetup()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ix.Nearest(points[i%len(points)], 10)
+	}
+}
+
+func BenchmarkLinearNearest(b *testing.B) {
+	shapes, _, points := benchSetup()
+	m := asMap(shapes)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		linearNearest(m, points[i%len(points)], 10)
+	}
+}
+
+func BenchmarkIndexInsert(b *testing.B) {
+	shapes := randomShapes(rand.New(rand.NewSource(5)), benchShapes)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		indexOf(shapes)
+	}
+}
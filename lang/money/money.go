@@ -0,0 +1,337 @@
+// Package money is an exact fixed point amount of money
+//
+// An amount is stored as an int64 count of the minor unit of its currency,
+// cents for EUR, so additions never lose precision. Operations that can
+// produce fractions of a minor unit, like taking a percentage, take an
+// explicit RoundingMode and every operation reports overflows instead of
+// wrapping around
+package money
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrOverflow         = errors.New("money: overflow")
+	ErrCurrencyMismatch = errors.New("money: currency mismatch")
+	ErrSyntax           = errors.New("money: invalid amount")
+	ErrUnknownCurrency  = errors.New("money: unknown currency")
+)
+
+// Currency is an ISO 4217 code with its number of decimal digits
+type Currency struct {
+	Code   string
+	Digits int
+}
+
+var (
+	EUR = Currency{"EUR", 2}
+	USD = Currency{"USD", 2}
+	GBP = Currency{"GBP", 2}
+	JPY = Currency{"JPY", 0}
+)
+
+var currencies = map[string]Currency{"EUR": EUR, "USD": USD, "GBP": GBP, "JPY": JPY}
+
+// LookupCurrency finds one of the known currencies by its code, "eur" works too
+func LookupCurrency(code string) (Currency, error) {
+	c, ok := currencies[strings.ToUpper(strings.TrimSpace(code))]
+	if !ok {
+		return Currency{}, fmt.Errorf("%w: %q", ErrUnknownCurrency, code)
+	}
+	return c, nil
+}
+
+// scale returns 10^Digits
+func (c Currency) scale() int64 {
+	s := int64(1)
+	for i := 0; i < c.Digits; i++ {
+		s *= 10
+	}
+	return s
+}
+
+// RoundingMode decides what happens to fractions of a minor unit
+type RoundingMode int
+
+const (
+	// HalfEven rounds to the nearest unit and ties to the even one, the
+	// banker's rounding, so rounding errors do not pile up in one direction
+	HalfEven RoundingMode = iota
+	// HalfUp rounds to the nearest unit and ties away from zero
+	HalfUp
+	// Truncate drops the fraction, what integer division does
+	Truncate
+)
+
+func (r RoundingMode) String() string {
+	switch r {
+	case HalfEven:
+		return "half-even"
+	case HalfUp:
+		return "half-up"
+	case Truncate:
+		return "truncate"
+	}
+	return fmt.Sprintf("RoundingMode(%d)", int(r))
+}
+
+// Money is an amount in the minor unit of a currency
+// The zero value is zero without currency, it can be added to any amount
+type Money struct {
+	minor    int64
+	currency Currency
+}
+
+// New returns minor units of c, New(8640, EUR) is 86.40 EUR
+func New(minor int64, c Currency) Money {
+	return Money{minor: minor, currency: c}
+}
+
+// FromUnits returns whole units of c, FromUnits(864, EUR) is 864.00 EUR
+func FromUnits(units int64, c Currency) (Money, error) {
+	minor, ok := mul(units, c.scale())
+	if !ok {
+		return Money{}, fmt.Errorf("%w: %d %s", ErrOverflow, units, c.Code)
+	}
+	return New(minor, c), nil
+}
+
+// MustFromUnits is FromUnits panicking on overflow, for constants and tests
+func MustFromUnits(units int64, c Currency) Money {
+	m, err := FromUnits(units, c)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// Parse reads an amount like "864", "-12.5" or "86.40" in currency c
+// More decimals than the currency has is an error, nothing is rounded
+func Parse(s string, c Currency) (Money, error) {
+	text := strings.TrimSpace(s)
+	// at most one sign, kept for ParseInt so the smallest int64 fits
+	sign := ""
+	if strings.HasPrefix(text, "-") || strings.HasPrefix(text, "+") {
+		sign, text = text[:1], text[1:]
+	}
+	units, fraction, hasDot := strings.Cut(text, ".")
+	if units == "" || (hasDot && fraction == "") || len(fraction) > c.Digits ||
+		strings.ContainsAny(units+fraction, "+-") {
+		return Money{}, fmt.Errorf("%w: %q", ErrSyntax, s)
+	}
+	fraction += strings.Repeat("0", c.Digits-len(fraction))
+
+	minor, err := strconv.ParseInt(sign+units+fraction, 10, 64)
+	if err != nil {
+		if errors.Is(err, strconv.ErrRange) {
+			return Money{}, fmt.Errorf("%w: %q", ErrOverflow, s)
+		}
+		return Money{}, fmt.Errorf("%w: %q", ErrSyntax, s)
+	}
+	return New(minor, c), nil
+}
+
+// MustParse is Parse panicking on error, for constants and tests
+func MustParse(s string, c Currency) Money {
+	m, err := Parse(s, c)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// Minor returns the amount in minor units
+func (m Money) Minor() int64 {
+	return m.minor
+}
+
+// Currency returns the currency of the amount
+func (m Money) Currency() Currency {
+	return m.currency
+}
+
+// IsZero reports whether the amount is zero
+func (m Money) IsZero() bool {
+	return m.minor == 0
+}
+
+// Sign returns -1, 0 or 1
+func (m Money) Sign() int {
+	switch {
+	case m.minor < 0:
+		return -1
+	case m.minor > 0:
+		return 1
+	}
+	return 0
+}
+
+// Cmp compares the amounts, -1 if m < o, 0 if equal and 1 if m > o
+// Amounts of different currencies are ordered by currency code first so
+// Cmp is a total order usable for sorting
+func (m Money) Cmp(o Money) int {
+	if m.currency.Code != o.currency.Code && !m.untyped() && !o.untyped() {
+		return strings.Compare(m.currency.Code, o.currency.Code)
+	}
+	switch {
+	case m.minor < o.minor:
+		return -1
+	case m.minor > o.minor:
+		return 1
+	}
+	return 0
+}
+
+// untyped reports if m is the zero value without currency
+func (m Money) untyped() bool {
+	return m.currency.Code == "" && m.minor == 0
+}
+
+// common returns the currency of an operation between m and o
+func (m Money) common(o Money) (Currency, error) {
+	switch {
+	case m.untyped():
+		return o.currency, nil
+	case o.untyped(), m.currency == o.currency:
+		return m.currency, nil
+	}
+	return Currency{}, fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.currency.Code, o.currency.Code)
+}
+
+// Add returns m + o
+func (m Money) Add(o Money) (Money, error) {
+	c, err := m.common(o)
+	if err != nil {
+		return Money{}, err
+	}
+	sum := m.minor + o.minor
+	if (sum > m.minor) != (o.minor > 0) {
+		return Money{}, fmt.Errorf("%w: %v + %v", ErrOverflow, m, o)
+	}
+	return New(sum, c), nil
+}
+
+// Sub returns m - o
+func (m Money) Sub(o Money) (Money, error) {
+	if o.minor == math.MinInt64 {
+		return Money{}, fmt.Errorf("%w: %v - %v", ErrOverflow, m, o)
+	}
+	return m.Add(New(-o.minor, o.currency))
+}
+
+// Neg returns -m
+func (m Money) Neg() (Money, error) {
+	return Money{}.Sub(m)
+}
+
+// Mul returns m * n
+func (m Money) Mul(n int64) (Money, error) {
+	product, ok := mul(m.minor, n)
+	if !ok {
+		return Money{}, fmt.Errorf("%w: %v * %d", ErrOverflow, m, n)
+	}
+	return New(product, m.currency), nil
+}
+
+// MulFrac returns m * num / den rounded to the minor unit with mode
+func (m Money) MulFrac(num, den int64, mode RoundingMode) (Money, error) {
+	if den == 0 {
+		return Money{}, errors.New("money: division by zero")
+	}
+	n := new(big.Int).Mul(big.NewInt(m.minor), big.NewInt(num))
+	d := big.NewInt(den)
+	neg := n.Sign()*d.Sign() < 0
+	n.Abs(n)
+	d.Abs(d)
+
+	q, r := new(big.Int).QuoRem(n, d, new(big.Int))
+	// Compare the remainder with half of the divisor
+	half := new(big.Int).Lsh(r, 1).Cmp(d)
+	switch mode {
+	case HalfUp:
+		if half >= 0 {
+			q.Add(q, big.NewInt(1))
+		}
+	case HalfEven:
+		if half > 0 || (half == 0 && q.Bit(0) == 1) {
+			q.Add(q, big.NewInt(1))
+		}
+	case Truncate:
+	default:
+		return Money{}, fmt.Errorf("money: unknown rounding mode %v", mode)
+	}
+	if neg {
+		q.Neg(q)
+	}
+	if !q.IsInt64() {
+		return Money{}, fmt.Errorf("%w: %v * %d / %d", ErrOverflow, m, num, den)
+	}
+	return New(q.Int64(), m.currency), nil
+}
+
+// Percent returns p percent of m rounded with mode
+func (m Money) Percent(p int64, mode RoundingMode) (Money, error) {
+	return m.MulFrac(p, 100, mode)
+}
+
+// Sum adds every amount, they must share the currency
+func Sum(amounts ...Money) (Money, error) {
+	var total Money
+	for _, a := range amounts {
+		var err error
+		if total, err = total.Add(a); err != nil {
+			return Money{}, err
+		}
+	}
+	return total, nil
+}
+
+// String formats the amount like "86.40 EUR"
+func (m Money) String() string {
+	digits := m.currency.Digits
+	abs := strconv.FormatUint(absUint(m.minor), 10)
+	if len(abs) <= digits {
+		abs = strings.Repeat("0", digits-len(abs)+1) + abs
+	}
+	s := abs
+	if digits > 0 {
+		s = abs[:len(abs)-digits] + "." + abs[len(abs)-digits:]
+	}
+	if m.minor < 0 {
+		s = "-" + s
+	}
+	if m.currency.Code == "" {
+		return s
+	}
+	return s + " " + m.currency.Code
+}
+
+// Amount formats the amount without the currency, "86.40"
+func (m Money) Amount() string {
+	return strings.TrimSuffix(m.String(), " "+m.currency.Code)
+}
+
+func absUint(v int64) uint64 {
+	if v < 0 {
+		return uint64(-(v + 1)) + 1
+	}
+	return uint64(v)
+}
+
+// mul returns a * b and false if it overflows
+func mul(a, b int64) (int64, bool) {
+	if a == 0 || b == 0 {
+		return 0, true
+	}
+	p := a * b
+	if p/b != a || (a == -1 && b == math.MinInt64) || (b == -1 && a == math.MinInt64) {
+		return 0, false
+	}
+	return p, true
+}
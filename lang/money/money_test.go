@@ -0,0 +1,143 @@
+package money
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestParseAndString(t *testing.T) {
+	tests := []struct {
+		in   string
+		c    Currency
+		want string
+	}{
+		{"864", EUR, "864.00 EUR"},
+		{"86.4", EUR, "86.40 EUR"},
+		{"-0.05", USD, "-0.05 USD"},
+		{"+12.34", EUR, "12.34 EUR"},
+		{"1500", JPY, "1500 JPY"},
+		// the smallest int64, its absolute value does not fit
+		{"-9223372036854775808", JPY, "-9223372036854775808 JPY"},
+		{"-92233720368547758.08", EUR, "-92233720368547758.08 EUR"},
+	}
+	for _, test := range tests {
+		m, err := Parse(test.in, test.c)
+		if err != nil {
+			t.Errorf("Parse(%q) returned %v", test.in, err)
+			continue
+		}
+		if got := m.String(); got != test.want {
+			t.Errorf("Parse(%q) got %s, wanted %s", test.in, got, test.want)
+		}
+	}
+
+	for _, bad := range []string{"", "abc", "1.234", "1.", "--1", "-+5", "+-5", "++5", "1.-5"} {
+		if _, err := Parse(bad, EUR); !errors.Is(err, ErrSyntax) {
+			t.Errorf("Parse(%q) got %v, wanted %v", bad, err, ErrSyntax)
+		}
+	}
+	if _, err := Parse("1.5", JPY); !errors.Is(err, ErrSyntax) {
+		t.Errorf("Parse of decimals in JPY got %v, wanted %v", err, ErrSyntax)
+	}
+	for _, big := range []string{"99999999999999999999", "9223372036854775808", "-9223372036854775809"} {
+		if _, err := Parse(big, JPY); !errors.Is(err, ErrOverflow) {
+			t.Errorf("Parse(%q) got %v, wanted %v", big, err, ErrOverflow)
+		}
+	}
+}
+
+func TestRounding(t *testing.T) {
+	tests := []struct {
+		minor    int64
+		num, den int64
+		mode     RoundingMode
+		want     int64
+	}{
+		// 10% of 864.00 is exact, the cents are kept
+		{86400, 10, 100, HalfEven, 8640},
+		{86400, 10, 100, Truncate, 8640},
+		// 0.25 / 10 = 0.025
+		{25, 1, 10, HalfEven, 2},
+		{25, 1, 10, HalfUp, 3},
+		{25, 1, 10, Truncate, 2},
+		// 0.35 / 10 = 0.035
+		{35, 1, 10, HalfEven, 4},
+		{35, 1, 10, HalfUp, 4},
+		// negative amounts round symmetrically
+		{-25, 1, 10, HalfUp, -3},
+		{-25, 1, 10, HalfEven, -2},
+		{-29, 1, 10, Truncate, -2},
+		{26, 1, 10, HalfEven, 3},
+	}
+	for _, test := range tests {
+		got, err := New(test.minor, EUR).MulFrac(test.num, test.den, test.mode)
+		if err != nil {
+			t.Errorf("MulFrac returned %v", err)
+			continue
+		}
+		if got.Minor() != test.want {
+			t.Errorf("%d * %d / %d %v got %d, wanted %d", test.minor, test.num, test.den, test.mode, got.Minor(), test.want)
+		}
+	}
+}
+
+func TestArithmetic(t *testing.T) {
+	a := MustParse("10.10", EUR)
+	b := MustParse("0.20", EUR)
+
+	sum, _ := a.Add(b)
+	if sum.String() != "10.30 EUR" {
+		t.Errorf("got %s, wanted 10.30 EUR", sum)
+	}
+	diff, _ := b.Sub(a)
+	if diff.String() != "-9.90 EUR" {
+		t.Errorf("got %s, wanted -9.90 EUR", diff)
+	}
+	total, _ := Sum(a, b, b)
+	if total.Minor() != 1050 {
+		t.Errorf("got %s, wanted 10.50 EUR", total)
+	}
+	if a.Cmp(b) != 1 || b.Cmp(a) != -1 || a.Cmp(a) != 0 {
+		t.Errorf("Cmp does not order 10.10 and 0.20")
+	}
+
+	if _, err := a.Add(MustParse("1", USD)); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("got %v, wanted %v", err, ErrCurrencyMismatch)
+	}
+}
+
+func TestOverflow(t *testing.T) {
+	max := New(math.MaxInt64, EUR)
+	if _, err := max.Add(New(1, EUR)); !errors.Is(err, ErrOverflow) {
+		t.Errorf("Add got %v, wanted %v", err, ErrOverflow)
+	}
+	if _, err := New(math.MinInt64, EUR).Sub(New(1, EUR)); !errors.Is(err, ErrOverflow) {
+		t.Errorf("Sub got %v, wanted %v", err, ErrOverflow)
+	}
+	if _, err := max.Mul(2); !errors.Is(err, ErrOverflow) {
+		t.Errorf("Mul got %v, wanted %v", err, ErrOverflow)
+	}
+	if _, err := max.MulFrac(3, 2, HalfEven); !errors.Is(err, ErrOverflow) {
+		t.Errorf("MulFrac got %v, wanted %v", err, ErrOverflow)
+	}
+	// The intermediate product overflows but the result fits
+	if got, err := max.MulFrac(2, 2, HalfEven); err != nil || got.Minor() != math.MaxInt64 {
+		t.Errorf("MulFrac got %v %v, wanted the amount back", got, err)
+	}
+	if _, err := FromUnits(math.MaxInt64/10, EUR); !errors.Is(err, ErrOverflow) {
+		t.Errorf("FromUnits got %v, wanted %v", err, ErrOverflow)
+	}
+}
+
+func TestLookupCurrency(t *testing.T) {
+	for code, want := range map[string]Currency{"EUR": EUR, "usd": USD, " JPY ": JPY} {
+		got, err := LookupCurrency(code)
+		if err != nil || got != want {
+			t.Errorf("LookupCurrency(%q) got %v %v, wanted %v", code, got, err, want)
+		}
+	}
+	if _, err := LookupCurrency("XXX"); !errors.Is(err, ErrUnknownCurrency) {
+		t.Errorf("LookupCurrency(XXX) got %v, wanted %v", err, ErrUnknownCurrency)
+	}
+}
@@ -0,0 +1,227 @@
+package lang
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/vrnvu/go-examples/lang/errs"
+	"github.com/vrnvu/go-examples/lang/money"
+)
+
+// BonusPolicy computes the bonus of an employee
+// It replaces the single BONUS_PERCENTAGE used by FindEmployeeBonus
+type BonusPolicy interface {
+	Bonus(e Employee) (money.Money, error)
+}
+
+// BonusPolicyFunc adapts a function to BonusPolicy
+type BonusPolicyFunc func(e Employee) (money.Money, error)
+
+func (f BonusPolicyFunc) Bonus(e Employee) (money.Money, error) {
+	return f(e)
+}
+
+// FlatPercentage pays Percent of the salary for every sale
+// FlatPercentage{Percent: BONUS_PERCENTAGE} is FindEmployeeBonus
+type FlatPercentage struct {
+	Percent  int64
+	Rounding money.RoundingMode
+}
+
+func (p FlatPercentage) Bonus(e Employee) (money.Money, error) {
+	perSale, err := e.salary.Percent(p.Percent, p.Rounding)
+	if err != nil {
+		return money.Money{}, err
+	}
+	return perSale.Mul(int64(e.sales))
+}
+
+// Tier pays Percent of the salary once an employee reaches MinSales
+type Tier struct {
+	MinSales int
+	Percent  int64
+}
+
+// TieredBySales pays the percentage of the highest tier reached
+// The percentage applies once to the salary, not per sale
+type TieredBySales struct {
+	Tiers    []Tier
+	Rounding money.RoundingMode
+}
+
+func (p TieredBySales) Bonus(e Employee) (money.Money, error) {
+	tiers := make([]Tier, len(p.Tiers))
+	copy(tiers, p.Tiers)
+	sort.Slice(tiers, func(i, j int) bool {
+		return tiers[i].MinSales < tiers[j].MinSales
+	})
+	var percent int64
+	for _, t := range tiers {
+		if e.sales >= t.MinSales {
+			percent = t.Percent
+		}
+	}
+	return e.salary.Percent(percent, p.Rounding)
+}
+
+// Capped limits the bonus of another policy to Max
+type Capped struct {
+	Policy BonusPolicy
+	Max    money.Money
+}
+
+func (p Capped) Bonus(e Employee) (money.Money, error) {
+	bonus, err := p.Policy.Bonus(e)
+	if err != nil {
+		return money.Money{}, err
+	}
+	// Cmp orders different currencies by code, a cap in another currency
+	// would be applied at random
+	if b, m := bonus.Currency().Code, p.Max.Currency().Code; b != "" && m != "" && b != m {
+		return money.Money{}, fmt.Errorf("%w: bonus %v, cap %v", money.ErrCurrencyMismatch, bonus, p.Max)
+	}
+	if bonus.Cmp(p.Max) > 0 {
+		return p.Max, nil
+	}
+	return bonus, nil
+}
+
+// CommissionPlusBase pays a fixed Base plus PerSale for every sale
+type CommissionPlusBase struct {
+	Base    money.Money
+	PerSale money.Money
+}
+
+func (p CommissionPlusBase) Bonus(e Employee) (money.Money, error) {
+	commission, err := p.PerSale.Mul(int64(e.sales))
+	if err != nil {
+		return money.Money{}, err
+	}
+	return p.Base.Add(commission)
+}
+
+// Payroll pays every employee with Policy, or with their entry in Plans
+type Payroll struct {
+	Policy BonusPolicy
+	Plans  map[string]BonusPolicy
+}
+
+// PaySlip is the pay of one employee
+type PaySlip struct {
+	Name   string
+	Salary money.Money
+	Bonus  money.Money
+	Total  money.Money
+}
+
+// PayrollReport is the outcome of a payroll run
+type PayrollReport struct {
+	Slips  []PaySlip
+	Salary money.Money
+	Bonus  money.Money
+	Total  money.Money
+}
+
+// Reconcile checks the report to the cent: every slip total is its salary
+// plus its bonus and the report totals are the sums of the slips
+func (r PayrollReport) Reconcile() error {
+	var salary, bonus, total money.Money
+	for _, slip := range r.Slips {
+		if sum, err := slip.Salary.Add(slip.Bonus); err != nil || sum != slip.Total {
+			return errs.New(errs.Internal, fmt.Sprintf("pay slip of %s: %v + %v is not %v", slip.Name, slip.Salary, slip.Bonus, slip.Total),
+				"employee", slip.Name)
+		}
+		var err error
+		if salary, err = salary.Add(slip.Salary); err != nil {
+			return err
+		}
+		if bonus, err = bonus.Add(slip.Bonus); err != nil {
+			return err
+		}
+		if total, err = total.Add(slip.Total); err != nil {
+			return err
+		}
+	}
+	if salary != r.Salary || bonus != r.Bonus || total != r.Total {
+		return errs.New(errs.Internal, fmt.Sprintf("payroll totals %v %v %v do not match the slips %v %v %v", r.Salary, r.Bonus, r.Total, salary, bonus, total))
+	}
+	return nil
+}
+
+// PolicyFor returns the policy paying the employee, an Invalid error when
+// there is none
+func (p Payroll) PolicyFor(e Employee) (BonusPolicy, error) {
+	if policy, ok := p.Plans[e.name]; ok && policy != nil {
+		return policy, nil
+	}
+	if p.Policy == nil {
+		return nil, errs.New(errs.Invalid, "no bonus policy for "+e.name, "employee", e.name)
+	}
+	return p.Policy, nil
+}
+
+// Run computes the bonus of every employee, stores it in the employee
+// and returns the pay slips in the order of employees
+// It stops at the first employee whose pay can not be computed, the error
+// is Invalid with the employee and the policy as fields
+func (p Payroll) Run(employees []*Employee) (PayrollReport, error) {
+	report := PayrollReport{Slips: make([]PaySlip, 0, len(employees))}
+	for _, e := range employees {
+		policy, err := p.PolicyFor(*e)
+		if err != nil {
+			return report, err
+		}
+		bonus, err := policy.Bonus(*e)
+		if err != nil {
+			return report, errs.Wrap(err, errs.Invalid, "bonus of "+e.name, "employee", e.name, "policy", fmt.Sprintf("%T", policy))
+		}
+		e.bonus = bonus
+		total, err := e.TotalPay()
+		if err != nil {
+			return report, errs.Wrap(err, errs.Invalid, "total pay of "+e.name, "employee", e.name)
+		}
+		slip := PaySlip{Name: e.name, Salary: e.salary, Bonus: e.bonus, Total: total}
+		report.Slips = append(report.Slips, slip)
+		if report.Salary, err = report.Salary.Add(slip.Salary); err != nil {
+			return report, err
+		}
+		if report.Bonus, err = report.Bonus.Add(slip.Bonus); err != nil {
+			return report, err
+		}
+		if report.Total, err = report.Total.Add(slip.Total); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// eur is a shorthand for the examples
+func eur(units int64) money.Money {
+	return money.MustFromUnits(units, money.EUR)
+}
+
+// PayrollRun pays a small team with different compensation plans
+func PayrollRun() {
+	employees := []*Employee{
+		NewEmployee("ann", eur(5000), 5, money.Money{}),
+		NewEmployee("bob", money.MustParse("6000.50", money.EUR), 2, money.Money{}),
+		NewEmployee("eve", money.MustParse("7000.99", money.EUR), 9, money.Money{}),
+	}
+	payroll := Payroll{
+		Policy: Capped{FlatPercentage{Percent: BONUS_PERCENTAGE}, eur(2000)},
+		Plans: map[string]BonusPolicy{
+			"bob": CommissionPlusBase{Base: eur(200), PerSale: money.MustParse("99.99", money.EUR)},
+			"eve": TieredBySales{Tiers: []Tier{{1, 5}, {5, 15}, {8, 25}}},
+		},
+	}
+	report, err := payroll.Run(employees)
+	if err != nil {
+		fmt.Println("error:", err)
+		return
+	}
+	for _, slip := range report.Slips {
+		fmt.Printf("%-4s salary: %12v bonus: %12v total: %12v\n", slip.Name, slip.Salary, slip.Bonus, slip.Total)
+	}
+	fmt.Printf("%-4s salary: %12v bonus: %12v total: %12v\n", "all", report.Salary, report.Bonus, report.Total)
+	fmt.Println("reconciled:", report.Reconcile() == nil)
+}
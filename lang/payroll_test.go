@@ -0,0 +1,99 @@
+package lang
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/vrnvu/go-examples/lang/errs"
+	"github.com/vrnvu/go-examples/lang/money"
+)
+
+type bonusPolicyTest struct {
+	name     string
+	policy   BonusPolicy
+	salary   string
+	sales    int
+	expected string
+	err      error
+}
+
+var tiers = []Tier{{5, 20}, {1, 5}}
+
+var bonusPolicyTests = []bonusPolicyTest{
+	{"flat", FlatPercentage{Percent: 10}, "5000", 5, "2500.00 EUR", nil},
+	{"flat keeps cents", FlatPercentage{Percent: 10}, "864", 2, "172.80 EUR", nil},
+	{"flat half even", FlatPercentage{Percent: 10}, "0.25", 1, "0.02 EUR", nil},
+	{"flat half up", FlatPercentage{Percent: 10, Rounding: money.HalfUp}, "0.25", 1, "0.03 EUR", nil},
+	{"flat truncate", FlatPercentage{Percent: 10, Rounding: money.Truncate}, "0.29", 1, "0.02 EUR", nil},
+	{"tier below", TieredBySales{Tiers: tiers}, "1000", 0, "0.00 EUR", nil},
+	{"tier low", TieredBySales{Tiers: tiers}, "1000", 3, "50.00 EUR", nil},
+	{"tier high", TieredBySales{Tiers: tiers}, "1000", 7, "200.00 EUR", nil},
+	{"capped", Capped{FlatPercentage{Percent: 10}, euros("1000")}, "5000", 5, "1000.00 EUR", nil},
+	{"under cap", Capped{FlatPercentage{Percent: 10}, euros("1000")}, "5000", 1, "500.00 EUR", nil},
+	{"commission", CommissionPlusBase{Base: euros("100"), PerSale: euros("25.50")}, "5000", 4, "202.00 EUR", nil},
+	{"cap in another currency", Capped{FlatPercentage{Percent: 10}, money.MustFromUnits(1000, money.USD)}, "5000", 5, "", money.ErrCurrencyMismatch},
+}
+
+func TestBonusPolicies(t *testing.T) {
+	for _, test := range bonusPolicyTests {
+		got, err := test.policy.Bonus(*NewEmployee(test.name, euros(test.salary), test.sales, money.Money{}))
+		if test.err != nil {
+			if !errors.Is(err, test.err) {
+				t.Errorf("%s: got %v %v, wanted %v", test.name, got, err, test.err)
+			}
+			continue
+		}
+		assertMoney(t, got, err, test.expected)
+	}
+}
+
+func TestPayrollRun(t *testing.T) {
+	e0 := NewEmployee("e0", euros("5000.01"), 5, money.Money{})
+	e1 := NewEmployee("e1", euros("8500.99"), 3, money.Money{})
+	payroll := Payroll{
+		Policy: FlatPercentage{Percent: BONUS_PERCENTAGE},
+		Plans:  map[string]BonusPolicy{"e1": CommissionPlusBase{Base: euros("100"), PerSale: euros("33.33")}},
+	}
+	report, err := payroll.Run([]*Employee{e0, e1})
+	if err != nil {
+		t.Fatalf("Run returned %v", err)
+	}
+
+	assertMoney(t, e0.Bonus(), nil, "2500.00 EUR")
+	assertMoney(t, e1.Bonus(), nil, "199.99 EUR")
+	total, err := e0.TotalPay()
+	assertMoney(t, total, err, "7500.01 EUR")
+	assertMoney(t, report.Salary, nil, "13501.00 EUR")
+	assertMoney(t, report.Bonus, nil, "2699.99 EUR")
+	assertMoney(t, report.Total, nil, "16200.99 EUR")
+	if err := report.Reconcile(); err != nil {
+		t.Errorf("Reconcile returned %v", err)
+	}
+
+	report.Total = euros("16201")
+	if err := report.Reconcile(); errs.KindOf(err) != errs.Internal {
+		t.Errorf("Reconcile of a wrong total got %v", err)
+	}
+}
+
+func TestPayrollRunErrors(t *testing.T) {
+	e := NewEmployee("e", euros("5000"), 1, money.Money{})
+	payroll := Payroll{Policy: CommissionPlusBase{Base: money.MustFromUnits(1, money.USD)}}
+	_, err := payroll.Run([]*Employee{e})
+	if !errors.Is(err, errs.Invalid) || !errors.Is(err, money.ErrCurrencyMismatch) {
+		t.Fatalf("Run of a bonus in another currency got %v", err)
+	}
+	if name, _ := errs.FieldOf(err, "employee"); name != "e" {
+		t.Errorf("employee field got %v, wanted e", name)
+	}
+
+	if _, err := (Payroll{}).Run([]*Employee{e}); !errors.Is(err, errs.Invalid) {
+		t.Errorf("Run without a policy got %v", err)
+	} else if name, _ := errs.FieldOf(err, "employee"); name != "e" {
+		t.Errorf("employee field got %v, wanted e", name)
+	}
+	nilPlan := Payroll{Plans: map[string]BonusPolicy{"e": nil}}
+	if _, err := nilPlan.Run([]*Employee{e}); !errors.Is(err, errs.Invalid) {
+		t.Errorf("Run with a nil plan got %v", err)
+	}
+}
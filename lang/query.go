@@ -0,0 +1,437 @@
+package lang
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// A Query is a boolean expression over the fields of an Employee
+//
+//	salary > 5500 && sales >= 3
+//	name == "ann" || !(bonus <= 100.50)
+//
+// Fields are name (a string), salary and bonus (amounts) and sales (a number)
+// Comparisons are == != < <= > >=, strings compare with strings and numbers
+// with numbers, amounts are compared exactly in the currency of the employee
+type Query struct {
+	src  string
+	root queryNode
+}
+
+// ParseError is a query that does not parse, Column is 1 based
+type ParseError struct {
+	Query  string
+	Column int
+	Msg    string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("query: column %d: %s", e.Column, e.Msg)
+}
+
+// Caret shows the query with a ^ under the column of the error
+func (e *ParseError) Caret() string {
+	return e.Query + "\n" + strings.Repeat(" ", e.Column-1) + "^"
+}
+
+// ParseQuery parses src, the errors are *ParseError
+func ParseQuery(src string) (*Query, error) {
+	p := &queryParser{src: src}
+	if err := p.lex(); err != nil {
+		return nil, err
+	}
+	root, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, p.errorf(t, "unexpected %s after the expression", t)
+	}
+	return &Query{src, root}, nil
+}
+
+// MustParseQuery is ParseQuery panicking on error, for constants and tests
+func MustParseQuery(src string) *Query {
+	q, err := ParseQuery(src)
+	if err != nil {
+		panic(err)
+	}
+	return q
+}
+
+// Match reports whether e satisfies the query, it is the predicate of
+// Filter and FilterPointers
+func (q *Query) Match(e Employee) bool {
+	return q.root.eval(e)
+}
+
+func (q *Query) String() string {
+	return q.src
+}
+
+// FilterQuery parses query and filters the employees with it
+func FilterQuery(employees []Employee, query string) ([]Employee, error) {
+	q, err := ParseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	return Filter(employees, q.Match), nil
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokNumber
+	tokString
+	tokOp
+	tokAnd
+	tokOr
+	tokNot
+	tokLParen
+	tokRParen
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int // 0 based byte offset
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of query"
+	case tokString:
+		return "string " + t.text
+	case tokNumber:
+		return "number " + t.text
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+type queryParser struct {
+	src    string
+	tokens []token
+	next   int
+}
+
+func (p *queryParser) errorf(t token, format string, args ...any) error {
+	return &ParseError{p.src, t.pos + 1, fmt.Sprintf(format, args...)}
+}
+
+// lex splits the query in tokens, strings keep their quotes
+func (p *queryParser) lex() error {
+	src := p.src
+	for i := 0; i < len(src); {
+		c := src[i]
+		start := i
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+			continue
+		case c == '(' || c == ')':
+			kind := tokLParen
+			if c == ')' {
+				kind = tokRParen
+			}
+			p.tokens = append(p.tokens, token{kind, src[i : i+1], i})
+			i++
+			continue
+		case strings.HasPrefix(src[i:], "&&"):
+			p.tokens = append(p.tokens, token{tokAnd, "&&", i})
+			i += 2
+			continue
+		case strings.HasPrefix(src[i:], "||"):
+			p.tokens = append(p.tokens, token{tokOr, "||", i})
+			i += 2
+			continue
+		case strings.ContainsRune("=!<>", rune(c)):
+			i++
+			if i < len(src) && src[i] == '=' {
+				i++
+			}
+			op := src[start:i]
+			switch op {
+			case "!":
+				p.tokens = append(p.tokens, token{tokNot, op, start})
+			case "=":
+				return &ParseError{src, start + 1, `unknown operator "=", use "=="`}
+			default:
+				p.tokens = append(p.tokens, token{tokOp, op, start})
+			}
+			continue
+		case c == '"' || c == '\'':
+			i++
+			for i < len(src) && src[i] != c {
+				if src[i] == '\\' {
+					i++
+				}
+				i++
+			}
+			if i >= len(src) {
+				return &ParseError{src, start + 1, "unterminated string"}
+			}
+			i++
+			p.tokens = append(p.tokens, token{tokString, src[start:i], start})
+			continue
+		// a number starts with a digit or a minus, ".5" is written "0.5"
+		case c >= '0' && c <= '9' || c == '-':
+			i++
+			for i < len(src) && (src[i] >= '0' && src[i] <= '9' || src[i] == '.') {
+				i++
+			}
+			p.tokens = append(p.tokens, token{tokNumber, src[start:i], start})
+			continue
+		case isIdentByte(c):
+			for i < len(src) && (isIdentByte(src[i]) || src[i] >= '0' && src[i] <= '9') {
+				i++
+			}
+			p.tokens = append(p.tokens, token{tokIdent, src[start:i], start})
+			continue
+		}
+		return &ParseError{src, i + 1, fmt.Sprintf("unexpected character %q", c)}
+	}
+	p.tokens = append(p.tokens, token{tokEOF, "", len(src)})
+	return nil
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func (p *queryParser) peek() token {
+	return p.tokens[p.next]
+}
+
+func (p *queryParser) take() token {
+	t := p.tokens[p.next]
+	if t.kind != tokEOF {
+		p.next++
+	}
+	return t
+}
+
+// or := and ("||" and)*
+func (p *queryParser) or() (queryNode, error) {
+	left, err := p.and()
+	for err == nil && p.peek().kind == tokOr {
+		p.take()
+		var right queryNode
+		if right, err = p.and(); err == nil {
+			left = orNode{left, right}
+		}
+	}
+	return left, err
+}
+
+// and := unary ("&&" unary)*
+func (p *queryParser) and() (queryNode, error) {
+	left, err := p.unary()
+	for err == nil && p.peek().kind == tokAnd {
+		p.take()
+		var right queryNode
+		if right, err = p.unary(); err == nil {
+			left = andNode{left, right}
+		}
+	}
+	return left, err
+}
+
+// unary := "!" unary | "(" or ")" | comparison
+func (p *queryParser) unary() (queryNode, error) {
+	switch t := p.peek(); t.kind {
+	case tokNot:
+		p.take()
+		n, err := p.unary()
+		return notNode{n}, err
+	case tokLParen:
+		p.take()
+		n, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.take(); closing.kind != tokRParen {
+			return nil, p.errorf(closing, "expected \")\" to close the \"(\" at column %d, found %s", t.pos+1, closing)
+		}
+		return n, nil
+	}
+	return p.comparison()
+}
+
+// comparison := operand op operand
+func (p *queryParser) comparison() (queryNode, error) {
+	left, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	op := p.take()
+	if op.kind != tokOp {
+		return nil, p.errorf(op, "expected a comparison operator after %s, found %s", left.token, op)
+	}
+	right, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	if left.kind != right.kind {
+		return nil, p.errorf(op, "cannot compare %s (%s) with %s (%s)", left.token.text, left.kind, right.token.text, right.kind)
+	}
+	return compareNode{op.text, left, right}, nil
+}
+
+// operand := field | number | string
+func (p *queryParser) operand() (operand, error) {
+	t := p.take()
+	switch t.kind {
+	case tokIdent:
+		field, ok := queryFields[strings.ToLower(t.text)]
+		if !ok {
+			return operand{}, p.errorf(t, "unknown field %q, fields are name, salary, sales and bonus", t.text)
+		}
+		field.token = t
+		return field, nil
+	case tokNumber:
+		d, ok := parseDecimal(t.text)
+		if !ok {
+			return operand{}, p.errorf(t, "invalid number %q", t.text)
+		}
+		return operand{kind: "number", token: t, number: func(Employee) decimal { return d }}, nil
+	case tokString:
+		s, err := unquote(t.text)
+		if err != nil {
+			return operand{}, p.errorf(t, "invalid string %s", t.text)
+		}
+		return operand{kind: "string", token: t, str: func(Employee) string { return s }}, nil
+	}
+	return operand{}, p.errorf(t, "expected a field, a number or a string, found %s", t)
+}
+
+func unquote(s string) (string, error) {
+	if s[0] == '\'' {
+		s = `"` + strings.ReplaceAll(strings.ReplaceAll(s[1:len(s)-1], `\'`, `'`), `"`, `\"`) + `"`
+	}
+	return strconv.Unquote(s)
+}
+
+// decimal is value / 10^scale, exact for the amounts of any currency
+type decimal struct {
+	value int64
+	scale int
+}
+
+func parseDecimal(s string) (decimal, bool) {
+	units, fraction, _ := strings.Cut(s, ".")
+	if units == "" || units == "-" || strings.Contains(fraction, ".") {
+		return decimal{}, false
+	}
+	v, err := strconv.ParseInt(units+fraction, 10, 64)
+	return decimal{v, len(fraction)}, err == nil
+}
+
+// cmp compares d and o without rounding, scaling both to the largest scale
+func (d decimal) cmp(o decimal) int {
+	a, b := big.NewInt(d.value), big.NewInt(o.value)
+	ten := big.NewInt(10)
+	for s := d.scale; s < o.scale; s++ {
+		a.Mul(a, ten)
+	}
+	for s := o.scale; s < d.scale; s++ {
+		b.Mul(b, ten)
+	}
+	return a.Cmp(b)
+}
+
+// operand is a field or a literal, only the func of its kind is set
+type operand struct {
+	kind   string
+	token  token
+	str    func(Employee) string
+	number func(Employee) decimal
+}
+
+var queryFields = map[string]operand{
+	"name": {kind: "string", str: func(e Employee) string { return e.name }},
+	"salary": {kind: "number", number: func(e Employee) decimal {
+		return decimal{e.salary.Minor(), e.salary.Currency().Digits}
+	}},
+	"sales": {kind: "number", number: func(e Employee) decimal { return decimal{int64(e.sales), 0} }},
+	"bonus": {kind: "number", number: func(e Employee) decimal {
+		return decimal{e.bonus.Minor(), e.bonus.Currency().Digits}
+	}},
+}
+
+type queryNode interface {
+	eval(e Employee) bool
+}
+
+type andNode struct{ left, right queryNode }
+type orNode struct{ left, right queryNode }
+type notNode struct{ n queryNode }
+
+type compareNode struct {
+	op          string
+	left, right operand
+}
+
+func (n andNode) eval(e Employee) bool { return n.left.eval(e) && n.right.eval(e) }
+func (n orNode) eval(e Employee) bool  { return n.left.eval(e) || n.right.eval(e) }
+func (n notNode) eval(e Employee) bool { return !n.n.eval(e) }
+
+func (n compareNode) eval(e Employee) bool {
+	var c int
+	if n.left.kind == "string" {
+		c = strings.Compare(n.left.str(e), n.right.str(e))
+	} else {
+		c = n.left.number(e).cmp(n.right.number(e))
+	}
+	switch n.op {
+	case "==":
+		return c == 0
+	case "!=":
+		return c != 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	}
+	return c >= 0
+}
+
+// QueryMain prints the employees of a roster matching a query,
+// the roster is employees.csv and the query salary > 5500 && sales >= 3 by default
+func QueryMain(args []string) error {
+	if len(args) == 0 {
+		args = []string{"salary > 5500 && sales >= 3"}
+	}
+	if len(args) > 2 {
+		return fmt.Errorf("usage: Query 'salary > 5500 && sales >= 3' [roster.csv|roster.json]")
+	}
+	q, err := ParseQuery(args[0])
+	if err != nil {
+		var perr *ParseError
+		if errors.As(err, &perr) {
+			fmt.Println(perr.Caret())
+		}
+		return err
+	}
+	var employees []*Employee
+	if len(args) == 2 {
+		employees, err = LoadRoster(args[1])
+	} else {
+		employees, err = LoadRosterCSV(bytes.NewReader(employeesCSV), "employees.csv")
+	}
+	if err != nil {
+		return err
+	}
+	for _, e := range FilterPointers(employees, q.Match) {
+		fmt.Printf("%-4s salary: %12v sales: %d\n", e.name, e.salary, e.sales)
+	}
+	return nil
+}
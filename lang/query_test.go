@@ -0,0 +1,115 @@
+package lang
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/vrnvu/go-examples/lang/money"
+)
+
+func queryEmployees() []Employee {
+	return []Employee{
+		*NewEmployee("ann", euros("5000"), 5, euros("500")),
+		*NewEmployee("bob", euros("5500.01"), 2, money.Money{}),
+		*NewEmployee("eve", euros("7000.99"), 9, euros("100.50")),
+		*NewEmployee("kai", money.MustParse("750000", money.JPY), 3, money.Money{}),
+	}
+}
+
+func names(employees []Employee) string {
+	result := make([]string, 0, len(employees))
+	for _, e := range employees {
+		result = append(result, e.name)
+	}
+	return strings.Join(result, ",")
+}
+
+type queryTest struct {
+	query, expected string
+}
+
+var queryTests = []queryTest{
+	{"salary > 5500 && sales >= 3", "eve,kai"},
+	{"salary > 5500.01", "eve,kai"},
+	{"salary >= 5500.010", "bob,eve,kai"},
+	{"salary < 5500.001", "ann"},
+	{"sales == 5 || sales == 2", "ann,bob"},
+	{"!(sales < 5)", "ann,eve"},
+	{"name == \"ann\" || name == 'kai'", "ann,kai"},
+	{"name != 'ann' && name < \"c\"", "bob"},
+	{"bonus > 0 && bonus <= 100.5", "eve"},
+	{"salary > bonus && bonus == 0", "bob,kai"},
+	{"SALES > 2 && (name == 'ann' || name >= 'k')", "ann,kai"},
+	{"sales > 2 || sales < 3 && name == 'bob'", "ann,bob,eve,kai"},
+	{"!!(sales > 100)", ""},
+	{"salary > -1", "ann,bob,eve,kai"},
+}
+
+func TestQuery(t *testing.T) {
+	employees := queryEmployees()
+	for _, test := range queryTests {
+		got, err := FilterQuery(employees, test.query)
+		if err != nil {
+			t.Errorf("%s: %v", test.query, err)
+			continue
+		}
+		if names(got) != test.expected {
+			t.Errorf("%s: got %q, wanted %q", test.query, names(got), test.expected)
+		}
+	}
+}
+
+func TestQueryFilterPointers(t *testing.T) {
+	var pemployees []*Employee
+	for _, e := range queryEmployees() {
+		e := e
+		pemployees = append(pemployees, &e)
+	}
+	got := FilterPointers(pemployees, MustParseQuery("sales >= 5").Match)
+	if len(got) != 2 || got[0] != pemployees[0] || got[1] != pemployees[2] {
+		t.Errorf("got %v, wanted ann and eve", got)
+	}
+}
+
+type queryErrorTest struct {
+	query  string
+	column int
+	msg    string
+}
+
+var queryErrorTests = []queryErrorTest{
+	{"salry > 5", 1, `unknown field "salry"`},
+	{"salary > 5 &&", 14, "expected a field, a number or a string, found end of query"},
+	{"salary 5", 8, `expected a comparison operator after "salary", found number 5`},
+	{"name > 5", 6, "cannot compare name (string) with 5 (number)"},
+	{"(sales > 1", 11, `expected ")" to close the "(" at column 1`},
+	{"sales > 1)", 10, `unexpected ")" after the expression`},
+	{"sales = 1", 7, `unknown operator "=", use "=="`},
+	{"name == 'ann", 9, "unterminated string"},
+	{"sales > 1.2.3", 9, `invalid number "1.2.3"`},
+	{"salary > .5", 10, `unexpected character '.'`},
+	{"sales > 1 & sales < 2", 11, `unexpected character '&'`},
+	{"", 1, "found end of query"},
+}
+
+func TestQueryErrors(t *testing.T) {
+	for _, test := range queryErrorTests {
+		_, err := ParseQuery(test.query)
+		var perr *ParseError
+		if !errors.As(err, &perr) {
+			t.Errorf("%q: got %v, wanted a *ParseError", test.query, err)
+			continue
+		}
+		assert(t, perr.Column, test.column)
+		if !strings.Contains(perr.Msg, test.msg) {
+			t.Errorf("%q: got %q, wanted it to contain %q", test.query, perr.Msg, test.msg)
+		}
+	}
+
+	_, err := ParseQuery("salry > 5")
+	want := "salry > 5\n^"
+	if got := err.(*ParseError).Caret(); got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
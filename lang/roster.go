@@ -0,0 +1,426 @@
+package lang
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/vrnvu/go-examples/lang/errs"
+	"github.com/vrnvu/go-examples/lang/money"
+)
+
+// RosterColumns is the schema of a roster, name, salary and sales are required
+// Amounts are decimals in the currency of the row, EUR when there is none
+var RosterColumns = []string{"name", "salary", "sales", "bonus", "currency"}
+
+var requiredColumns = []string{"name", "salary", "sales"}
+
+// Errors wrapped by RosterError, check them with errors.Is
+var (
+	ErrMissingColumn   = errors.New("missing column")
+	ErrMissingField    = errors.New("missing field")
+	ErrUnknownColumn   = errors.New("unknown column")
+	ErrDuplicateColumn = errors.New("duplicate column")
+	ErrEmptyEmployee   = errors.New("empty name")
+	ErrDuplicateName   = errors.New("duplicate name")
+	ErrNegative        = errors.New("negative value")
+	ErrUnknownFormat   = errors.New("unknown roster format")
+)
+
+// RosterError is a bad employee of a roster file
+// Line is the 1 based line of a CSV file, Record the 1 based employee,
+// the header is not a record
+type RosterError struct {
+	File   string
+	Line   int
+	Record int
+	Field  string
+	Value  string
+	Err    error
+}
+
+func (e *RosterError) Error() string {
+	pos := fmt.Sprintf("record %d", e.Record)
+	if e.Line > 0 {
+		pos = fmt.Sprintf("line %d", e.Line)
+	}
+	if e.File != "" {
+		pos = e.File + ": " + pos
+	}
+	if e.Field == "" {
+		return fmt.Sprintf("%s: %v", pos, e.Err)
+	}
+	return fmt.Sprintf("%s: %s %q: %v", pos, e.Field, e.Value, e.Err)
+}
+
+func (e *RosterError) Unwrap() error {
+	return e.Err
+}
+
+// rosterError reports e through errs, Duplicate for the duplicate names and
+// columns and Invalid otherwise, with its position as fields
+func rosterError(e *RosterError) error {
+	kind := errs.Invalid
+	if errors.Is(e.Err, ErrDuplicateName) || errors.Is(e.Err, ErrDuplicateColumn) {
+		kind = errs.Duplicate
+	}
+	return errs.Wrap(e, kind, "", "file", e.File, "line", e.Line, "record", e.Record, "field", e.Field)
+}
+
+// rosterRow is one employee before validation, missing fields are nil
+type rosterRow struct {
+	line, record int
+	fields       map[string]*string
+}
+
+// rosterLoader validates rows and remembers every error
+type rosterLoader struct {
+	file      string
+	employees []*Employee
+	seen      map[string]rosterRow
+	errs      errs.List
+}
+
+func (l *rosterLoader) fail(row rosterRow, field, value string, err error) {
+	l.errs.Add(rosterError(&RosterError{l.file, row.line, row.record, field, value, err}))
+}
+
+// add checks row against the schema, a bad row reports all its bad fields
+func (l *rosterLoader) add(row rosterRow) {
+	bad := l.errs.Len()
+	value := func(field string) (string, bool) {
+		v := row.fields[field]
+		if v == nil {
+			return "", false
+		}
+		return strings.TrimSpace(*v), true
+	}
+	for _, field := range requiredColumns {
+		if _, ok := value(field); !ok {
+			l.fail(row, field, "", ErrMissingField)
+		}
+	}
+
+	name, ok := value("name")
+	if ok && name == "" {
+		l.fail(row, "name", name, ErrEmptyEmployee)
+	}
+	currency := money.EUR
+	if code, _ := value("currency"); code != "" {
+		c, err := money.LookupCurrency(code)
+		if err != nil {
+			l.fail(row, "currency", code, err)
+		}
+		currency = c
+	}
+	amount := func(field string) money.Money {
+		text, ok := value(field)
+		if !ok || (field == "bonus" && text == "") {
+			return money.New(0, currency)
+		}
+		m, err := money.Parse(text, currency)
+		if err == nil && m.Sign() < 0 {
+			err = ErrNegative
+		}
+		if err != nil && currency.Code != "" {
+			l.fail(row, field, text, err)
+		}
+		return m
+	}
+	salary := amount("salary")
+	bonus := amount("bonus")
+	sales := 0
+	if text, ok := value("sales"); ok {
+		n, err := strconv.Atoi(text)
+		if err == nil && n < 0 {
+			err = ErrNegative
+		}
+		if err != nil {
+			l.fail(row, "sales", text, err)
+		}
+		sales = n
+	}
+
+	if first, ok := l.seen[name]; ok && name != "" {
+		pos := fmt.Sprintf("record %d", first.record)
+		if first.line > 0 {
+			pos = fmt.Sprintf("line %d", first.line)
+		}
+		l.fail(row, "name", name, fmt.Errorf("%w, first on %s", ErrDuplicateName, pos))
+	} else {
+		l.seen[name] = row
+	}
+	if l.errs.Len() == bad {
+		l.employees = append(l.employees, NewEmployee(name, salary, sales, bonus))
+	}
+}
+
+// result returns the employees, or every error found and no employee
+func (l *rosterLoader) result() ([]*Employee, error) {
+	if err := l.errs.Err(); err != nil {
+		return nil, err
+	}
+	return l.employees, nil
+}
+
+// LoadRosterCSV reads a CSV roster whose header names the columns, in any order
+// All the bad rows are reported at once in an *errs.Multi
+func LoadRosterCSV(r io.Reader, file string) ([]*Employee, error) {
+	l := &rosterLoader{file: file, seen: make(map[string]rosterRow)}
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, rosterError(&RosterError{File: file, Line: 1, Err: fmt.Errorf("%w: empty file", ErrMissingColumn)})
+	}
+	if err != nil {
+		return nil, rosterError(&RosterError{File: file, Line: 1, Err: err})
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch _, dup := columns[name]; {
+		case !isRosterColumn(name):
+			l.errs.Add(rosterError(&RosterError{File: file, Line: 1, Field: "header", Value: name, Err: ErrUnknownColumn}))
+		case dup:
+			l.errs.Add(rosterError(&RosterError{File: file, Line: 1, Field: "header", Value: name, Err: ErrDuplicateColumn}))
+		}
+		columns[name] = i
+	}
+	for _, name := range requiredColumns {
+		if _, ok := columns[name]; !ok {
+			l.errs.Add(rosterError(&RosterError{File: file, Line: 1, Field: "header", Value: name, Err: ErrMissingColumn}))
+		}
+	}
+	if l.errs.Len() > 0 {
+		return l.result()
+	}
+
+	for record := 1; ; record++ {
+		values, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// FieldPos panics after a failed Read, the line is in the error
+			line := 0
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				line = parseErr.Line
+			}
+			return nil, rosterError(&RosterError{File: file, Line: line, Record: record, Err: err})
+		}
+		line, _ := cr.FieldPos(0)
+		row := rosterRow{line: line, record: record, fields: make(map[string]*string)}
+		for name, i := range columns {
+			if i < len(values) {
+				row.fields[name] = &values[i]
+			}
+		}
+		l.add(row)
+	}
+	return l.result()
+}
+
+func isRosterColumn(name string) bool {
+	for _, column := range RosterColumns {
+		if column == name {
+			return true
+		}
+	}
+	return false
+}
+
+// rosterJSON is the JSON form of an employee, amounts are read as
+// json.Number so they stay exact, quoted amounts are accepted too
+type rosterJSON struct {
+	Name     *string      `json:"name"`
+	Salary   *json.Number `json:"salary"`
+	Sales    *json.Number `json:"sales"`
+	Bonus    *json.Number `json:"bonus,omitempty"`
+	Currency *string      `json:"currency,omitempty"`
+}
+
+// LoadRosterJSON reads a JSON array of employees, unknown fields are errors
+func LoadRosterJSON(r io.Reader, file string) ([]*Employee, error) {
+	l := &rosterLoader{file: file, seen: make(map[string]rosterRow)}
+	var raw []json.RawMessage
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, rosterError(&RosterError{File: file, Err: err})
+	}
+	for i, message := range raw {
+		row := rosterRow{record: i + 1, fields: make(map[string]*string)}
+		var e rosterJSON
+		d := json.NewDecoder(bytes.NewReader(message))
+		d.DisallowUnknownFields()
+		if err := d.Decode(&e); err != nil {
+			l.fail(row, "", "", err)
+			continue
+		}
+		row.fields["name"] = e.Name
+		row.fields["salary"] = (*string)(e.Salary)
+		row.fields["sales"] = (*string)(e.Sales)
+		row.fields["bonus"] = (*string)(e.Bonus)
+		row.fields["currency"] = e.Currency
+		l.add(row)
+	}
+	return l.result()
+}
+
+// rosterValues checks the currencies of e and returns its columns
+func rosterValues(e *Employee) ([]string, error) {
+	currency := e.salary.Currency()
+	if currency.Code == "" {
+		currency = e.bonus.Currency()
+	}
+	if c := e.bonus.Currency(); c.Code != "" && c != currency {
+		return nil, fmt.Errorf("%s: %w: salary %v, bonus %v", e.name, money.ErrCurrencyMismatch, e.salary, e.bonus)
+	}
+	// amounts of the zero Money are written in the currency of the row
+	amount := func(m money.Money) string {
+		if m.Currency().Code == "" {
+			return money.New(m.Minor(), currency).Amount()
+		}
+		return m.Amount()
+	}
+	return []string{e.name, amount(e.salary), strconv.Itoa(e.sales), amount(e.bonus), currency.Code}, nil
+}
+
+// SaveRosterCSV writes the employees with a RosterColumns header
+func SaveRosterCSV(w io.Writer, employees []*Employee) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(RosterColumns); err != nil {
+		return err
+	}
+	for _, e := range employees {
+		values, err := rosterValues(e)
+		if err != nil {
+			return err
+		}
+		if err := cw.Write(values); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// SaveRosterJSON writes the employees as an indented JSON array
+func SaveRosterJSON(w io.Writer, employees []*Employee) error {
+	rows := make([]rosterJSON, 0, len(employees))
+	for _, e := range employees {
+		values, err := rosterValues(e)
+		if err != nil {
+			return err
+		}
+		salary, sales, bonus := json.Number(values[1]), json.Number(values[2]), json.Number(values[3])
+		rows = append(rows, rosterJSON{&values[0], &salary, &sales, &bonus, &values[4]})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(rows)
+}
+
+// LoadRoster reads a .csv or .json roster file
+func LoadRoster(path string) ([]*Employee, error) {
+	load, err := loadRosterFunc(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return load(f, path)
+}
+
+// SaveRoster writes a .csv or .json roster file, the file is only replaced
+// once everything was written
+func SaveRoster(path string, employees []*Employee) error {
+	var save func(io.Writer, []*Employee) error
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		save = SaveRosterCSV
+	case ".json":
+		save = SaveRosterJSON
+	default:
+		return fmt.Errorf("%w: %s", ErrUnknownFormat, path)
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if err := f.Chmod(0o644); err != nil {
+		f.Close()
+		return err
+	}
+	if err := save(f, employees); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+func loadRosterFunc(path string) (func(io.Reader, string) ([]*Employee, error), error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return LoadRosterCSV, nil
+	case ".json":
+		return LoadRosterJSON, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownFormat, path)
+}
+
+//go:embed employees.csv
+var employeesCSV []byte
+
+// RosterMain loads a roster, employees.csv by default, and pays it
+// With -save the paid roster is written to a .csv or .json file
+func RosterMain(args []string) error {
+	flags := flag.NewFlagSet("Roster", flag.ContinueOnError)
+	save := flags.String("save", "", "write the paid roster to this .csv or .json file")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	var employees []*Employee
+	var err error
+	switch flags.NArg() {
+	case 0:
+		employees, err = LoadRosterCSV(bytes.NewReader(employeesCSV), "employees.csv")
+	case 1:
+		employees, err = LoadRoster(flags.Arg(0))
+	default:
+		return fmt.Errorf("expected one roster file, got %d", flags.NArg())
+	}
+	if err != nil {
+		return err
+	}
+
+	report, err := Payroll{Policy: FlatPercentage{Percent: BONUS_PERCENTAGE}}.Run(employees)
+	if err != nil {
+		return err
+	}
+	for _, slip := range report.Slips {
+		fmt.Printf("%-4s salary: %12v bonus: %12v total: %12v\n", slip.Name, slip.Salary, slip.Bonus, slip.Total)
+	}
+	if *save != "" {
+		return SaveRoster(*save, employees)
+	}
+	return nil
+}
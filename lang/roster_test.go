@@ -0,0 +1,185 @@
+package lang
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/vrnvu/go-examples/lang/errs"
+	"github.com/vrnvu/go-examples/lang/money"
+)
+
+// rosterString prints every field so two rosters can be compared
+func rosterString(employees []*Employee) string {
+	var b strings.Builder
+	for _, e := range employees {
+		fmt.Fprintf(&b, "%s %v %d %v\n", e.name, e.salary, e.sales, e.bonus)
+	}
+	return b.String()
+}
+
+func testRoster() []*Employee {
+	return []*Employee{
+		NewEmployee("ann", euros("5000"), 5, euros("500.25")),
+		NewEmployee("bob, jr", euros("6000.50"), 0, money.Money{}),
+		NewEmployee("kai", money.MustParse("750000", money.JPY), 3, money.Money{}),
+	}
+}
+
+const wantRoster = `ann 5000.00 EUR 5 500.25 EUR
+bob, jr 6000.50 EUR 0 0.00 EUR
+kai 750000 JPY 3 0 JPY
+`
+
+func TestRosterRoundTrip(t *testing.T) {
+	formats := []struct {
+		name string
+		save func(w *bytes.Buffer, employees []*Employee) error
+		load func(r *bytes.Buffer) ([]*Employee, error)
+	}{
+		{"csv",
+			func(w *bytes.Buffer, employees []*Employee) error { return SaveRosterCSV(w, employees) },
+			func(r *bytes.Buffer) ([]*Employee, error) { return LoadRosterCSV(r, "") }},
+		{"json",
+			func(w *bytes.Buffer, employees []*Employee) error { return SaveRosterJSON(w, employees) },
+			func(r *bytes.Buffer) ([]*Employee, error) { return LoadRosterJSON(r, "") }},
+	}
+	for _, format := range formats {
+		var buf bytes.Buffer
+		if err := format.save(&buf, testRoster()); err != nil {
+			t.Fatalf("%s: save returned %v", format.name, err)
+		}
+		saved := buf.String()
+		got, err := format.load(&buf)
+		if err != nil {
+			t.Fatalf("%s: load returned %v\n%s", format.name, err, saved)
+		}
+		if rosterString(got) != wantRoster {
+			t.Errorf("%s: got\n%swanted\n%s", format.name, rosterString(got), wantRoster)
+		}
+
+		buf.Reset()
+		if err := format.save(&buf, got); err != nil {
+			t.Fatalf("%s: second save returned %v", format.name, err)
+		}
+		if buf.String() != saved {
+			t.Errorf("%s: second save got\n%swanted\n%s", format.name, buf.String(), saved)
+		}
+	}
+}
+
+func TestRosterFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"roster.csv", "roster.json"} {
+		path := filepath.Join(dir, name)
+		if err := SaveRoster(path, testRoster()); err != nil {
+			t.Fatalf("SaveRoster(%s) returned %v", name, err)
+		}
+		got, err := LoadRoster(path)
+		if err != nil {
+			t.Fatalf("LoadRoster(%s) returned %v", name, err)
+		}
+		if rosterString(got) != wantRoster {
+			t.Errorf("%s: got\n%swanted\n%s", name, rosterString(got), wantRoster)
+		}
+	}
+	if err := SaveRoster(filepath.Join(dir, "roster.txt"), testRoster()); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("SaveRoster(roster.txt) got %v, wanted %v", err, ErrUnknownFormat)
+	}
+	matches, _ := filepath.Glob(filepath.Join(dir, "*.*.*"))
+	if len(matches) != 0 {
+		t.Errorf("temp files left behind: %v", matches)
+	}
+}
+
+func TestLoadRosterCSVOptionalColumns(t *testing.T) {
+	in := "Sales,Name,Salary\n5,ann,5000\n3,bob,6000.5\n"
+	got, err := LoadRosterCSV(strings.NewReader(in), "")
+	if err != nil {
+		t.Fatalf("LoadRosterCSV returned %v", err)
+	}
+	want := "ann 5000.00 EUR 5 0.00 EUR\nbob 6000.50 EUR 3 0.00 EUR\n"
+	if rosterString(got) != want {
+		t.Errorf("got\n%swanted\n%s", rosterString(got), want)
+	}
+}
+
+type rosterErrorTest struct {
+	name   string
+	in     string
+	errs   []error
+	output string
+}
+
+var rosterCSVErrorTests = []rosterErrorTest{
+	{"unknown column", "name,salary,sales,age\n", []error{ErrUnknownColumn}, `roster.csv: line 1: header "age": unknown column`},
+	{"missing column", "name,sales\n", []error{ErrMissingColumn}, `header "salary": missing column`},
+	{"duplicate column", "name,salary,sales,name\n", []error{ErrDuplicateColumn, errs.Duplicate}, `header "name": duplicate column`},
+	{"empty name", "name,salary,sales\n ,5000,5\n", []error{ErrEmptyEmployee}, `line 2: name "": empty name`},
+	{"negative", "name,salary,sales\nann,-5000,5\n", []error{ErrNegative, errs.Invalid}, `line 2: salary "-5000": negative value`},
+	{"bad amount", "name,salary,sales\nann,5000.001,5\n", []error{money.ErrSyntax}, `line 2: salary "5000.001"`},
+	{"bad sales", "name,salary,sales\nann,5000,five\n", []error{strconv.ErrSyntax}, `line 2: sales "five"`},
+	{"missing field", "name,salary,sales\nann,5000\n", []error{ErrMissingField}, `line 2: sales "": missing field`},
+	{"unknown currency", "name,salary,sales,currency\nann,5000,5,XXX\n", []error{money.ErrUnknownCurrency}, `line 2: currency "XXX"`},
+	{"duplicate name", "name,salary,sales\nann,5000,5\nbob,1,1\nann,6000,5\n", []error{ErrDuplicateName, errs.Duplicate}, `line 4: name "ann": duplicate name, first on line 2`},
+	{"malformed quote", "name,salary,sales\nann,5\"00,1\n", []error{csv.ErrBareQuote}, `roster.csv: line 2: `},
+	{"every row", "name,salary,sales\nann,-1,5\nbob,1,-1\n", []error{ErrNegative, errs.Invalid}, "line 2: salary \"-1\": negative value\nroster.csv: line 3: sales \"-1\": negative value"},
+}
+
+func checkRosterError(t *testing.T, test rosterErrorTest, got []*Employee, err error) {
+	t.Helper()
+	if err == nil {
+		t.Errorf("%s: got %d employees, wanted an error", test.name, len(got))
+		return
+	}
+	if got != nil {
+		t.Errorf("%s: got %d employees with an error", test.name, len(got))
+	}
+	for _, want := range test.errs {
+		if !errors.Is(err, want) {
+			t.Errorf("%s: got %v, wanted %v", test.name, err, want)
+		}
+	}
+	var rowErr *RosterError
+	if !errors.As(err, &rowErr) {
+		t.Errorf("%s: got %T, wanted a *RosterError", test.name, err)
+	}
+	if !strings.Contains(err.Error(), test.output) {
+		t.Errorf("%s: got %q, wanted it to contain %q", test.name, err, test.output)
+	}
+}
+
+func TestLoadRosterCSVErrors(t *testing.T) {
+	for _, test := range rosterCSVErrorTests {
+		got, err := LoadRosterCSV(strings.NewReader(test.in), "roster.csv")
+		checkRosterError(t, test, got, err)
+	}
+}
+
+var rosterJSONErrorTests = []rosterErrorTest{
+	{"unknown field", `[{"name":"ann","salary":5000,"sales":5,"age":30}]`, nil, `record 1: json: unknown field "age"`},
+	{"missing field", `[{"name":"ann","sales":5}]`, []error{ErrMissingField}, `record 1: salary "": missing field`},
+	{"quoted amount", `[{"name":"ann","salary":"-1","sales":5}]`, []error{ErrNegative}, `record 1: salary "-1": negative value`},
+	{"duplicate name", `[{"name":"ann","salary":1,"sales":1},{"name":"ann","salary":2,"sales":2}]`, []error{ErrDuplicateName, errs.Duplicate}, `record 2: name "ann": duplicate name, first on record 1`},
+	{"not an array", `{"name":"ann"}`, nil, `cannot unmarshal object`},
+}
+
+func TestLoadRosterJSONErrors(t *testing.T) {
+	for _, test := range rosterJSONErrorTests {
+		got, err := LoadRosterJSON(strings.NewReader(test.in), "roster.json")
+		checkRosterError(t, test, got, err)
+	}
+}
+
+func TestSaveRosterCurrencyMismatch(t *testing.T) {
+	e := NewEmployee("ann", euros("5000"), 5, money.MustFromUnits(1, money.USD))
+	var buf bytes.Buffer
+	if err := SaveRosterCSV(&buf, []*Employee{e}); !errors.Is(err, money.ErrCurrencyMismatch) {
+		t.Errorf("got %v, wanted %v", err, money.ErrCurrencyMismatch)
+	}
+}
@@ -0,0 +1,348 @@
+package sorting
+
+import (
+	"bufio"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Reader streams the values to sort, Read returns io.EOF after the last one
+// mapreduce.RecordReader is a Reader[mapreduce.Record]
+type Reader[T any] interface {
+	Read() (T, error)
+}
+
+// Encoder writes values to a run file
+type Encoder[T any] interface {
+	Encode(v T) error
+}
+
+// Decoder reads back the values of a run file, io.EOF after the last one
+type Decoder[T any] interface {
+	Decode() (T, error)
+}
+
+// Codec turns values into bytes for the runs written on disk
+type Codec[T any] interface {
+	NewEncoder(w io.Writer) Encoder[T]
+	NewDecoder(r io.Reader) Decoder[T]
+}
+
+// CodecFuncs builds a Codec from two functions
+type CodecFuncs[T any] struct {
+	Encoder func(w io.Writer) Encoder[T]
+	Decoder func(r io.Reader) Decoder[T]
+}
+
+func (c CodecFuncs[T]) NewEncoder(w io.Writer) Encoder[T] { return c.Encoder(w) }
+func (c CodecFuncs[T]) NewDecoder(r io.Reader) Decoder[T] { return c.Decoder(r) }
+
+type encoderFunc[T any] func(v T) error
+type decoderFunc[T any] func() (T, error)
+
+func (f encoderFunc[T]) Encode(v T) error   { return f(v) }
+func (f decoderFunc[T]) Decode() (T, error) { return f() }
+
+// Gob encodes the values with encoding/gob, the fields must be exported
+func Gob[T any]() Codec[T] {
+	return CodecFuncs[T]{
+		Encoder: func(w io.Writer) Encoder[T] {
+			enc := gob.NewEncoder(w)
+			return encoderFunc[T](func(v T) error { return enc.Encode(v) })
+		},
+		Decoder: func(r io.Reader) Decoder[T] {
+			dec := gob.NewDecoder(r)
+			return decoderFunc[T](func() (T, error) {
+				var v T
+				err := dec.Decode(&v)
+				return v, err
+			})
+		},
+	}
+}
+
+// JSON encodes one JSON value per line, the runs are readable when debugging
+func JSON[T any]() Codec[T] {
+	return CodecFuncs[T]{
+		Encoder: func(w io.Writer) Encoder[T] {
+			enc := json.NewEncoder(w)
+			return encoderFunc[T](func(v T) error { return enc.Encode(v) })
+		},
+		Decoder: func(r io.Reader) Decoder[T] {
+			dec := json.NewDecoder(r)
+			return decoderFunc[T](func() (T, error) {
+				var v T
+				err := dec.Decode(&v)
+				return v, err
+			})
+		},
+	}
+}
+
+// Lines stores strings one per line, they must not contain a newline
+func Lines() Codec[string] {
+	return CodecFuncs[string]{
+		Encoder: func(w io.Writer) Encoder[string] {
+			return encoderFunc[string](func(s string) error {
+				if strings.Contains(s, "\n") {
+					return fmt.Errorf("sorting: Lines codec value %q has a newline", s)
+				}
+				_, err := io.WriteString(w, s+"\n")
+				return err
+			})
+		},
+		Decoder: func(r io.Reader) Decoder[string] {
+			sc := bufio.NewScanner(r)
+			return decoderFunc[string](func() (string, error) {
+				if sc.Scan() {
+					return sc.Text(), nil
+				}
+				if err := sc.Err(); err != nil {
+					return "", err
+				}
+				return "", io.EOF
+			})
+		},
+	}
+}
+
+// ExternalOptions configures External, the zero value is usable
+type ExternalOptions struct {
+	// RunSize is the number of values sorted in memory at once, 65536 by default
+	RunSize int
+	// FanIn is the number of runs merged at once, so the number of open
+	// files, 64 by default. More runs are merged in several passes
+	FanIn int
+	// TempDir is where the runs are written, os.TempDir by default
+	TempDir string
+}
+
+// Sorted streams the output of External, Close removes its temp files
+type Sorted[T any] struct {
+	// Runs is the number of runs written on disk, 0 when it fit in memory
+	Runs int
+
+	next  func() (T, bool)
+	stop  func()
+	err   *error
+	dir   string
+	files []*os.File
+}
+
+// Read returns the next value in order, io.EOF after the last one
+// A run that cannot be decoded is an error, the values after it are lost
+func (s *Sorted[T]) Read() (T, error) {
+	v, ok := s.next()
+	if *s.err != nil {
+		var zero T
+		return zero, *s.err
+	}
+	if !ok {
+		return v, io.EOF
+	}
+	return v, nil
+}
+
+// Close releases the files and removes the runs, it is safe to call twice
+func (s *Sorted[T]) Close() error {
+	s.stop()
+	var errs []error
+	for _, f := range s.files {
+		errs = append(errs, f.Close())
+	}
+	s.files = nil
+	if s.dir != "" {
+		errs = append(errs, os.RemoveAll(s.dir))
+		s.dir = ""
+	}
+	return errors.Join(errs...)
+}
+
+// External sorts more values than fit in memory. It reads RunSize values
+// at a time, sorts them stably and writes them as a run file with codec,
+// then merges the runs with a k-way heap merge. Equal values keep their
+// input order. When every value fits in one run nothing touches the disk
+// On error the temp files are removed before returning
+func External[T any](r Reader[T], c Comparator[T], codec Codec[T], opts ExternalOptions) (*Sorted[T], error) {
+	if opts.RunSize <= 0 {
+		opts.RunSize = 1 << 16
+	}
+	if opts.FanIn < 2 {
+		opts.FanIn = 64
+	}
+	x := &external[T]{c: c, codec: codec, opts: opts}
+	sorted, err := x.sort(r)
+	if err != nil {
+		x.cleanup()
+		return nil, err
+	}
+	return sorted, nil
+}
+
+type external[T any] struct {
+	c     Comparator[T]
+	codec Codec[T]
+	opts  ExternalOptions
+	dir   string
+	runs  []string
+	count int
+}
+
+func (x *external[T]) sort(r Reader[T]) (*Sorted[T], error) {
+	buf := make([]T, 0, min(x.opts.RunSize, 1024))
+	for {
+		v, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if buf = append(buf, v); len(buf) == x.opts.RunSize {
+			if err := x.addRun(buf); err != nil {
+				return nil, err
+			}
+			buf = buf[:0]
+		}
+	}
+
+	if len(x.runs) == 0 {
+		var noErr error
+		slices.SortStableFunc(buf, x.c)
+		next, stop := iter.Pull(slices.Values(buf))
+		return &Sorted[T]{next: next, stop: stop, err: &noErr}, nil
+	}
+	if len(buf) > 0 {
+		if err := x.addRun(buf); err != nil {
+			return nil, err
+		}
+	}
+	written := len(x.runs)
+
+	// merge passes until the last merge opens at most FanIn files
+	for len(x.runs) > x.opts.FanIn {
+		var merged []string
+		for start := 0; start < len(x.runs); start += x.opts.FanIn {
+			group := x.runs[start:min(start+x.opts.FanIn, len(x.runs))]
+			seq, files, errp, err := x.open(group)
+			if err != nil {
+				return nil, err
+			}
+			path, err := x.writeRun(seq)
+			for _, f := range files {
+				f.Close()
+			}
+			if err == nil {
+				err = *errp
+			}
+			if err != nil {
+				return nil, err
+			}
+			merged = append(merged, path)
+			for _, path := range group {
+				os.Remove(path)
+			}
+		}
+		x.runs = merged
+	}
+
+	seq, files, errp, err := x.open(x.runs)
+	if err != nil {
+		return nil, err
+	}
+	next, stop := iter.Pull(seq)
+	return &Sorted[T]{Runs: written, next: next, stop: stop, err: errp, dir: x.dir, files: files}, nil
+}
+
+// addRun sorts buf and writes it as a new run
+func (x *external[T]) addRun(buf []T) error {
+	slices.SortStableFunc(buf, x.c)
+	path, err := x.writeRun(slices.Values(buf))
+	if err != nil {
+		return err
+	}
+	x.runs = append(x.runs, path)
+	return nil
+}
+
+// writeRun writes seq to a new run file in the temp dir of the sort
+func (x *external[T]) writeRun(seq iter.Seq[T]) (string, error) {
+	if x.dir == "" {
+		dir, err := os.MkdirTemp(x.opts.TempDir, "extsort-")
+		if err != nil {
+			return "", err
+		}
+		x.dir = dir
+	}
+	path := filepath.Join(x.dir, fmt.Sprintf("run-%06d", x.count))
+	x.count++
+	f, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	w := bufio.NewWriter(f)
+	enc := x.codec.NewEncoder(w)
+	for v := range seq {
+		if err = enc.Encode(v); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return path, err
+}
+
+// open merges the runs at paths, the first decoding error stops the merge
+// and is stored in the returned error pointer
+func (x *external[T]) open(paths []string) (iter.Seq[T], []*os.File, *error, error) {
+	decodeErr := new(error)
+	var files []*os.File
+	var seqs []iter.Seq[T]
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			for _, f := range files {
+				f.Close()
+			}
+			return nil, nil, nil, err
+		}
+		files = append(files, f)
+		dec := x.codec.NewDecoder(bufio.NewReader(f))
+		seqs = append(seqs, func(yield func(T) bool) {
+			for {
+				v, err := dec.Decode()
+				if err == io.EOF {
+					return
+				}
+				if err != nil {
+					if *decodeErr == nil {
+						*decodeErr = fmt.Errorf("sorting: run %s: %w", filepath.Base(path), err)
+					}
+					return
+				}
+				if !yield(v) {
+					return
+				}
+			}
+		})
+	}
+	return Merge(x.c, seqs...), files, decodeErr, nil
+}
+
+func (x *external[T]) cleanup() {
+	if x.dir != "" {
+		os.RemoveAll(x.dir)
+	}
+}
@@ -0,0 +1,162 @@
+package sorting
+
+import (
+	"errors"
+	"io"
+	"math/rand"
+	"os"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+// sliceReader reads xs then fails with err, io.EOF when err is nil
+type sliceReader[T any] struct {
+	xs  []T
+	err error
+}
+
+func (r *sliceReader[T]) Read() (T, error) {
+	var zero T
+	if len(r.xs) == 0 {
+		if r.err != nil {
+			return zero, r.err
+		}
+		return zero, io.EOF
+	}
+	v := r.xs[0]
+	r.xs = r.xs[1:]
+	return v, nil
+}
+
+func readAll[T any](t *testing.T, s *Sorted[T]) []T {
+	t.Helper()
+	var got []T
+	for {
+		v, err := s.Read()
+		if err == io.EOF {
+			return got
+		}
+		if err != nil {
+			t.Fatalf("Read returned %v", err)
+		}
+		got = append(got, v)
+	}
+}
+
+func assertEmptyDir(t *testing.T, dir string) {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("%d temp files left in %s", len(entries), dir)
+	}
+}
+
+// Item has an exported field so the gob codec works
+type Item struct {
+	Key, Seq int
+}
+
+func TestExternal(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	tests := []struct {
+		name     string
+		n        int
+		opts     ExternalOptions
+		codec    Codec[Item]
+		wantRuns int
+	}{
+		{"in memory", 50, ExternalOptions{RunSize: 100}, Gob[Item](), 0},
+		{"one pass", 100, ExternalOptions{RunSize: 30}, Gob[Item](), 4},
+		{"several passes", 500, ExternalOptions{RunSize: 7, FanIn: 3}, JSON[Item](), 72},
+		{"empty", 0, ExternalOptions{RunSize: 2}, Gob[Item](), 0},
+	}
+	for _, test := range tests {
+		dir := t.TempDir()
+		test.opts.TempDir = dir
+		items := make([]Item, test.n)
+		for i := range items {
+			items[i] = Item{r.Intn(test.n/4 + 1), i}
+		}
+		s, err := External[Item](&sliceReader[Item]{xs: slices.Clone(items)}, By(func(i Item) int { return i.Key }), test.codec, test.opts)
+		if err != nil {
+			t.Fatalf("%s: External returned %v", test.name, err)
+		}
+		got := readAll(t, s)
+		if s.Runs != test.wantRuns {
+			t.Errorf("%s: got %d runs, wanted %d", test.name, s.Runs, test.wantRuns)
+		}
+		if err := s.Close(); err != nil {
+			t.Errorf("%s: Close returned %v", test.name, err)
+		}
+		assertEmptyDir(t, dir)
+
+		// stable, equal keys keep the input order given by Seq
+		slices.SortStableFunc(items, func(a, b Item) int { return a.Key - b.Key })
+		if !slices.Equal(got, items) {
+			t.Errorf("%s: got %v, wanted %v", test.name, got, items)
+		}
+	}
+}
+
+func TestExternalCleanupOnError(t *testing.T) {
+	dir := t.TempDir()
+	broken := errors.New("broken input")
+	words := []string{"d", "c", "b", "a", "e"}
+	_, err := External[string](&sliceReader[string]{xs: words, err: broken}, Natural[string](), Lines(), ExternalOptions{RunSize: 2, TempDir: dir})
+	if !errors.Is(err, broken) {
+		t.Errorf("got %v, wanted %v", err, broken)
+	}
+	assertEmptyDir(t, dir)
+
+	_, err = External[string](&sliceReader[string]{xs: []string{"a\nb", "c"}}, Natural[string](), Lines(), ExternalOptions{RunSize: 1, TempDir: dir})
+	if err == nil {
+		t.Errorf("a newline in the Lines codec got no error")
+	}
+	assertEmptyDir(t, dir)
+}
+
+func TestExternalDecodeError(t *testing.T) {
+	dir := t.TempDir()
+	bad := errors.New("bad run")
+	// a codec whose decoder fails on the value 13
+	codec := CodecFuncs[string]{
+		Encoder: Lines().NewEncoder,
+		Decoder: func(r io.Reader) Decoder[string] {
+			dec := Lines().NewDecoder(r)
+			return decoderFunc[string](func() (string, error) {
+				s, err := dec.Decode()
+				if s == "13" {
+					return "", bad
+				}
+				return s, err
+			})
+		},
+	}
+	var xs []string
+	for i := 20; i > 0; i-- {
+		xs = append(xs, strconv.Itoa(i))
+	}
+	s, err := External[string](&sliceReader[string]{xs: xs}, By(func(s string) int { n, _ := strconv.Atoi(s); return n }), codec, ExternalOptions{RunSize: 5, TempDir: dir})
+	if err != nil {
+		t.Fatalf("External returned %v", err)
+	}
+	defer s.Close()
+	var got []string
+	for {
+		v, err := s.Read()
+		if err != nil {
+			if !errors.Is(err, bad) {
+				t.Errorf("got %v, wanted %v", err, bad)
+			}
+			break
+		}
+		got = append(got, v)
+	}
+	if len(got) > 12 {
+		t.Errorf("read %v past the bad run", got)
+	}
+}
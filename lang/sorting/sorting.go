@@ -0,0 +1,223 @@
+// Package sorting replaces the sort.Interface types of SortingBy, like
+// byLength, with comparators that are built once and combined
+//
+//	sorting.Sort(employees, sorting.ByCmp(Employee.Salary, money.Money.Cmp).Reversed().
+//		ThenBy(sorting.By(Employee.Name)))
+//
+// A Comparator returns a negative number when a sorts before b, zero when
+// they are equal and a positive number otherwise, like cmp.Compare
+package sorting
+
+import (
+	"cmp"
+	"container/heap"
+	"iter"
+	"slices"
+)
+
+// Comparator orders two values of T
+type Comparator[T any] func(a, b T) int
+
+// By orders by an ordered key, By(func(s string) int { return len(s) }) is byLength
+func By[T any, K cmp.Ordered](key func(T) K) Comparator[T] {
+	return func(a, b T) int {
+		return cmp.Compare(key(a), key(b))
+	}
+}
+
+// ByCmp orders by a key compared with compare, for keys like money.Money
+func ByCmp[T, K any](key func(T) K, compare func(K, K) int) Comparator[T] {
+	return func(a, b T) int {
+		return compare(key(a), key(b))
+	}
+}
+
+// Natural orders ordered values ascending
+func Natural[T cmp.Ordered]() Comparator[T] {
+	return cmp.Compare[T]
+}
+
+// Reversed orders the other way around
+func (c Comparator[T]) Reversed() Comparator[T] {
+	return func(a, b T) int {
+		return c(b, a)
+	}
+}
+
+// ThenBy breaks the ties of c with next
+func (c Comparator[T]) ThenBy(next Comparator[T]) Comparator[T] {
+	return func(a, b T) int {
+		if r := c(a, b); r != 0 {
+			return r
+		}
+		return next(a, b)
+	}
+}
+
+// NullsFirst orders pointers, nil before anything else and the rest with c
+func NullsFirst[T any](c Comparator[T]) Comparator[*T] {
+	return nulls(c, -1)
+}
+
+// NullsLast orders pointers, nil after anything else and the rest with c
+func NullsLast[T any](c Comparator[T]) Comparator[*T] {
+	return nulls(c, 1)
+}
+
+func nulls[T any](c Comparator[T], nilOrder int) Comparator[*T] {
+	return func(a, b *T) int {
+		switch {
+		case a == nil && b == nil:
+			return 0
+		case a == nil:
+			return nilOrder
+		case b == nil:
+			return -nilOrder
+		}
+		return c(*a, *b)
+	}
+}
+
+// Sort sorts xs in place, equal elements can be reordered
+func Sort[T any](xs []T, c Comparator[T]) {
+	slices.SortFunc(xs, c)
+}
+
+// SortStable sorts xs in place keeping the order of equal elements
+func SortStable[T any](xs []T, c Comparator[T]) {
+	slices.SortStableFunc(xs, c)
+}
+
+// IsSorted reports whether xs is sorted by c
+func IsSorted[T any](xs []T, c Comparator[T]) bool {
+	return slices.IsSortedFunc(xs, c)
+}
+
+// TopK returns the k first elements of xs in the order of c, without
+// sorting all of xs. It keeps a heap of k elements, O(n log k)
+func TopK[T any](xs []T, k int, c Comparator[T]) []T {
+	if k <= 0 {
+		return []T{}
+	}
+	// max heap of the k best so far, the root is the worst of them
+	h := &sliceHeap[T]{less: func(a, b T) bool { return c(a, b) > 0 }}
+	for _, x := range xs {
+		if h.Len() < k {
+			heap.Push(h, x)
+		} else if c(x, h.items[0]) < 0 {
+			h.items[0] = x
+			heap.Fix(h, 0)
+		}
+	}
+	top := h.items
+	slices.SortStableFunc(top, c)
+	return top
+}
+
+// PartialSort rearranges xs so xs[:k] are the k first elements in order,
+// the rest of xs is left in no particular order
+func PartialSort[T any](xs []T, k int, c Comparator[T]) {
+	k = min(max(k, 0), len(xs))
+	// quickselect the element at k, everything before it ends up smaller
+	lo, hi := 0, len(xs)-1
+	for lo < hi && k < len(xs) {
+		lt, gt := partition(xs, lo, hi, c)
+		switch {
+		case k < lt:
+			hi = lt - 1
+		case k > gt:
+			lo = gt + 1
+		default:
+			lo = hi
+		}
+	}
+	slices.SortFunc(xs[:k], c)
+}
+
+// partition is a three way partition around the middle element, so runs of
+// equal elements do not make quickselect quadratic
+// After it xs[lo:lt] < pivot, xs[lt:gt+1] == pivot and xs[gt+1:hi+1] > pivot
+func partition[T any](xs []T, lo, hi int, c Comparator[T]) (lt, gt int) {
+	pivot := xs[lo+(hi-lo)/2]
+	lt, i, gt := lo, lo, hi
+	for i <= gt {
+		switch r := c(xs[i], pivot); {
+		case r < 0:
+			xs[lt], xs[i] = xs[i], xs[lt]
+			lt++
+			i++
+		case r > 0:
+			xs[i], xs[gt] = xs[gt], xs[i]
+			gt--
+		default:
+			i++
+		}
+	}
+	return lt, gt
+}
+
+// Merge merges runs already sorted by c into one sorted stream with a
+// k-way heap merge, equal elements come out in the order of the runs
+func Merge[T any](c Comparator[T], runs ...iter.Seq[T]) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		type head struct {
+			value T
+			run   int
+			next  func() (T, bool)
+		}
+		h := &sliceHeap[head]{less: func(a, b head) bool {
+			if r := c(a.value, b.value); r != 0 {
+				return r < 0
+			}
+			return a.run < b.run
+		}}
+		for i, run := range runs {
+			next, stop := iter.Pull(run)
+			defer stop()
+			if v, ok := next(); ok {
+				h.items = append(h.items, head{v, i, next})
+			}
+		}
+		heap.Init(h)
+		for h.Len() > 0 {
+			top := h.items[0]
+			if !yield(top.value) {
+				return
+			}
+			if v, ok := top.next(); ok {
+				h.items[0].value = v
+				heap.Fix(h, 0)
+			} else {
+				heap.Pop(h)
+			}
+		}
+	}
+}
+
+// MergeSorted merges slices already sorted by c into a new sorted slice
+func MergeSorted[T any](c Comparator[T], runs ...[]T) []T {
+	seqs := make([]iter.Seq[T], len(runs))
+	n := 0
+	for i, run := range runs {
+		seqs[i] = slices.Values(run)
+		n += len(run)
+	}
+	return slices.AppendSeq(make([]T, 0, n), Merge(c, seqs...))
+}
+
+// sliceHeap is a container/heap over a slice ordered by less
+type sliceHeap[T any] struct {
+	items []T
+	less  func(a, b T) bool
+}
+
+func (h *sliceHeap[T]) Len() int           { return len(h.items) }
+func (h *sliceHeap[T]) Less(i, j int) bool { return h.less(h.items[i], h.items[j]) }
+func (h *sliceHeap[T]) Swap(i, j int)      { h.items[i], h.items[j] = h.items[j], h.items[i] }
+func (h *sliceHeap[T]) Push(x any)         { h.items = append(h.items, x.(T)) }
+
+func (h *sliceHeap[T]) Pop() any {
+	last := h.items[len(h.items)-1]
+	h.items = h.items[:len(h.items)-1]
+	return last
+}
@@ -0,0 +1,146 @@
+package sorting
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+type person struct {
+	name string
+	age  int
+}
+
+var people = []person{{"eve", 30}, {"ann", 25}, {"bob", 30}, {"kai", 20}, {"al", 25}}
+
+func byAge(p person) int     { return p.age }
+func byName(p person) string { return p.name }
+
+func TestComparators(t *testing.T) {
+	tests := []struct {
+		name     string
+		c        Comparator[person]
+		expected string
+	}{
+		{"by age", By(byAge), "[{kai 20} {ann 25} {al 25} {eve 30} {bob 30}]"},
+		{"reversed", By(byAge).Reversed(), "[{eve 30} {bob 30} {ann 25} {al 25} {kai 20}]"},
+		{"then by", By(byAge).Reversed().ThenBy(By(byName)), "[{bob 30} {eve 30} {al 25} {ann 25} {kai 20}]"},
+		{"by cmp", ByCmp(byName, func(a, b string) int { return len(a) - len(b) }), "[{al 25} {eve 30} {ann 25} {bob 30} {kai 20}]"},
+	}
+	for _, test := range tests {
+		xs := slices.Clone(people)
+		SortStable(xs, test.c)
+		if got := fmt.Sprint(xs); got != test.expected {
+			t.Errorf("%s: got %s, wanted %s", test.name, got, test.expected)
+		}
+		if !IsSorted(xs, test.c) {
+			t.Errorf("%s: IsSorted got false", test.name)
+		}
+	}
+
+	words := strings.Fields("pear fig apple kiwi")
+	Sort(words, Natural[string]())
+	if got := fmt.Sprint(words); got != "[apple fig kiwi pear]" {
+		t.Errorf("natural got %s", got)
+	}
+}
+
+func TestNulls(t *testing.T) {
+	one, two := 1, 2
+	xs := []*int{&two, nil, &one, nil}
+	format := func(xs []*int) string {
+		var parts []string
+		for _, x := range xs {
+			if x == nil {
+				parts = append(parts, "nil")
+			} else {
+				parts = append(parts, fmt.Sprint(*x))
+			}
+		}
+		return strings.Join(parts, " ")
+	}
+	SortStable(xs, NullsFirst(Natural[int]()))
+	if got := format(xs); got != "nil nil 1 2" {
+		t.Errorf("nulls first got %s", got)
+	}
+	SortStable(xs, NullsLast(Natural[int]().Reversed()))
+	if got := format(xs); got != "2 1 nil nil" {
+		t.Errorf("nulls last got %s", got)
+	}
+}
+
+// randomInts has many duplicates so the three way partition is exercised
+func randomInts(r *rand.Rand, n int) []int {
+	xs := make([]int, n)
+	for i := range xs {
+		xs[i] = r.Intn(n/3 + 1)
+	}
+	return xs
+}
+
+func TestTopKAndPartialSort(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for trial := 0; trial < 200; trial++ {
+		xs := randomInts(r, r.Intn(50))
+		k := r.Intn(len(xs) + 3)
+		sorted := slices.Clone(xs)
+		slices.Sort(sorted)
+		want := sorted[:min(k, len(sorted))]
+
+		if got := TopK(xs, k, Natural[int]()); !slices.Equal(got, want) {
+			t.Fatalf("TopK(%v, %d) got %v, wanted %v", xs, k, got, want)
+		}
+		if got := TopK(xs, k, Natural[int]().Reversed()); len(got) != len(want) || !IsSorted(got, Natural[int]().Reversed()) {
+			t.Fatalf("TopK reversed (%v, %d) got %v", xs, k, got)
+		}
+
+		partial := slices.Clone(xs)
+		PartialSort(partial, k, Natural[int]())
+		if !slices.Equal(partial[:len(want)], want) {
+			t.Fatalf("PartialSort(%v, %d) got %v, wanted prefix %v", xs, k, partial, want)
+		}
+		slices.Sort(partial)
+		if !slices.Equal(partial, sorted) {
+			t.Fatalf("PartialSort(%v, %d) lost elements: %v", xs, k, partial)
+		}
+	}
+	if got := TopK([]int{3, 1}, 0, Natural[int]()); len(got) != 0 {
+		t.Errorf("TopK 0 got %v", got)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	got := MergeSorted(By(byAge), []person{{"kai", 20}, {"ann", 25}}, nil,
+		[]person{{"al", 25}, {"eve", 30}}, []person{{"bob", 19}})
+	want := "[{bob 19} {kai 20} {ann 25} {al 25} {eve 30}]"
+	if fmt.Sprint(got) != want {
+		t.Errorf("MergeSorted got %v, wanted %s", got, want)
+	}
+
+	r := rand.New(rand.NewSource(2))
+	var runs [][]int
+	var all []int
+	for i := 0; i < 7; i++ {
+		run := randomInts(r, r.Intn(20))
+		slices.Sort(run)
+		runs = append(runs, run)
+		all = append(all, run...)
+	}
+	slices.Sort(all)
+	if merged := MergeSorted(Natural[int](), runs...); !reflect.DeepEqual(merged, all) {
+		t.Errorf("MergeSorted got %v, wanted %v", merged, all)
+	}
+
+	var first []int
+	for v := range Merge(Natural[int](), slices.Values([]int{1, 4}), slices.Values([]int{2, 3})) {
+		if first = append(first, v); len(first) == 2 {
+			break
+		}
+	}
+	if !slices.Equal(first, []int{1, 2}) {
+		t.Errorf("Merge with break got %v", first)
+	}
+}
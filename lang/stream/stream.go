@@ -0,0 +1,287 @@
+// Package stream is a lazy pipeline over iter.Seq
+//
+// Nothing runs until the final seq is ranged over, and every stage stops
+// pulling from the previous one as soon as its consumer stops, so
+//
+//	stream.Take(stream.Filter(stream.FileLines(path)...), 10)
+//
+// reads the file only until ten lines matched. Stages are plain functions
+// because methods cannot have type parameters
+package stream
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"encoding/csv"
+	"io"
+	"iter"
+	"os"
+	"runtime"
+	"slices"
+	"sync"
+
+	"github.com/vrnvu/go-examples/lang/collections"
+)
+
+// Of streams its arguments
+func Of[T any](xs ...T) iter.Seq[T] {
+	return FromSlice(xs)
+}
+
+// FromSlice streams the elements of xs
+func FromSlice[T any](xs []T) iter.Seq[T] {
+	return slices.Values(xs)
+}
+
+// FromMap streams the entries of m sorted by key
+func FromMap[K cmp.Ordered, V any](m map[K]V) iter.Seq[collections.Pair[K, V]] {
+	return func(yield func(collections.Pair[K, V]) bool) {
+		for _, k := range collections.Keys(m) {
+			if !yield(collections.Pair[K, V]{First: k, Second: m[k]}) {
+				return
+			}
+		}
+	}
+}
+
+// FromChan streams what is received from ch until it is closed, like
+// RangeOverChannels. Values are left in ch when the consumer stops early
+func FromChan[T any](ch <-chan T) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for v := range ch {
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// Iterate streams seed, f(seed), f(f(seed))... forever, use Take to stop it
+func Iterate[T any](seed T, f func(T) T) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for v := seed; yield(v); v = f(v) {
+		}
+	}
+}
+
+// Lines streams the lines of r without their line ending
+// The error of the reader is returned by err once the stream ended
+func Lines(r io.Reader) (seq iter.Seq[string], err func() error) {
+	var scanErr error
+	seq = func(yield func(string) bool) {
+		sc := bufio.NewScanner(r)
+		for sc.Scan() {
+			if !yield(sc.Text()) {
+				return
+			}
+		}
+		scanErr = sc.Err()
+	}
+	return seq, func() error { return scanErr }
+}
+
+// Records streams the CSV records of r, the first error of the reader,
+// like a malformed quote, ends the stream and is returned by err
+func Records(r io.Reader) (seq iter.Seq[[]string], err func() error) {
+	var readErr error
+	seq = func(yield func([]string) bool) {
+		cr := csv.NewReader(r)
+		for {
+			record, err := cr.Read()
+			if err != nil {
+				if err != io.EOF {
+					readErr = err
+				}
+				return
+			}
+			if !yield(record) {
+				return
+			}
+		}
+	}
+	return seq, func() error { return readErr }
+}
+
+// FileLines is Lines of the file at path, the file is opened every time the
+// stream is ranged over and closed when it ends or the consumer stops
+func FileLines(path string) (seq iter.Seq[string], err func() error) {
+	var fileErr error
+	seq = func(yield func(string) bool) {
+		f, err := os.Open(path)
+		if err != nil {
+			fileErr = err
+			return
+		}
+		defer f.Close()
+		lines, linesErr := Lines(f)
+		for line := range lines {
+			if !yield(line) {
+				return
+			}
+		}
+		fileErr = linesErr()
+	}
+	return seq, func() error { return fileErr }
+}
+
+// Map streams f of every element
+func Map[T, U any](seq iter.Seq[T], f func(T) U) iter.Seq[U] {
+	return func(yield func(U) bool) {
+		for v := range seq {
+			if !yield(f(v)) {
+				return
+			}
+		}
+	}
+}
+
+// Filter streams the elements keep returns true for
+func Filter[T any](seq iter.Seq[T], keep func(T) bool) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for v := range seq {
+			if keep(v) && !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// FlatMap streams every element of f of every element
+func FlatMap[T, U any](seq iter.Seq[T], f func(T) iter.Seq[U]) iter.Seq[U] {
+	return func(yield func(U) bool) {
+		for v := range seq {
+			for u := range f(v) {
+				if !yield(u) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Take streams the first n elements, seq is not pulled once they are out
+func Take[T any](seq iter.Seq[T], n int) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		if n <= 0 {
+			return
+		}
+		taken := 0
+		for v := range seq {
+			if !yield(v) {
+				return
+			}
+			if taken++; taken == n {
+				return
+			}
+		}
+	}
+}
+
+// Skip drops the first n elements
+func Skip[T any](seq iter.Seq[T], n int) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		skipped := 0
+		for v := range seq {
+			if skipped < n {
+				skipped++
+				continue
+			}
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// Reduce folds the whole stream starting with init
+func Reduce[T, A any](seq iter.Seq[T], init A, f func(A, T) A) A {
+	acc := init
+	for v := range seq {
+		acc = f(acc, v)
+	}
+	return acc
+}
+
+// Collect returns the elements of a finite stream
+func Collect[T any](seq iter.Seq[T]) []T {
+	return slices.Collect(seq)
+}
+
+// Handler is the work Parallel does on every element
+type Handler[T, U any] func(ctx context.Context, v T) (U, error)
+
+// Parallel is ParallelN with GOMAXPROCS workers
+func Parallel[T, U any](ctx context.Context, seq iter.Seq[T], handler Handler[T, U]) iter.Seq2[U, error] {
+	return ParallelN(ctx, seq, runtime.GOMAXPROCS(0), handler)
+}
+
+// ParallelN runs handler over the elements with n workers and streams the
+// results in input order, each one with the error of its handler
+// seq is pulled from another goroutine, at most n elements ahead of the
+// consumer. When the consumer stops the context of the handlers is
+// cancelled and ParallelN returns once every worker exited, a seq blocked
+// in its source, like FromChan of a channel nobody sends to, delays that
+// return
+func ParallelN[T, U any](ctx context.Context, seq iter.Seq[T], n int, handler Handler[T, U]) iter.Seq2[U, error] {
+	type result struct {
+		v   U
+		err error
+	}
+	type job struct {
+		v    T
+		slot chan result
+	}
+	n = max(n, 1)
+	return func(yield func(U, error) bool) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		// every job has a slot for its result, slots keeps them in input
+		// order and its buffer bounds how far the feeder runs ahead
+		jobs := make(chan job)
+		slots := make(chan chan result, n)
+		go func() {
+			defer close(slots)
+			defer close(jobs)
+			for v := range seq {
+				j := job{v: v, slot: make(chan result, 1)}
+				select {
+				case jobs <- j:
+				case <-ctx.Done():
+					return
+				}
+				select {
+				case slots <- j.slot:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+
+		var wg sync.WaitGroup
+		for range n {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := range jobs {
+					// the slot is buffered, a worker never waits for the consumer
+					v, err := handler(ctx, j.v)
+					j.slot <- result{v, err}
+				}
+			}()
+		}
+		defer wg.Wait()
+
+		for slot := range slots {
+			r := <-slot
+			if !yield(r.v, r.err) {
+				cancel()
+				// drained so the feeder is not blocked
+				for range slots {
+				}
+				return
+			}
+		}
+	}
+}
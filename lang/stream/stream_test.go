@@ -0,0 +1,206 @@
+package stream
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"iter"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/vrnvu/go-examples/lang/collections"
+)
+
+func check(t *testing.T, name string, got, want interface{}) {
+	t.Helper()
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("%s: got %v, wanted %v", name, got, want)
+	}
+}
+
+// counted counts how many elements were pulled from seq
+func counted[T any](seq iter.Seq[T], pulled *int) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for v := range seq {
+			*pulled++
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+func naturals() iter.Seq[int] {
+	return Iterate(1, func(n int) int { return n + 1 })
+}
+
+func isEven(n int) bool { return n%2 == 0 }
+
+func TestStages(t *testing.T) {
+	tests := []struct {
+		name      string
+		got, want []string
+	}{
+		{"map", Collect(Map(Of(1, 2, 3), strconv.Itoa)), []string{"1", "2", "3"}},
+		{"filter", Collect(Map(Filter(Take(naturals(), 6), isEven), strconv.Itoa)), []string{"2", "4", "6"}},
+		{"skip", Collect(Skip(Of("a", "b", "c"), 2)), []string{"c"}},
+		{"skip all", Collect(Skip(Of("a", "b"), 5)), nil},
+		{"take 0", Collect(Take(Of("a"), 0)), nil},
+		{"take more", Collect(Take(Of("a", "b"), 5)), []string{"a", "b"}},
+		{"flat map", Collect(FlatMap(Of("a b", "", "c"), func(s string) iter.Seq[string] {
+			return FromSlice(strings.Fields(s))
+		})), []string{"a", "b", "c"}},
+		{"from map", Collect(Map(FromMap(map[string]int{"b": 2, "a": 1}), func(p collections.Pair[string, int]) string {
+			return fmt.Sprint(p.First, p.Second)
+		})), []string{"a1", "b2"}},
+	}
+	for _, test := range tests {
+		check(t, test.name, test.got, test.want)
+	}
+	check(t, "reduce", Reduce(Take(naturals(), 4), 0, func(acc, n int) int { return acc + n }), 10)
+}
+
+func TestShortCircuit(t *testing.T) {
+	pulled := 0
+	got := Collect(Take(Filter(counted(naturals(), &pulled), isEven), 3))
+	check(t, "take", got, []int{2, 4, 6})
+	check(t, "pulled", pulled, 6)
+
+	pulled = 0
+	got = Collect(Take(Skip(FlatMap(counted(naturals(), &pulled), func(n int) iter.Seq[int] {
+		return Of(n, n)
+	}), 1), 2))
+	check(t, "flat map", got, []int{1, 2})
+	check(t, "flat map pulled", pulled, 2)
+
+	pulled = 0
+	for range Map(counted(naturals(), &pulled), strconv.Itoa) {
+		break
+	}
+	check(t, "break", pulled, 1)
+}
+
+func TestFromChan(t *testing.T) {
+	ch := make(chan string, 3)
+	ch <- "one"
+	ch <- "two"
+	ch <- "three"
+	close(ch)
+	check(t, "chan", Collect(Take(FromChan(ch), 2)), []string{"one", "two"})
+	// the rest is still in the channel
+	check(t, "left", <-ch, "three")
+}
+
+func TestLines(t *testing.T) {
+	lines, err := Lines(strings.NewReader("a\r\nb\n\nc"))
+	check(t, "lines", Collect(lines), []string{"a", "b", "", "c"})
+	check(t, "err", err(), nil)
+
+	broken := errors.New("broken")
+	lines, err = Lines(iotest.TimeoutReader(iotest.ErrReader(broken)))
+	check(t, "broken lines", Collect(lines), []string(nil))
+	check(t, "broken err", err(), broken)
+
+	path := filepath.Join(t.TempDir(), "lines.txt")
+	if err := os.WriteFile(path, []byte("x\ny\nz\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	fileLines, fileErr := FileLines(path)
+	check(t, "file take", Collect(Take(fileLines, 2)), []string{"x", "y"})
+	check(t, "file again", Collect(fileLines), []string{"x", "y", "z"})
+	check(t, "file err", fileErr(), nil)
+
+	missing, missingErr := FileLines(filepath.Join(t.TempDir(), "missing.txt"))
+	check(t, "missing", Collect(missing), []string(nil))
+	if !errors.Is(missingErr(), os.ErrNotExist) {
+		t.Errorf("missing file got %v, wanted %v", missingErr(), os.ErrNotExist)
+	}
+}
+
+func TestRecords(t *testing.T) {
+	records, err := Records(strings.NewReader("name,salary\n\"bob, jr\",6000\n"))
+	check(t, "records", Collect(records), [][]string{{"name", "salary"}, {"bob, jr", "6000"}})
+	check(t, "err", err(), nil)
+
+	records, err = Records(strings.NewReader("a,1\nb,1\"0\nc,2\n"))
+	check(t, "bad quote", Collect(records), [][]string{{"a", "1"}})
+	if !errors.Is(err(), csv.ErrBareQuote) {
+		t.Errorf("got %v, wanted %v", err(), csv.ErrBareQuote)
+	}
+}
+
+func TestParallel(t *testing.T) {
+	square := func(ctx context.Context, n int) (int, error) {
+		// later inputs finish first, the output keeps the input order
+		time.Sleep(time.Duration(10-n) * time.Millisecond)
+		if n == 4 {
+			return 0, errors.New("four")
+		}
+		return n * n, nil
+	}
+	var got []string
+	for v, err := range Parallel(context.Background(), Take(naturals(), 6), square) {
+		if err != nil {
+			got = append(got, err.Error())
+			continue
+		}
+		got = append(got, strconv.Itoa(v))
+	}
+	check(t, "parallel", got, []string{"1", "4", "9", "four", "25", "36"})
+}
+
+func TestParallelN(t *testing.T) {
+	var running, most atomic.Int32
+	slow := func(ctx context.Context, n int) (int, error) {
+		now := running.Add(1)
+		defer running.Add(-1)
+		for m := most.Load(); now > m && !most.CompareAndSwap(m, now); m = most.Load() {
+		}
+		time.Sleep(time.Millisecond)
+		return -n, nil
+	}
+	var got []int
+	for v, err := range ParallelN(context.Background(), Take(naturals(), 20), 2, slow) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, v)
+	}
+	if len(got) != 20 || got[0] != -1 || got[19] != -20 {
+		t.Errorf("got %v", got)
+	}
+	if most.Load() > 2 {
+		t.Errorf("%d handlers ran at once, wanted at most 2", most.Load())
+	}
+}
+
+func TestParallelStop(t *testing.T) {
+	before := runtime.NumGoroutine()
+	identity := func(ctx context.Context, n int) (int, error) { return n, nil }
+	var got []int
+	for v := range Parallel(context.Background(), naturals(), identity) {
+		got = append(got, v)
+		if len(got) == 3 {
+			break
+		}
+	}
+	check(t, "parallel stop", got, []int{1, 2, 3})
+
+	// an infinite source is not pulled after the consumer stopped
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if n := runtime.NumGoroutine(); n > before {
+		t.Errorf("%d goroutines left, wanted %d", n, before)
+	}
+}
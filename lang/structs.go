@@ -3,7 +3,9 @@ package lang
 import (
 	"errors"
 	"fmt"
-	"math"
+
+	"github.com/vrnvu/go-examples/lang/errs"
+	"github.com/vrnvu/go-examples/lang/geometry"
 )
 
 func VariadicFunctions(nums ...int) {
@@ -126,42 +128,38 @@ func Methods() {
 	fmt.Println("perim: ", rp.perim())
 }
 
-// TODO in previous example is mentioned that a pass by value copies the struct
-// How we would work with pointers only? A pointer to interface type?
-type geometry interface {
-	area() float64
-}
-
-type rectf64 struct {
-	width, height float64
-}
-
-type circlef64 struct {
-	radius float64
-}
-
-func (r *rectf64) area() float64 {
-	return r.width * r.height
-}
-
-func (c circlef64) area() float64 {
-	return 2 * math.Pi * c.radius
-}
-
-func measure(g geometry) {
-	fmt.Println("&g", &g)
+// An interface is a named set of method signatures
+// geometry.Shape is the interface, Rect, Circle, Triangle and Polygon
+// implement it with value receivers, so values and pointers both work
+func measure(g geometry.Shape) {
 	fmt.Println(g)
-	fmt.Println(g.area())
+	fmt.Printf("area: %.2f perimeter: %.2f bounds: %v\n", g.Area(), g.Perimeter(), g.Bounds())
 }
 
 func Interfaces() {
-	// TODO how I would pass pointer instead of values?
-	r := rectf64{3, 4}
-	c := circlef64{5}
-	fmt.Println("&r", &r)
-	fmt.Println("&c", &c)
-	measure(&r)
-	measure(c)
+	r := geometry.R(0, 0, 3, 4)
+	c := geometry.Circle{Center: geometry.Pt(0, 0), Radius: 5}
+	measure(r)
+	measure(&c)
+	measure(geometry.Triangle{A: geometry.Pt(0, 0), B: geometry.Pt(3, 0), C: geometry.Pt(0, 4)})
+	measure(geometry.Poly(geometry.Pt(0, 0), geometry.Pt(4, 0), geometry.Pt(4, 2), geometry.Pt(0, 2)))
+	fmt.Println("circle contains (3,4):", c.Contains(geometry.Pt(3, 4)))
+}
+
+// SpatialIndex finds shapes with an R-tree instead of testing them one by
+// one like measure
+func SpatialIndex() {
+	ix := geometry.NewIndex[string]()
+	ix.Insert("rect", geometry.R(0, 0, 3, 4))
+	ix.Insert("circle", geometry.Circle{Center: geometry.Pt(10, 10), Radius: 2})
+	ix.Insert("triangle", geometry.Triangle{A: geometry.Pt(5, 0), B: geometry.Pt(8, 0), C: geometry.Pt(5, 3)})
+	ix.Insert("square", geometry.Poly(geometry.Pt(1, 1), geometry.Pt(2, 1), geometry.Pt(2, 2), geometry.Pt(1, 2)))
+
+	fmt.Println("at (1.5,1.5):", ix.At(geometry.Pt(1.5, 1.5)))
+	fmt.Println("in [4,0 9,9]:", ix.Range(geometry.R(4, 0, 9, 9)))
+	fmt.Println("2 nearest to (9,4):", ix.Nearest(geometry.Pt(9, 4), 2))
+	ix.Delete("triangle")
+	fmt.Println("2 nearest to (9,4) without the triangle:", ix.Nearest(geometry.Pt(9, 4), 2))
 }
 
 // By conventions errors are the last value and have type error
@@ -208,10 +206,29 @@ func Errors() {
 		}
 	}
 
-	// To use the data inside our custom error struct
+	// To use the data inside our custom error struct, errors.As also finds
+	// it when it is wrapped, a type assertion does not
 	_, e := f2(42)
-	if ae, ok := e.(*argError); ok {
+	var ae *argError
+	if errors.As(fmt.Errorf("calling f2: %w", e), &ae) {
 		fmt.Println(ae.arg)
 		fmt.Println(ae.msg)
 	}
+
+	// f3 needs no custom type, the kind and the fields are in the error
+	_, e = f3(42)
+	wrapped := fmt.Errorf("calling f3: %w", e)
+	fmt.Println("f3 failed:", wrapped)
+	fmt.Println("invalid:", errors.Is(wrapped, errs.Invalid), "kind:", errs.KindOf(wrapped))
+	arg, _ := errs.FieldOf(wrapped, "arg")
+	fmt.Println("arg:", arg)
+	fmt.Println("created in:", errs.Stack(wrapped)[0].Function)
+}
+
+// f3 is f2 with errs instead of argError
+func f3(arg int) (int, error) {
+	if arg == 42 {
+		return -1, errs.New(errs.Invalid, "value was 42", "arg", arg)
+	}
+	return arg + 3, nil
 }
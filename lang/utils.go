@@ -2,11 +2,18 @@ package lang
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"os"
 	"regexp"
 	"sort"
+	"strconv"
 	s "strings"
+
+	"github.com/vrnvu/go-examples/lang/collections"
+	"github.com/vrnvu/go-examples/lang/money"
+	"github.com/vrnvu/go-examples/lang/sorting"
+	"github.com/vrnvu/go-examples/lang/stream"
 )
 
 func Sorting() {
@@ -44,6 +51,13 @@ func SortingBy() {
 	fruits := []string{"peach", "banana", "kiwi"}
 	sort.Sort(byLength(fruits))
 	fmt.Println(fruits)
+
+	// With a comparator there is no need for a new type, and orderings combine
+	byLen := sorting.By(func(f string) int { return len(f) })
+	sorting.SortStable(fruits, byLen.Reversed().ThenBy(sorting.Natural[string]()))
+	fmt.Println(fruits)
+	fmt.Println("top 2:", sorting.TopK(fruits, 2, byLen))
+	fmt.Println("merged:", sorting.MergeSorted(sorting.Natural[string](), []string{"apple", "kiwi"}, []string{"fig", "pear"}))
 }
 
 func Panic() {
@@ -56,9 +70,57 @@ func Panic() {
 	}
 }
 
-// Go does not support generics
+// Since Go 1.18 the helpers are generic, see the collections package
 func CollectionFunctions() {
-	// skip, you get the idea :)
+	fruits := []string{"peach", "banana", "kiwi", "apple", "kiwi"}
+
+	fmt.Println("Map:      ", collections.Map(fruits, s.ToUpper))
+	fmt.Println("Filter:   ", collections.Filter(fruits, func(f string) bool { return s.Contains(f, "e") }))
+	fmt.Println("Reduce:   ", collections.Reduce(fruits, 0, func(n int, f string) int { return n + len(f) }))
+	fmt.Println("GroupBy:  ", collections.GroupBy(fruits, func(f string) int { return len(f) }))
+	long, short := collections.Partition(fruits, func(f string) bool { return len(f) > 4 })
+	fmt.Println("Partition:", long, short)
+	fmt.Println("Chunk:    ", collections.Chunk(fruits, 2))
+	fmt.Println("Window:   ", collections.Window(fruits, 3))
+	fmt.Println("Zip:      ", collections.Zip(fruits, []int{1, 2, 3}))
+	fmt.Println("Distinct: ", collections.Distinct(fruits))
+	fmt.Println("FlatMap:  ", collections.FlatMap([]string{"a b", "c"}, s.Fields))
+	fmt.Println("SortBy:   ", collections.SortBy(fruits, func(f string) int { return len(f) }))
+}
+
+// Streams reads the roster line by line, nothing is loaded in a slice
+// The bonuses are computed by a worker pool, in roster order
+func Streams() {
+	records, readErr := stream.Records(bytes.NewReader(employeesCSV))
+	sellers := stream.Filter(stream.Skip(records, 1), func(row []string) bool {
+		return row[2] != "0"
+	})
+	bonus := func(ctx context.Context, row []string) (string, error) {
+		salary, err := money.Parse(row[1], money.EUR)
+		if err != nil {
+			return "", err
+		}
+		sales, err := strconv.Atoi(row[2])
+		if err != nil {
+			return "", err
+		}
+		b, err := FindEmployeeBonus(salary, sales)
+		return row[0] + " " + b.String(), err
+	}
+	for line, err := range stream.Parallel(context.Background(), stream.Take(sellers, 3), bonus) {
+		if err != nil {
+			fmt.Println("error:", err)
+			continue
+		}
+		fmt.Println(line)
+	}
+	// Take stopped the reader early, an error is only there if a row broke
+	if err := readErr(); err != nil {
+		fmt.Println("read error:", err)
+	}
+
+	squares := stream.Map(stream.Iterate(1, func(n int) int { return n + 1 }), func(n int) int { return n * n })
+	fmt.Println("first squares:", stream.Collect(stream.Take(squares, 5)))
 }
 
 // Note that len and indexing work at the byte live.
@@ -1,73 +1,126 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"os"
+	"runtime"
+	"text/tabwriter"
+
+	"github.com/vrnvu/go-examples/registry"
+
+	// Imported for the examples they register
+	_ "github.com/vrnvu/go-examples/concurrency"
+	_ "github.com/vrnvu/go-examples/lang"
 )
 
+const usage = `Usage:
+  go run . list [--category name]
+  go run . run <name>...
+  go run . run <name> [example flags]
+  go run . run --category name
+  go run . run --all
+
+Race conditions need the race detector
+  go run -race . run RaceConditionDetector
+`
+
 // To execute run
-// $ go run *.go
+// $ go run . list
 func main() {
-	fmt.Println("main run")
-	// lang.ForIter()
-	// lang.IfElseAndSwitch()
-	// lang.Arrays()
-	// lang.Slices()
-	// lang.Maps()
-	// lang.Ranges()
-	// lang.Functions()
-	// lang.VariadicFunctions(1, 2)
-	// lang.VariadicFunctions(1, 2, 3)
-	// lang.VariadicFunctions([]int{1, 2, 3, 4}...)
-	// lang.Closures()
-	// fmt.Println(lang.RecursionFact(7))
-	// lang.Pointers()
-	// lang.Structs()
-	// lang.Methods()
-	// lang.Interfaces()
-	// lang.Errors()
-	// concurrency.Goroutines()
-	// concurrency.Channels()
-	// concurrency.ChannelBuffering()
-	// concurrency.ChannelSync()
-	// concurrency.ChannelDirections()
-	// concurrency.Select()
-	// concurrency.Timeouts()
-	// concurrency.NonBlockingChannelOperations()
-	// concurrency.ClosingChannels()
-	// concurrency.RangeOverChannels()
-	// concurrency.RangeOverChannelsWorker()
-	// concurrency.Timers()
-	// concurrency.Tickers()
-	// concurrency.WorkerPools()
-	// concurrency.WaitGroups()
-	// concurrency.WaitGroupsExtended()
-	// concurrency.RateLimiting()
-	// concurrency.AtomicCounters()
-	// concurrency.Mutexes()
-	// concurrency.StatefulGoroutines()
-	// lang.Sorting()
-	// lang.SortingBy()
-	// lang.Panic()
-	// lang.CollectionFunctions()
-	// lang.StringFunctions()
-	// lang.StringFormatting()
-	// lang.RegularExpressions()
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "list":
+		err = list(os.Args[2:])
+	case "run":
+		err = run(os.Args[2:])
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+	default:
+		err = fmt.Errorf("unknown command %q", os.Args[1])
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+func list(args []string) error {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	category := fs.String("category", "", "only list examples of this category")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
 
-	// BroadcastPattern
-	// concurrency.BadThreadBroadcastPattern()
-	// concurrency.CondThreadBroadcastPattern()
+	examples := registry.All()
+	if *category != "" {
+		examples = registry.ByCategory(*category)
+	}
 
-	// MapReduce example
-	// concurrency.MapReduce()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tCATEGORY\tDESCRIPTION")
+	for _, e := range examples {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", e.Name, e.Category, e.Description)
+	}
+	return w.Flush()
+}
 
-	// Manual logical processors for the scheduler tuning
-	// concurrency.OneProcessor()
-	// concurrency.TwoProcessor()
-	// concurrency.DefaultProcessor()
+func run(args []string) error {
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	category := fs.String("category", "", "run every example of this category")
+	all := fs.Bool("all", false, "run every example")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
 
-	// Race conditions
-	// go build -race
-	// concurrency.RaceConditionDetector()
+	var examples []registry.Example
+	switch {
+	case *all:
+		examples = registry.All()
+	case *category != "":
+		examples = registry.ByCategory(*category)
+		if len(examples) == 0 {
+			return fmt.Errorf("unknown category %q, known categories are %v", *category, registry.Categories())
+		}
+	default:
+		if fs.NArg() == 0 {
+			return fmt.Errorf("run needs an example name, --category or --all")
+		}
+		// The arguments after an example with a Main are its own flags
+		if e, ok := registry.Lookup(fs.Arg(0)); ok && e.Main != nil {
+			return runExamples([]registry.Example{e}, fs.Args()[1:])
+		}
+		for _, name := range fs.Args() {
+			e, ok := registry.Lookup(name)
+			if !ok {
+				return fmt.Errorf("unknown example %q, see `go run . list`", name)
+			}
+			examples = append(examples, e)
+		}
+	}
+
+	return runExamples(examples, nil)
 }
 
-//  LocalWords:  mv
+func runExamples(examples []registry.Example, args []string) error {
+	failed := 0
+	for _, e := range examples {
+		fmt.Fprintf(os.Stderr, "=== %s/%s\n", e.Category, e.Name)
+		// Some examples tune the scheduler, restore it for the next one
+		procs := runtime.GOMAXPROCS(0)
+		if err := registry.Run(e, args...); err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			failed++
+		}
+		runtime.GOMAXPROCS(procs)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d examples failed", failed, len(examples))
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+// Package registry keeps track of the runnable examples so main can
+// list and execute them by name instead of commenting calls in and out
+package registry
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Example is a single runnable snippet
+// Examples taking command line arguments set Main instead of Run
+type Example struct {
+	Name        string
+	Category    string
+	Description string
+	Run         func()
+	Main        func(args []string) error
+}
+
+var (
+	mu       sync.RWMutex
+	examples = make(map[string]Example)
+	order    []string
+)
+
+// Register adds an example to the registry
+// It panics if the name is empty, Run and Main are nil or the name was already
+// registered, the same way database/sql.Register does
+func Register(e Example) {
+	if e.Name == "" {
+		panic("registry: Register example with empty name")
+	}
+	if e.Run == nil && e.Main == nil {
+		panic("registry: Register example " + e.Name + " with nil Run and Main")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	key := strings.ToLower(e.Name)
+	if _, dup := examples[key]; dup {
+		panic("registry: Register called twice for example " + e.Name)
+	}
+	examples[key] = e
+	order = append(order, key)
+}
+
+// Lookup returns the example registered under name, the match is case insensitive
+func Lookup(name string) (Example, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	e, ok := examples[strings.ToLower(name)]
+	return e, ok
+}
+
+// All returns every example in registration order
+func All() []Example {
+	mu.RLock()
+	defer mu.RUnlock()
+	result := make([]Example, 0, len(order))
+	for _, key := range order {
+		result = append(result, examples[key])
+	}
+	return result
+}
+
+// ByCategory returns the examples of a category in registration order
+func ByCategory(category string) []Example {
+	result := make([]Example, 0)
+	for _, e := range All() {
+		if strings.EqualFold(e.Category, category) {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+// Categories returns the sorted list of known categories
+func Categories() []string {
+	seen := make(map[string]bool)
+	result := make([]string, 0)
+	for _, e := range All() {
+		if !seen[e.Category] {
+			seen[e.Category] = true
+			result = append(result, e.Category)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// Run executes the example and turns a panic into an error
+// so a single broken example does not stop a --all run
+// args are only accepted by the examples with a Main
+func Run(e Example, args ...string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("example %s panicked: %v", e.Name, r)
+		}
+	}()
+	if e.Main != nil {
+		return e.Main(args)
+	}
+	if len(args) > 0 {
+		return fmt.Errorf("example %s takes no arguments, got %v", e.Name, args)
+	}
+	e.Run()
+	return nil
+}
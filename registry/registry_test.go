@@ -0,0 +1,49 @@
+package registry
+
+import "testing"
+
+func TestRegisterAndLookup(t *testing.T) {
+	Register(Example{Name: "TestHello", Category: "test", Description: "hello", Run: func() {}})
+
+	e, ok := Lookup("testhello")
+	if !ok {
+		t.Fatalf("Lookup did not find TestHello")
+	}
+	if e.Category != "test" {
+		t.Errorf("got category %q, wanted %q", e.Category, "test")
+	}
+	if got := ByCategory("TEST"); len(got) != 1 {
+		t.Errorf("got %d examples, wanted 1", len(got))
+	}
+}
+
+func TestRegisterTwicePanics(t *testing.T) {
+	Register(Example{Name: "TestTwice", Run: func() {}})
+	defer func() {
+		if recover() == nil {
+			t.Errorf("second Register did not panic")
+		}
+	}()
+	Register(Example{Name: "testtwice", Run: func() {}})
+}
+
+func TestRunRecoversPanic(t *testing.T) {
+	err := Run(Example{Name: "TestPanic", Run: func() { panic("boom") }})
+	if err == nil {
+		t.Errorf("Run returned nil error for a panicking example")
+	}
+}
+
+func TestRunArgs(t *testing.T) {
+	var got []string
+	main := Example{Name: "TestMain", Main: func(args []string) error {
+		got = args
+		return nil
+	}}
+	if err := Run(main, "-input", "a.csv"); err != nil || len(got) != 2 {
+		t.Errorf("got %v %v, wanted the arguments passed to Main", got, err)
+	}
+	if err := Run(Example{Name: "TestNoArgs", Run: func() {}}, "x"); err == nil {
+		t.Errorf("Run passed arguments to an example without Main")
+	}
+}